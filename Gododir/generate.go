@@ -17,7 +17,7 @@ package dat
 {{ range $idx, $builder := .builders }}
 	// Interpolate interpolates this builders sql.
 	func (b *{{$builder}}) Interpolate() (string, []interface{}, error) {
-		return interpolate(b)
+		return interpolate(b.Dialect(), b)
 	}
 
 	// IsInterpolated determines if this builder will interpolate when
@@ -32,6 +32,80 @@ package dat
 		return b
 	}
 
+	// Dialect returns the SQLDialect this builder generates SQL for.
+	func (b *{{$builder}}) Dialect() SQLDialect {
+		if b.dialect != nil {
+			return b.dialect
+		}
+		return Dialect
+	}
+
+	// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+	// added as sub queries inherit it, so set it before adding them.
+	func (b *{{$builder}}) SetDialect(dialect SQLDialect) *{{$builder}} {
+		b.dialect = dialect
+		return b
+	}
+
+	{{if hasDialectSQL $builder}}
+	// embeddedSQL returns the SQL of a builder embedded in another builder
+	// with the dialect and default scopes of the other builder unless it has
+	// its own.
+	func (b *{{$builder}}) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+		if b.dialect != nil {
+			dialect = b.dialect
+		}
+		{{if hasTableScopes $builder}}
+		if b.tableScopes != nil {
+			scopes = b.tableScopes
+		}
+		return b.toSQL(dialect, scopes)
+		{{else}}
+		return b.toSQL(dialect)
+		{{end}}
+	}
+	{{end}}
+
+	{{if hasWithCTE $builder}}
+	// With adds the common table expression name AS (sqlOrBuilder) to the WITH
+	// clause. sqlOrBuilder may be a string with args, a Builder, including
+	// data-modifying INSERT, UPDATE and DELETE builders, or a slice of structs
+	// or scalars as in SelectDocBuilder.With.
+	func (b *{{$builder}}) With(name string, sqlOrBuilder interface{}, args ...interface{}) *{{$builder}} {
+		b.addWith(name, sqlOrBuilder, args, "")
+		return b
+	}
+	{{end}}
+
+	{{if hasWith $builder}}
+	// WithRecursive adds a common table expression which may refer to itself
+	// and makes the WITH clause RECURSIVE.
+	func (b *{{$builder}}) WithRecursive(name string, sqlOrBuilder interface{}, args ...interface{}) *{{$builder}} {
+		b.with.recursive = true
+		b.addWith(name, sqlOrBuilder, args, "")
+		return b
+	}
+
+	// WithMaterialized adds a common table expression which is computed once.
+	func (b *{{$builder}}) WithMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *{{$builder}} {
+		b.addWith(name, sqlOrBuilder, args, "MATERIALIZED ")
+		return b
+	}
+
+	// WithNotMaterialized adds a common table expression which may be folded
+	// into the statement.
+	func (b *{{$builder}}) WithNotMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *{{$builder}} {
+		b.addWith(name, sqlOrBuilder, args, "NOT MATERIALIZED ")
+		return b
+	}
+
+	func (b *{{$builder}}) addWith(name string, sqlOrBuilder interface{}, args []interface{}, materialized string) {
+		if err := b.with.add(name, sqlOrBuilder, args, materialized); err != nil {
+			b.err = err
+		}
+	}
+	{{end}}
+
 	// CanJSON determines if a builder can output JSON.
 	func (b *{{$builder}}) CanJSON() bool {
 		{{if canJson $builder}}
@@ -55,6 +129,43 @@ func generateTasks(p *do.Project) {
 						return true
 					}
 				},
+				// builders with a WITH clause
+				"hasWith": func(builder string) bool {
+					switch builder {
+					default:
+						return false
+					case "DeleteBuilder", "InsertBuilder", "SelectBuilder",
+						"SelectDocBuilder", "UpdateBuilder":
+						return true
+					}
+				},
+				// builders whose SQL depends on the dialect
+				"hasDialectSQL": func(builder string) bool {
+					switch builder {
+					default:
+						return true
+					case "CallBuilder", "RawBuilder":
+						return false
+					}
+				},
+				// builders with default table scopes
+				"hasTableScopes": func(builder string) bool {
+					switch builder {
+					default:
+						return false
+					case "DeleteBuilder", "SelectBuilder", "SelectDocBuilder", "UpdateBuilder":
+						return true
+					}
+				},
+				// SelectDocBuilder.With adds sub queries
+				"hasWithCTE": func(builder string) bool {
+					switch builder {
+					default:
+						return false
+					case "DeleteBuilder", "InsertBuilder", "SelectBuilder", "UpdateBuilder":
+						return true
+					}
+				},
 			}).Parse(builderTemplate)
 		c.Check(err, "Could not parse template")
 
@@ -62,7 +173,8 @@ func generateTasks(p *do.Project) {
 			"builders": []string{
 				"CallBuilder",
 				"DeleteBuilder", "InsectBuilder",
-				"InsertBuilder", "JSQLBuilder", "RawBuilder",
+				"InsertBuilder", "JSQLBuilder", "MergeBuilder",
+				"RawBuilder",
 				"SelectBuilder", "SelectDocBuilder",
 				"UpdateBuilder", "UpsertBuilder",
 			},
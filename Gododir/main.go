@@ -22,6 +22,11 @@ func tasks(p *do.Project) {
 	}).Src("**/*.go").
 		Desc("test with -race flag")
 
+	p.Task("test-pgx", nil, func(c *do.Context) {
+		c.Bash(`DAT_DRIVER=pgx go test`, do.M{"$in": "sqlx-runner"})
+	}).Src("**/*.go").
+		Desc("test sqlx-runner with the pgx driver")
+
 	p.Task("test-fast", nil, func(c *do.Context) {
 		c.Run(`go test`)
 		c.Run(`go test`, do.M{"$in": "sqlx-runner"})
@@ -45,6 +45,13 @@ func Insect(table string) *InsectBuilder {
 	return b
 }
 
+// Merge creates a new MergeBuilder for the given table.
+func Merge(table string) *MergeBuilder {
+	b := NewMergeBuilder(table)
+	b.Execer = nullExecer
+	return b
+}
+
 // Select creates a new SelectBuilder for the given columns.
 func Select(columns ...string) *SelectBuilder {
 	b := NewSelectBuilder(columns...)
@@ -4,7 +4,7 @@ package dat
 
 // Interpolate interpolates this builders sql.
 func (b *CallBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -19,6 +19,21 @@ func (b *CallBuilder) SetIsInterpolated(enable bool) *CallBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *CallBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *CallBuilder) SetDialect(dialect SQLDialect) *CallBuilder {
+	b.dialect = dialect
+	return b
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *CallBuilder) CanJSON() bool {
 
@@ -28,7 +43,7 @@ func (b *CallBuilder) CanJSON() bool {
 
 // Interpolate interpolates this builders sql.
 func (b *DeleteBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -43,6 +58,72 @@ func (b *DeleteBuilder) SetIsInterpolated(enable bool) *DeleteBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *DeleteBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *DeleteBuilder) SetDialect(dialect SQLDialect) *DeleteBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// embeddedSQL returns the SQL of a builder embedded in another builder
+// with the dialect and default scopes of the other builder unless it has
+// its own.
+func (b *DeleteBuilder) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+	if b.dialect != nil {
+		dialect = b.dialect
+	}
+
+	if b.tableScopes != nil {
+		scopes = b.tableScopes
+	}
+	return b.toSQL(dialect, scopes)
+
+}
+
+// With adds the common table expression name AS (sqlOrBuilder) to the WITH
+// clause. sqlOrBuilder may be a string with args, a Builder, including
+// data-modifying INSERT, UPDATE and DELETE builders, or a slice of structs
+// or scalars as in SelectDocBuilder.With.
+func (b *DeleteBuilder) With(name string, sqlOrBuilder interface{}, args ...interface{}) *DeleteBuilder {
+	b.addWith(name, sqlOrBuilder, args, "")
+	return b
+}
+
+// WithRecursive adds a common table expression which may refer to itself
+// and makes the WITH clause RECURSIVE.
+func (b *DeleteBuilder) WithRecursive(name string, sqlOrBuilder interface{}, args ...interface{}) *DeleteBuilder {
+	b.with.recursive = true
+	b.addWith(name, sqlOrBuilder, args, "")
+	return b
+}
+
+// WithMaterialized adds a common table expression which is computed once.
+func (b *DeleteBuilder) WithMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *DeleteBuilder {
+	b.addWith(name, sqlOrBuilder, args, "MATERIALIZED ")
+	return b
+}
+
+// WithNotMaterialized adds a common table expression which may be folded
+// into the statement.
+func (b *DeleteBuilder) WithNotMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *DeleteBuilder {
+	b.addWith(name, sqlOrBuilder, args, "NOT MATERIALIZED ")
+	return b
+}
+
+func (b *DeleteBuilder) addWith(name string, sqlOrBuilder interface{}, args []interface{}, materialized string) {
+	if err := b.with.add(name, sqlOrBuilder, args, materialized); err != nil {
+		b.err = err
+	}
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *DeleteBuilder) CanJSON() bool {
 
@@ -52,7 +133,7 @@ func (b *DeleteBuilder) CanJSON() bool {
 
 // Interpolate interpolates this builders sql.
 func (b *InsectBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -67,6 +148,33 @@ func (b *InsectBuilder) SetIsInterpolated(enable bool) *InsectBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *InsectBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *InsectBuilder) SetDialect(dialect SQLDialect) *InsectBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// embeddedSQL returns the SQL of a builder embedded in another builder
+// with the dialect and default scopes of the other builder unless it has
+// its own.
+func (b *InsectBuilder) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+	if b.dialect != nil {
+		dialect = b.dialect
+	}
+
+	return b.toSQL(dialect)
+
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *InsectBuilder) CanJSON() bool {
 
@@ -76,7 +184,7 @@ func (b *InsectBuilder) CanJSON() bool {
 
 // Interpolate interpolates this builders sql.
 func (b *InsertBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -91,6 +199,69 @@ func (b *InsertBuilder) SetIsInterpolated(enable bool) *InsertBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *InsertBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *InsertBuilder) SetDialect(dialect SQLDialect) *InsertBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// embeddedSQL returns the SQL of a builder embedded in another builder
+// with the dialect and default scopes of the other builder unless it has
+// its own.
+func (b *InsertBuilder) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+	if b.dialect != nil {
+		dialect = b.dialect
+	}
+
+	return b.toSQL(dialect)
+
+}
+
+// With adds the common table expression name AS (sqlOrBuilder) to the WITH
+// clause. sqlOrBuilder may be a string with args, a Builder, including
+// data-modifying INSERT, UPDATE and DELETE builders, or a slice of structs
+// or scalars as in SelectDocBuilder.With.
+func (b *InsertBuilder) With(name string, sqlOrBuilder interface{}, args ...interface{}) *InsertBuilder {
+	b.addWith(name, sqlOrBuilder, args, "")
+	return b
+}
+
+// WithRecursive adds a common table expression which may refer to itself
+// and makes the WITH clause RECURSIVE.
+func (b *InsertBuilder) WithRecursive(name string, sqlOrBuilder interface{}, args ...interface{}) *InsertBuilder {
+	b.with.recursive = true
+	b.addWith(name, sqlOrBuilder, args, "")
+	return b
+}
+
+// WithMaterialized adds a common table expression which is computed once.
+func (b *InsertBuilder) WithMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *InsertBuilder {
+	b.addWith(name, sqlOrBuilder, args, "MATERIALIZED ")
+	return b
+}
+
+// WithNotMaterialized adds a common table expression which may be folded
+// into the statement.
+func (b *InsertBuilder) WithNotMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *InsertBuilder {
+	b.addWith(name, sqlOrBuilder, args, "NOT MATERIALIZED ")
+	return b
+}
+
+func (b *InsertBuilder) addWith(name string, sqlOrBuilder interface{}, args []interface{}, materialized string) {
+	if err := b.with.add(name, sqlOrBuilder, args, materialized); err != nil {
+		b.err = err
+	}
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *InsertBuilder) CanJSON() bool {
 
@@ -100,7 +271,7 @@ func (b *InsertBuilder) CanJSON() bool {
 
 // Interpolate interpolates this builders sql.
 func (b *JSQLBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -115,6 +286,33 @@ func (b *JSQLBuilder) SetIsInterpolated(enable bool) *JSQLBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *JSQLBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *JSQLBuilder) SetDialect(dialect SQLDialect) *JSQLBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// embeddedSQL returns the SQL of a builder embedded in another builder
+// with the dialect and default scopes of the other builder unless it has
+// its own.
+func (b *JSQLBuilder) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+	if b.dialect != nil {
+		dialect = b.dialect
+	}
+
+	return b.toSQL(dialect)
+
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *JSQLBuilder) CanJSON() bool {
 
@@ -122,9 +320,60 @@ func (b *JSQLBuilder) CanJSON() bool {
 
 }
 
+// Interpolate interpolates this builders sql.
+func (b *MergeBuilder) Interpolate() (string, []interface{}, error) {
+	return interpolate(b.Dialect(), b)
+}
+
+// IsInterpolated determines if this builder will interpolate when
+// Interpolate() is called.
+func (b *MergeBuilder) IsInterpolated() bool {
+	return b.isInterpolated
+}
+
+// SetIsInterpolated sets whether this builder should interpolate.
+func (b *MergeBuilder) SetIsInterpolated(enable bool) *MergeBuilder {
+	b.isInterpolated = enable
+	return b
+}
+
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *MergeBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *MergeBuilder) SetDialect(dialect SQLDialect) *MergeBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// embeddedSQL returns the SQL of a builder embedded in another builder
+// with the dialect and default scopes of the other builder unless it has
+// its own.
+func (b *MergeBuilder) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+	if b.dialect != nil {
+		dialect = b.dialect
+	}
+
+	return b.toSQL(dialect)
+
+}
+
+// CanJSON determines if a builder can output JSON.
+func (b *MergeBuilder) CanJSON() bool {
+
+	return false
+
+}
+
 // Interpolate interpolates this builders sql.
 func (b *RawBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -139,6 +388,21 @@ func (b *RawBuilder) SetIsInterpolated(enable bool) *RawBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *RawBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *RawBuilder) SetDialect(dialect SQLDialect) *RawBuilder {
+	b.dialect = dialect
+	return b
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *RawBuilder) CanJSON() bool {
 
@@ -148,7 +412,7 @@ func (b *RawBuilder) CanJSON() bool {
 
 // Interpolate interpolates this builders sql.
 func (b *SelectBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -163,6 +427,72 @@ func (b *SelectBuilder) SetIsInterpolated(enable bool) *SelectBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *SelectBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *SelectBuilder) SetDialect(dialect SQLDialect) *SelectBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// embeddedSQL returns the SQL of a builder embedded in another builder
+// with the dialect and default scopes of the other builder unless it has
+// its own.
+func (b *SelectBuilder) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+	if b.dialect != nil {
+		dialect = b.dialect
+	}
+
+	if b.tableScopes != nil {
+		scopes = b.tableScopes
+	}
+	return b.toSQL(dialect, scopes)
+
+}
+
+// With adds the common table expression name AS (sqlOrBuilder) to the WITH
+// clause. sqlOrBuilder may be a string with args, a Builder, including
+// data-modifying INSERT, UPDATE and DELETE builders, or a slice of structs
+// or scalars as in SelectDocBuilder.With.
+func (b *SelectBuilder) With(name string, sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	b.addWith(name, sqlOrBuilder, args, "")
+	return b
+}
+
+// WithRecursive adds a common table expression which may refer to itself
+// and makes the WITH clause RECURSIVE.
+func (b *SelectBuilder) WithRecursive(name string, sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	b.with.recursive = true
+	b.addWith(name, sqlOrBuilder, args, "")
+	return b
+}
+
+// WithMaterialized adds a common table expression which is computed once.
+func (b *SelectBuilder) WithMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	b.addWith(name, sqlOrBuilder, args, "MATERIALIZED ")
+	return b
+}
+
+// WithNotMaterialized adds a common table expression which may be folded
+// into the statement.
+func (b *SelectBuilder) WithNotMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	b.addWith(name, sqlOrBuilder, args, "NOT MATERIALIZED ")
+	return b
+}
+
+func (b *SelectBuilder) addWith(name string, sqlOrBuilder interface{}, args []interface{}, materialized string) {
+	if err := b.with.add(name, sqlOrBuilder, args, materialized); err != nil {
+		b.err = err
+	}
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *SelectBuilder) CanJSON() bool {
 
@@ -172,7 +502,7 @@ func (b *SelectBuilder) CanJSON() bool {
 
 // Interpolate interpolates this builders sql.
 func (b *SelectDocBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -187,6 +517,63 @@ func (b *SelectDocBuilder) SetIsInterpolated(enable bool) *SelectDocBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *SelectDocBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *SelectDocBuilder) SetDialect(dialect SQLDialect) *SelectDocBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// embeddedSQL returns the SQL of a builder embedded in another builder
+// with the dialect and default scopes of the other builder unless it has
+// its own.
+func (b *SelectDocBuilder) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+	if b.dialect != nil {
+		dialect = b.dialect
+	}
+
+	if b.tableScopes != nil {
+		scopes = b.tableScopes
+	}
+	return b.toSQL(dialect, scopes)
+
+}
+
+// WithRecursive adds a common table expression which may refer to itself
+// and makes the WITH clause RECURSIVE.
+func (b *SelectDocBuilder) WithRecursive(name string, sqlOrBuilder interface{}, args ...interface{}) *SelectDocBuilder {
+	b.with.recursive = true
+	b.addWith(name, sqlOrBuilder, args, "")
+	return b
+}
+
+// WithMaterialized adds a common table expression which is computed once.
+func (b *SelectDocBuilder) WithMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *SelectDocBuilder {
+	b.addWith(name, sqlOrBuilder, args, "MATERIALIZED ")
+	return b
+}
+
+// WithNotMaterialized adds a common table expression which may be folded
+// into the statement.
+func (b *SelectDocBuilder) WithNotMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *SelectDocBuilder {
+	b.addWith(name, sqlOrBuilder, args, "NOT MATERIALIZED ")
+	return b
+}
+
+func (b *SelectDocBuilder) addWith(name string, sqlOrBuilder interface{}, args []interface{}, materialized string) {
+	if err := b.with.add(name, sqlOrBuilder, args, materialized); err != nil {
+		b.err = err
+	}
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *SelectDocBuilder) CanJSON() bool {
 
@@ -196,7 +583,7 @@ func (b *SelectDocBuilder) CanJSON() bool {
 
 // Interpolate interpolates this builders sql.
 func (b *UpdateBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -211,6 +598,72 @@ func (b *UpdateBuilder) SetIsInterpolated(enable bool) *UpdateBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *UpdateBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *UpdateBuilder) SetDialect(dialect SQLDialect) *UpdateBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// embeddedSQL returns the SQL of a builder embedded in another builder
+// with the dialect and default scopes of the other builder unless it has
+// its own.
+func (b *UpdateBuilder) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+	if b.dialect != nil {
+		dialect = b.dialect
+	}
+
+	if b.tableScopes != nil {
+		scopes = b.tableScopes
+	}
+	return b.toSQL(dialect, scopes)
+
+}
+
+// With adds the common table expression name AS (sqlOrBuilder) to the WITH
+// clause. sqlOrBuilder may be a string with args, a Builder, including
+// data-modifying INSERT, UPDATE and DELETE builders, or a slice of structs
+// or scalars as in SelectDocBuilder.With.
+func (b *UpdateBuilder) With(name string, sqlOrBuilder interface{}, args ...interface{}) *UpdateBuilder {
+	b.addWith(name, sqlOrBuilder, args, "")
+	return b
+}
+
+// WithRecursive adds a common table expression which may refer to itself
+// and makes the WITH clause RECURSIVE.
+func (b *UpdateBuilder) WithRecursive(name string, sqlOrBuilder interface{}, args ...interface{}) *UpdateBuilder {
+	b.with.recursive = true
+	b.addWith(name, sqlOrBuilder, args, "")
+	return b
+}
+
+// WithMaterialized adds a common table expression which is computed once.
+func (b *UpdateBuilder) WithMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *UpdateBuilder {
+	b.addWith(name, sqlOrBuilder, args, "MATERIALIZED ")
+	return b
+}
+
+// WithNotMaterialized adds a common table expression which may be folded
+// into the statement.
+func (b *UpdateBuilder) WithNotMaterialized(name string, sqlOrBuilder interface{}, args ...interface{}) *UpdateBuilder {
+	b.addWith(name, sqlOrBuilder, args, "NOT MATERIALIZED ")
+	return b
+}
+
+func (b *UpdateBuilder) addWith(name string, sqlOrBuilder interface{}, args []interface{}, materialized string) {
+	if err := b.with.add(name, sqlOrBuilder, args, materialized); err != nil {
+		b.err = err
+	}
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *UpdateBuilder) CanJSON() bool {
 
@@ -220,7 +673,7 @@ func (b *UpdateBuilder) CanJSON() bool {
 
 // Interpolate interpolates this builders sql.
 func (b *UpsertBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b.Dialect(), b)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -235,6 +688,33 @@ func (b *UpsertBuilder) SetIsInterpolated(enable bool) *UpsertBuilder {
 	return b
 }
 
+// Dialect returns the SQLDialect this builder generates SQL for.
+func (b *UpsertBuilder) Dialect() SQLDialect {
+	if b.dialect != nil {
+		return b.dialect
+	}
+	return Dialect
+}
+
+// SetDialect sets the SQLDialect this builder generates SQL for. Builders
+// added as sub queries inherit it, so set it before adding them.
+func (b *UpsertBuilder) SetDialect(dialect SQLDialect) *UpsertBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// embeddedSQL returns the SQL of a builder embedded in another builder
+// with the dialect and default scopes of the other builder unless it has
+// its own.
+func (b *UpsertBuilder) embeddedSQL(dialect SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
+	if b.dialect != nil {
+		dialect = b.dialect
+	}
+
+	return b.toSQL(dialect)
+
+}
+
 // CanJSON determines if a builder can output JSON.
 func (b *UpsertBuilder) CanJSON() bool {
 
@@ -4,6 +4,7 @@ package dat
 type CallBuilder struct {
 	Execer
 
+	dialect        SQLDialect
 	args           []interface{}
 	isInterpolated bool
 	sproc          string
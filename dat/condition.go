@@ -0,0 +1,347 @@
+package dat
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/matcherino/dat/common"
+)
+
+// Condition is a WHERE or HAVING condition built with Gt, Like, And, Or and
+// the other condition constructors. Eq maps and expressions created with
+// Expr are also conditions and may be combined with them.
+type Condition interface {
+	writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error
+}
+
+type compareCondition struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (c *compareCondition) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	writeIdentifier(buf, c.column)
+	buf.WriteString(c.op)
+	if sub, ok := c.value.(Builder); ok {
+		return writeSubquery(d, buf, sub, true, args, pos)
+	}
+	writePlaceholder(buf, int(*pos))
+	*args = append(*args, c.value)
+	*pos++
+	return nil
+}
+
+// Gt is column > value. Like the other comparisons, value may be a Builder
+// returning a single value.
+func Gt(column string, value interface{}) Condition {
+	return &compareCondition{column: column, op: " > ", value: value}
+}
+
+// Gte is column >= value.
+func Gte(column string, value interface{}) Condition {
+	return &compareCondition{column: column, op: " >= ", value: value}
+}
+
+// Lt is column < value.
+func Lt(column string, value interface{}) Condition {
+	return &compareCondition{column: column, op: " < ", value: value}
+}
+
+// Lte is column <= value.
+func Lte(column string, value interface{}) Condition {
+	return &compareCondition{column: column, op: " <= ", value: value}
+}
+
+// NotEq is column <> value.
+func NotEq(column string, value interface{}) Condition {
+	return &compareCondition{column: column, op: " <> ", value: value}
+}
+
+// Like is column LIKE pattern.
+func Like(column string, pattern string) Condition {
+	return &compareCondition{column: column, op: " LIKE ", value: pattern}
+}
+
+type ilikeCondition struct {
+	column  string
+	pattern string
+}
+
+// ILike is the case insensitive column ILIKE pattern. Dialects without
+// ILIKE compare LOWER(column) LIKE LOWER(pattern).
+func ILike(column string, pattern string) Condition {
+	return &ilikeCondition{column: column, pattern: pattern}
+}
+
+func (c *ilikeCondition) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	if DialectSupports(d, FeatureILike) {
+		writeIdentifier(buf, c.column)
+		buf.WriteString(" ILIKE ")
+		writePlaceholder(buf, int(*pos))
+	} else {
+		buf.WriteString("LOWER(")
+		writeIdentifier(buf, c.column)
+		buf.WriteString(") LIKE LOWER(")
+		writePlaceholder(buf, int(*pos))
+		buf.WriteRune(')')
+	}
+	*args = append(*args, c.pattern)
+	*pos++
+	return nil
+}
+
+type betweenCondition struct {
+	column string
+	low    interface{}
+	high   interface{}
+}
+
+// Between is column BETWEEN low AND high.
+func Between(column string, low, high interface{}) Condition {
+	return &betweenCondition{column: column, low: low, high: high}
+}
+
+func (c *betweenCondition) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	writeIdentifier(buf, c.column)
+	buf.WriteString(" BETWEEN ")
+	writePlaceholder(buf, int(*pos))
+	buf.WriteString(" AND ")
+	writePlaceholder(buf, int(*pos)+1)
+	*args = append(*args, c.low, c.high)
+	*pos += 2
+	return nil
+}
+
+type nullCondition struct {
+	column string
+}
+
+// IsNull is column IS NULL. Use Not(IsNull(column)) for IS NOT NULL.
+func IsNull(column string) Condition {
+	return &nullCondition{column: column}
+}
+
+func (c *nullCondition) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	writeIdentifier(buf, c.column)
+	buf.WriteString(" IS NULL")
+	return nil
+}
+
+type notInCondition struct {
+	column string
+	values interface{}
+}
+
+// NotIn is column NOT IN values where values is a slice. An empty slice
+// matches every row.
+func NotIn(column string, values interface{}) Condition {
+	return &notInCondition{column: column, values: values}
+}
+
+func (c *notInCondition) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	v := reflect.ValueOf(c.values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return NewError("NotIn requires a slice of values")
+	}
+	switch v.Len() {
+	case 0:
+		buf.WriteString("1=1")
+		return nil
+	case 1:
+		writeIdentifier(buf, c.column)
+		buf.WriteString(" <> ")
+		*args = append(*args, v.Index(0).Interface())
+	default:
+		writeIdentifier(buf, c.column)
+		buf.WriteString(" NOT IN ")
+		*args = append(*args, c.values)
+	}
+	writePlaceholder(buf, int(*pos))
+	*pos++
+	return nil
+}
+
+type anyCondition struct {
+	column string
+	values interface{}
+}
+
+// Any is column = ANY(ARRAY[values...]) where values is a slice. An empty
+// slice matches no rows. Requires FeatureArrays.
+func Any(column string, values interface{}) Condition {
+	return &anyCondition{column: column, values: values}
+}
+
+func (c *anyCondition) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	if err := ErrUnsupported(d, FeatureArrays); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(c.values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return NewError("Any requires a slice of values")
+	}
+	n := v.Len()
+	if n == 0 {
+		buf.WriteString("1=0")
+		return nil
+	}
+	writeIdentifier(buf, c.column)
+	buf.WriteString(" = ANY(ARRAY[")
+	writePlaceholders(buf, n, ",", int(*pos))
+	buf.WriteString("])")
+	for i := 0; i < n; i++ {
+		*args = append(*args, v.Index(i).Interface())
+	}
+	*pos += int64(n)
+	return nil
+}
+
+type jsonContainsCondition struct {
+	column string
+	value  interface{}
+}
+
+// JSONContains is column @> value, true when the jsonb column contains the
+// JSON document value. Strings, []byte and JSON are used as encoded JSON,
+// other values are marshaled. Requires FeatureJSONContains.
+func JSONContains(column string, value interface{}) Condition {
+	return &jsonContainsCondition{column: column, value: value}
+}
+
+func (c *jsonContainsCondition) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	if err := ErrUnsupported(d, FeatureJSONContains); err != nil {
+		return err
+	}
+	var doc string
+	switch t := c.value.(type) {
+	case string:
+		doc = t
+	case []byte:
+		doc = string(t)
+	case JSON:
+		doc = string(t)
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		doc = string(b)
+	}
+	writeIdentifier(buf, c.column)
+	buf.WriteString(" @> ")
+	writePlaceholder(buf, int(*pos))
+	buf.WriteString("::jsonb")
+	*args = append(*args, doc)
+	*pos++
+	return nil
+}
+
+type boolCondition struct {
+	op         string
+	conditions []Condition
+	// empty is written when there are no conditions
+	empty string
+}
+
+// And is true when all of conditions are true. And with no conditions is
+// true.
+func And(conditions ...Condition) Condition {
+	return &boolCondition{op: " AND ", conditions: conditions, empty: "1=1"}
+}
+
+// Or is true when any of conditions is true. Or with no conditions is
+// false.
+func Or(conditions ...Condition) Condition {
+	return &boolCondition{op: " OR ", conditions: conditions, empty: "1=0"}
+}
+
+func (c *boolCondition) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	if len(c.conditions) == 0 {
+		buf.WriteString(c.empty)
+		return nil
+	}
+	if len(c.conditions) == 1 {
+		return c.conditions[0].writeCondition(d, buf, args, pos)
+	}
+	for i, cond := range c.conditions {
+		if i > 0 {
+			buf.WriteString(c.op)
+		}
+		if err := writeNestedCondition(d, buf, cond, args, pos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type notCondition struct {
+	condition Condition
+}
+
+// Not is true when condition is false.
+func Not(condition Condition) Condition {
+	return &notCondition{condition: condition}
+}
+
+func (c *notCondition) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	buf.WriteString("NOT (")
+	if err := c.condition.writeCondition(d, buf, args, pos); err != nil {
+		return err
+	}
+	buf.WriteRune(')')
+	return nil
+}
+
+// writeNestedCondition writes a condition of And or Or, in parentheses
+// unless it is a single comparison.
+func writeNestedCondition(d SQLDialect, buf common.BufferWriter, cond Condition, args *[]interface{}, pos *int64) error {
+	if !needsParens(cond) {
+		return cond.writeCondition(d, buf, args, pos)
+	}
+	buf.WriteRune('(')
+	if err := cond.writeCondition(d, buf, args, pos); err != nil {
+		return err
+	}
+	buf.WriteRune(')')
+	return nil
+}
+
+func needsParens(cond Condition) bool {
+	switch c := cond.(type) {
+	case *boolCondition:
+		return len(c.conditions) > 1
+	case Eq:
+		return len(c) > 1
+	case *Expression:
+		return true
+	}
+	return false
+}
+
+// writeCondition writes the pairs of an Eq map joined by AND. An empty map
+// is true.
+func (eq Eq) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	if len(eq) == 0 {
+		buf.WriteString("1=1")
+		return nil
+	}
+	if len(eq) == 1 {
+		// a single pair needs no parentheses inside And and Or
+		for k, v := range eq {
+			writeEquality(buf, k, v, args, pos)
+		}
+		return nil
+	}
+	writeEqualityMapToSQL(buf, eq, args, false, pos)
+	return nil
+}
+
+// writeCondition writes the expression with its placeholders renumbered.
+func (exp *Expression) writeCondition(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	if hasBuilderArg(exp.Args) {
+		return writeBuilderArgs(d, buf, exp.Sql, exp.Args, args, pos)
+	}
+	exp.writeRelativeArgs(d, buf, args, pos)
+	return nil
+}
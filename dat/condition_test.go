@@ -0,0 +1,125 @@
+package dat
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+
+	"github.com/matcherino/dat/postgres"
+)
+
+func TestConditionOperators(t *testing.T) {
+	sql, args, err := Select("a").From("b").
+		Where(Gt("c", 1)).
+		Where(Gte("d", 2)).
+		Where(Lt("e", 3)).
+		Where(Lte("f", 4)).
+		Where(NotEq("g", 5)).
+		Where(Like("h", "x%")).
+		Where(ILike("i", "y%")).
+		Where(Between("j", 6, 7)).
+		Where(IsNull("k")).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT a FROM b WHERE (c > $1) AND (d >= $2) AND (e < $3) AND (f <= $4)
+		AND (g <> $5) AND (h LIKE $6) AND (i ILIKE $7) AND (j BETWEEN $8 AND $9)
+		AND (k IS NULL)`), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5, "x%", "y%", 6, 7}, args)
+}
+
+func TestConditionTree(t *testing.T) {
+	sql, args, err := Select("a").From("b").
+		Where("c = $1", 1).
+		Where(Or(
+			Gt("d", 2),
+			And(Lt("e", 3), Not(IsNull("f"))),
+			Eq{"g": 4},
+			Expr("h = $1 OR h = $2", 5, 6),
+		)).
+		Where(Eq{"i": 7}).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT a FROM b WHERE (c = $1)
+		AND (d > $2 OR (e < $3 AND NOT (f IS NULL)) OR g = $4 OR (h = $5 OR h = $6))
+		AND (i = $7)`), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5, 6, 7}, args)
+}
+
+func TestConditionEmpty(t *testing.T) {
+	sql, args, err := Select("a").From("b").
+		Where(And()).
+		Where(Or()).
+		Where(NotIn("c", []int{})).
+		Where(Or(Eq{})).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a FROM b WHERE (1=1) AND (1=0) AND (1=1) AND (1=1)"), stripWS(sql))
+	assert.Equal(t, 0, len(args))
+}
+
+func TestConditionIn(t *testing.T) {
+	sql, args, err := Select("a").From("b").
+		Where(NotIn("c", []int{1})).
+		Where(NotIn("d", []int{2, 3})).
+		Where(Any("e", []string{"x", "y"})).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a FROM b WHERE (c <> $1) AND (d NOT IN $2) AND (e = ANY(ARRAY[$3,$4]))"), stripWS(sql))
+	assert.Equal(t, []interface{}{1, []int{2, 3}, "x", "y"}, args)
+
+	sql, args, err = Select("a").From("b").Where(NotIn("d", []int{2, 3})).ToSQL()
+	assert.NoError(t, err)
+	sql, args, err = Interpolate(sql, args)
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a FROM b WHERE (d NOT IN (2,3))"), stripWS(sql))
+	assert.Equal(t, 0, len(args))
+
+	_, _, err = Select("a").From("b").Where(NotIn("d", 1)).ToSQL()
+	assert.Error(t, err)
+}
+
+func TestConditionJSONContains(t *testing.T) {
+	sql, args, err := Select("a").From("b").
+		Where(JSONContains("c", map[string]interface{}{"tags": []string{"x"}})).
+		Where(JSONContains("d", `{"e": 1}`)).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a FROM b WHERE (c @> $1::jsonb) AND (d @> $2::jsonb)"), stripWS(sql))
+	assert.Equal(t, []interface{}{`{"tags":["x"]}`, `{"e": 1}`}, args)
+}
+
+func TestConditionDialect(t *testing.T) {
+	d := questionDialect{postgres.New()}
+	sql, args, err := Select("a").From("b").SetDialect(d).
+		Where(And(ILike("c", "x%"), Gt("d", 1))).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a FROM b WHERE (LOWER(c) LIKE LOWER($1) AND d > $2)"), stripWS(sql))
+	assert.Equal(t, []interface{}{"x%", 1}, args)
+
+	_, _, err = Select("a").From("b").SetDialect(d).Where(Any("c", []int{1})).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Select("a").From("b").SetDialect(d).Where(Or(JSONContains("c", "{}"))).ToSQL()
+	assert.Error(t, err)
+}
+
+func TestConditionBuilders(t *testing.T) {
+	cond := Or(Gt("a", 1), Lt("a", 0))
+
+	sql, args, err := Update("b").Set("c", 2).Where(cond).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE b SET c = $1 WHERE (a > $2 OR a < $3)", sql)
+	assert.Equal(t, []interface{}{2, 1, 0}, args)
+
+	sql, args, err = DeleteFrom("b").Where(cond).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETE FROM b WHERE (a > $1 OR a < $2)", sql)
+	assert.Equal(t, []interface{}{1, 0}, args)
+
+	sql, args, err = Select("a", "count(*)").From("b").GroupBy("a").Having(Gt("count(*)", 2)).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a, count(*) FROM b GROUP BY a HAVING (count(*) > $1)"), stripWS(sql))
+	assert.Equal(t, []interface{}{2}, args)
+}
@@ -0,0 +1,135 @@
+package dat
+
+import "github.com/matcherino/dat/common"
+
+// conflictRows returns the columns and rows of values of the Columns,
+// Values and Record clauses of an Upsert or Insect.
+func conflictRows(d SQLDialect, cols []string, isBlacklist bool, vals [][]interface{}, records []interface{}, timestamps []string) ([]string, [][]interface{}, error) {
+	if len(cols) == 0 {
+		return nil, nil, NewError("no columns specified")
+	}
+	if len(vals) == 0 && len(records) == 0 {
+		return nil, nil, NewError("no values or records specified")
+	}
+	if len(records) == 0 && cols[0] == "*" {
+		return nil, nil, NewError(`"*" can only be used in conjunction with Record`)
+	}
+	if len(records) == 0 && isBlacklist {
+		return nil, nil, NewError(`Blacklist can only be used in conjunction with Record`)
+	}
+
+	// reflect fields removing blacklisted columns
+	if len(records) > 0 && isBlacklist {
+		cols = reflectExcludeColumns(records[0], cols)
+	}
+	// reflect all fields
+	if len(records) > 0 && cols[0] == "*" {
+		cols = reflectColumns(records[0])
+	}
+
+	cols, rows, err := recordRows(d, cols, vals, records, timestamps)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range rows {
+		if len(row) != len(cols) {
+			return nil, nil, NewError("the number of values does not match the number of columns")
+		}
+	}
+	return cols, rows, nil
+}
+
+// recordConflictColumns returns conflictColumns or, if there are none and
+// no Where clause, the inserted columns of the fields of records tagged pk.
+func recordConflictColumns(conflictColumns []string, whereFragments []*whereFragment, cols []string, records []interface{}) []string {
+	if len(conflictColumns) > 0 || len(whereFragments) > 0 || len(records) == 0 {
+		return conflictColumns
+	}
+	var pks []string
+	for _, column := range primaryKeyColumns(records[0]) {
+		if indexOfString(cols, column) >= 0 {
+			pks = append(pks, column)
+		}
+	}
+	return pks
+}
+
+// conflictWhere returns the conditions matching the conflict columns to
+// their values in row, which is used in place of ON CONFLICT by older
+// servers.
+func conflictWhere(conflictColumns, cols []string, row []interface{}) ([]*whereFragment, error) {
+	fragments := make([]*whereFragment, 0, len(conflictColumns))
+	for _, column := range conflictColumns {
+		i := indexOfString(cols, column)
+		if i < 0 {
+			return nil, NewError("conflict column " + column + " is not inserted")
+		}
+		fragment, err := newWhereFragment(column+" = $1", row[i:i+1])
+		if err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, fragment)
+	}
+	return fragments, nil
+}
+
+// writeConflictInsert writes
+//
+//	INSERT INTO table (cols) VALUES (...), ... ON CONFLICT (conflictColumns) DO UPDATE SET
+//
+// followed by a space. The SET list is written by the caller.
+func writeConflictInsert(d SQLDialect, buf common.BufferWriter, table string, cols []string, rows [][]interface{}, conflictColumns []string, args *[]interface{}, pos *int64) error {
+	buf.WriteString("INSERT INTO ")
+	writeIdentifier(buf, table)
+	buf.WriteString(" (")
+	writeIdentifiers(buf, cols, ",")
+	buf.WriteString(") VALUES ")
+	if err := writeRows(d, buf, rows, args, pos); err != nil {
+		return err
+	}
+	buf.WriteString(" ON CONFLICT (")
+	writeIdentifiers(buf, conflictColumns, ", ")
+	buf.WriteString(") DO UPDATE SET ")
+	return nil
+}
+
+// writeRows writes (values), ... for each of rows.
+func writeRows(d SQLDialect, buf common.BufferWriter, rows [][]interface{}, args *[]interface{}, pos *int64) error {
+	for i, row := range rows {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		buf.WriteRune('(')
+		for j, value := range row {
+			if j > 0 {
+				buf.WriteRune(',')
+			}
+			if err := writeValue(d, buf, value, args, pos); err != nil {
+				return err
+			}
+		}
+		buf.WriteRune(')')
+	}
+	return nil
+}
+
+// writeExcluded writes column = EXCLUDED.column for each of columns.
+func writeExcluded(buf common.BufferWriter, columns []string) {
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writeIdentifier(buf, column)
+		buf.WriteString(" = EXCLUDED.")
+		writeIdentifier(buf, column)
+	}
+}
+
+func indexOfString(a []string, s string) int {
+	for i, v := range a {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,105 @@
+package dat
+
+import (
+	"reflect"
+
+	"github.com/matcherino/dat/common"
+)
+
+// cte is a common table expression of a WITH clause.
+type cte struct {
+	name         string
+	sqlOrBuilder interface{}
+	args         []interface{}
+	// materialized is "", "MATERIALIZED " or "NOT MATERIALIZED "
+	materialized string
+}
+
+// withClause holds the common table expressions of a statement.
+type withClause struct {
+	recursive bool
+	ctes      []*cte
+}
+
+// add appends a common table expression. sqlOrBuilder is a string, a
+// Builder or a slice of structs or scalars as accepted by
+// SelectDocBuilder.With.
+func (w *withClause) add(name string, sqlOrBuilder interface{}, args []interface{}, materialized string) error {
+	switch sqlOrBuilder.(type) {
+	case string, Builder:
+	default:
+		if sqlOrBuilder == nil || reflect.TypeOf(sqlOrBuilder).Kind() != reflect.Slice {
+			return NewError("With accepts only {string, Builder, slice} type")
+		}
+	}
+	w.ctes = append(w.ctes, &cte{name: name, sqlOrBuilder: sqlOrBuilder, args: args, materialized: materialized})
+	return nil
+}
+
+// copy returns a copy which may be appended to independently of w.
+func (w withClause) copy() withClause {
+	return withClause{recursive: w.recursive, ctes: append([]*cte{}, w.ctes...)}
+}
+
+// writeWith writes the WITH clause, if any, followed by a space. Sub
+// queries inherit d.
+func writeWith(d SQLDialect, buf common.BufferWriter, w *withClause, args *[]interface{}, pos *int64) error {
+	if len(w.ctes) == 0 {
+		return nil
+	}
+	buf.WriteString("WITH ")
+	if w.recursive {
+		buf.WriteString("RECURSIVE ")
+	}
+	for i, c := range w.ctes {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if c.materialized != "" {
+			if err := ErrUnsupported(d, FeatureMaterializedCTE); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(c.name)
+		buf.WriteString(" AS ")
+		buf.WriteString(c.materialized)
+
+		sqlOrBuilder, values := c.sqlOrBuilder, c.args
+		if reflect.TypeOf(sqlOrBuilder).Kind() == reflect.Slice {
+			sql, tableArgs, err := arrayToTable(d, sqlOrBuilder)
+			if err != nil {
+				return err
+			}
+			sqlOrBuilder, values = sql, tableArgs
+		}
+
+		switch t := sqlOrBuilder.(type) {
+		case Builder:
+			if isDataModifying(t) {
+				if err := ErrUnsupported(d, FeatureDataModifyingCTE); err != nil {
+					return err
+				}
+			}
+			if err := writeSubquery(d, buf, t, true, args, pos); err != nil {
+				return err
+			}
+		case string:
+			buf.WriteRune('(')
+			if err := writeBuilderArgs(d, buf, t, values, args, pos); err != nil {
+				return err
+			}
+			buf.WriteRune(')')
+		}
+	}
+	buf.WriteRune(' ')
+	return nil
+}
+
+// isDataModifying returns whether b is an INSERT, UPDATE or DELETE.
+func isDataModifying(b Builder) bool {
+	switch b.(type) {
+	case *InsertBuilder, *UpdateBuilder, *DeleteBuilder, *UpsertBuilder, *InsectBuilder:
+		return true
+	}
+	return false
+}
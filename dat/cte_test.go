@@ -0,0 +1,124 @@
+package dat
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+
+	"github.com/matcherino/dat/postgres"
+)
+
+func TestSelectWith(t *testing.T) {
+	sql, args, err := Select("*").
+		With("recent", Select("id").From("posts").Where("created_at > $1", "2020-01-01")).
+		WithMaterialized("popular", "SELECT post_id FROM likes GROUP BY post_id HAVING count(*) > $1", 10).
+		From("recent").
+		Join("popular ON popular.post_id = recent.id").
+		Where("recent.id <> $1", 3).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH recent AS (SELECT id FROM posts WHERE (created_at > $1)),
+		popular AS MATERIALIZED (SELECT post_id FROM likes GROUP BY post_id HAVING count(*) > $2)
+		SELECT * FROM recent INNER JOIN popular ON popular.post_id = recent.id WHERE (recent.id <> $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"2020-01-01", 10, 3}, args)
+}
+
+func TestWithRecursive(t *testing.T) {
+	sql, args, err := Select("n").
+		WithRecursive("t(n)", "SELECT $1 UNION ALL SELECT n + 1 FROM t WHERE n < $2", 1, 10).
+		WithNotMaterialized("u", "SELECT 1").
+		From("t").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH RECURSIVE t(n) AS (SELECT $1 UNION ALL SELECT n + 1 FROM t WHERE n < $2),
+		u AS NOT MATERIALIZED (SELECT 1)
+		SELECT n FROM t`), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 10}, args)
+}
+
+func TestDataModifyingWith(t *testing.T) {
+	moved := DeleteFrom("posts").Where("state = $1", "archived").Returning("*")
+	sql, args, err := Select("count(*)").With("moved", moved).From("moved").ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH moved AS (DELETE FROM posts WHERE (state = $1) RETURNING *)
+		SELECT count(*) FROM moved`), stripWS(sql))
+	assert.Equal(t, []interface{}{"archived"}, args)
+
+	sql, args, err = InsertInto("log").Columns("msg").Values("x").
+		With("moved", moved).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH moved AS (DELETE FROM posts WHERE (state = $1) RETURNING *)
+		INSERT INTO log (msg) VALUES ($2)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"archived", "x"}, args)
+
+	d := questionDialect{postgres.New()}
+	_, _, err = Select("count(*)").SetDialect(d).With("moved", DeleteFrom("posts")).From("moved").ToSQL()
+	assert.Error(t, err)
+	_, _, err = Select("count(*)").SetDialect(d).WithMaterialized("m", "SELECT 1").From("m").ToSQL()
+	assert.Error(t, err)
+}
+
+func TestUpdateDeleteWith(t *testing.T) {
+	ids := Select("id").From("users").Where("banned = $1", true)
+
+	sql, args, err := Update("posts").
+		With("banned", ids).
+		Set("hidden", true).
+		Where("user_id IN (SELECT id FROM banned)").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH banned AS (SELECT id FROM users WHERE (banned = $1))
+		UPDATE posts SET hidden = $2 WHERE (user_id IN (SELECT id FROM banned))`), stripWS(sql))
+	assert.Equal(t, []interface{}{true, true}, args)
+
+	sql, args, err = DeleteFrom("posts").
+		With("banned", ids).
+		Where("user_id IN (SELECT id FROM banned) AND state = $1", "draft").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH banned AS (SELECT id FROM users WHERE (banned = $1))
+		DELETE FROM posts WHERE (user_id IN (SELECT id FROM banned) AND state = $2)`), stripWS(sql))
+	assert.Equal(t, []interface{}{true, "draft"}, args)
+}
+
+func TestWithSlice(t *testing.T) {
+	type pair struct {
+		A int    `db:"a"`
+		B string `db:"b"`
+	}
+	sql, args, err := Select("a, b").
+		With("pairs", []pair{{1, "x"}, {2, "y"}}).
+		From("pairs").
+		Where("a > $1", 0).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH pairs AS (SELECT UNNEST(ARRAY[$1,$2]::bigint[]) AS "a", UNNEST(ARRAY[$3,$4]::text[]) AS "b")
+		SELECT a, b FROM pairs WHERE (a > $5)`), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 2, "x", "y", 0}, args)
+
+	_, _, err = Select("a").With("x", 1).From("x").ToSQL()
+	assert.Error(t, err)
+	_, _, err = SelectDoc("a").WithMaterialized("x", 1).From("x").ToSQL()
+	assert.Error(t, err)
+}
+
+func TestSelectDocWithRecursive(t *testing.T) {
+	sql, args, err := SelectDoc("id").
+		WithRecursive("t(n)", "SELECT $1", 1).
+		With("u", "SELECT $1", 2).
+		From("t").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH RECURSIVE t(n) AS (SELECT $1), u AS (SELECT $2)
+		SELECT row_to_json(dat__item.*) FROM ( SELECT id FROM t ) as dat__item`), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 2}, args)
+}
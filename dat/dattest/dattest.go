@@ -0,0 +1,159 @@
+// Package dattest provides golden file helpers for testing SQL generated by
+// dat builders.
+//
+//	func TestPeopleQuery(t *testing.T) {
+//		b := dat.SelectDoc("id", "name").
+//			Many("posts", `SELECT id, title FROM posts WHERE user_id = people.id`).
+//			From("people").
+//			Where("id = $1", 1)
+//		dattest.AssertSQLSnapshot(t, b)
+//	}
+//
+// The first run with DAT_UPDATE=1 writes testdata/TestPeopleQuery.golden.
+// Later runs fail if the SQL or arguments differ from the golden file.
+//
+//	DAT_UPDATE=1 go test -run TestPeopleQuery
+//
+// Test packages with their own -update flag set Update from it.
+//
+//	var update = flag.Bool("update", false, "update golden files")
+//
+//	func TestMain(m *testing.M) {
+//		flag.Parse()
+//		dattest.Update = *update
+//		os.Exit(m.Run())
+//	}
+package dattest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/matcherino/dat/dat"
+	"github.com/matcherino/dat/postgres"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+// Dir is the directory, relative to the package under test, where golden
+// files are stored.
+var Dir = "testdata"
+
+// Update rewrites golden files instead of comparing against them. It is
+// set if the DAT_UPDATE environment variable is true, such as "1".
+var Update, _ = strconv.ParseBool(os.Getenv("DAT_UPDATE"))
+
+// AssertSQLSnapshot compares the SQL and arguments generated by builder
+// against the golden file named after the current test.
+func AssertSQLSnapshot(t testing.TB, builder dat.Builder) bool {
+	return AssertSQLSnapshotNamed(t, t.Name(), builder)
+}
+
+// AssertSQLSnapshotNamed compares the SQL and arguments generated by builder
+// against the golden file with the given name. Use it when a test asserts
+// more than one builder.
+func AssertSQLSnapshotNamed(t testing.TB, name string, builder dat.Builder) bool {
+	actual := Snapshot(builder)
+	filename := goldenFilename(name)
+
+	if Update {
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("dattest: could not create golden file directory: %s", err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(actual), 0644); err != nil {
+			t.Fatalf("dattest: could not write golden file: %s", err)
+		}
+		return true
+	}
+
+	expected, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		t.Errorf("dattest: golden file %s does not exist, run the test with DAT_UPDATE=1 to create it", filename)
+		return false
+	} else if err != nil {
+		t.Fatalf("dattest: could not read golden file: %s", err)
+	}
+
+	return assert.Equal(t, string(expected), actual, "SQL does not match golden file %s", filename)
+}
+
+// Snapshot renders the golden file contents for builder: the pretty formatted
+// ToSQL output and its arguments followed by the interpolated SQL and any
+// remaining arguments. Builders without a dialect, when dat.Dialect is not
+// set, are rendered for Postgres.
+func Snapshot(builder dat.Builder) string {
+	var buf bytes.Buffer
+
+	d := dat.BuilderDialect(builder)
+	if d == nil {
+		d = postgres.New()
+	}
+	sql, args, err := dat.ToSQLWithDialect(d, builder)
+	if err != nil {
+		buf.WriteString("-- error\n")
+		buf.WriteString(err.Error())
+		buf.WriteRune('\n')
+		return buf.String()
+	}
+
+	buf.WriteString("-- sql\n")
+	buf.WriteString(FormatSQL(sql))
+	buf.WriteString("\n\n-- args\n")
+	writeArgs(&buf, args)
+
+	isql, iargs, err := dat.InterpolateWith(d, sql, args)
+	if err != nil {
+		buf.WriteString("\n-- interpolate error\n")
+		buf.WriteString(err.Error())
+		buf.WriteRune('\n')
+		return buf.String()
+	}
+
+	buf.WriteString("\n-- interpolated\n")
+	buf.WriteString(FormatSQL(isql))
+	buf.WriteString("\n\n-- interpolated args\n")
+	writeArgs(&buf, iargs)
+
+	return buf.String()
+}
+
+func writeArgs(buf *bytes.Buffer, args []interface{}) {
+	if len(args) == 0 {
+		buf.WriteString("(none)\n")
+		return
+	}
+	for i, arg := range args {
+		fmt.Fprintf(buf, "$%d = %T(%s)\n", i+1, arg, formatArg(arg))
+	}
+}
+
+func formatArg(arg interface{}) string {
+	switch t := arg.(type) {
+	case []byte:
+		return fmt.Sprintf("%q", t)
+	case string:
+		return fmt.Sprintf("%q", t)
+	case fmt.Stringer:
+		return t.String()
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
+// goldenFilename maps a test name such as "TestFoo/sub case" to
+// "testdata/TestFoo/sub_case.golden".
+func goldenFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', ':', '*', '?', '"', '<', '>', '|', '\\':
+			return '_'
+		}
+		return r
+	}, name)
+	return filepath.Join(Dir, filepath.FromSlash(name)+".golden")
+}
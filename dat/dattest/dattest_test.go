@@ -0,0 +1,57 @@
+package dattest
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/matcherino/dat/dat"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestFormatSQL(t *testing.T) {
+	sql := FormatSQL(`SELECT a, b FROM t LEFT JOIN u ON u.id = t.u_id WHERE (a = $1) AND b IN (SELECT id FROM x WHERE name = 'select from') ORDER BY a LIMIT 10`)
+	expected := `SELECT a, b
+FROM t
+LEFT JOIN u ON u.id = t.u_id
+WHERE (a = $1) AND b IN (
+    SELECT id
+    FROM x
+    WHERE name = 'select from')
+ORDER BY a
+LIMIT 10`
+	assert.Equal(t, expected, sql)
+}
+
+func TestFormatSQLDollarQuoted(t *testing.T) {
+	sql := FormatSQL(`SELECT $$ select  from $$, $1::text`)
+	assert.Equal(t, `SELECT $$ select  from $$, $1::text`, sql)
+}
+
+func TestSnapshotError(t *testing.T) {
+	assert.Equal(t, "-- error\nno columns specified\n", Snapshot(dat.SelectDoc().From("a")))
+}
+
+func TestSelectDocSnapshot(t *testing.T) {
+	b := dat.SelectDoc("id", "name").
+		Many("posts", `SELECT id, title FROM posts WHERE user_id = people.id AND state = $1`, "published").
+		One("account", `SELECT balance FROM accounts WHERE user_id = people.id`).
+		From("people").
+		Where("id = $1", 1)
+	AssertSQLSnapshot(t, b)
+}
+
+func TestInsertSnapshot(t *testing.T) {
+	b := dat.InsertInto("people").
+		Columns("name", "email").
+		Values("mario", "mario@acme.com").
+		Returning("id")
+	AssertSQLSnapshotNamed(t, "insert/people", b)
+}
+
+func TestNoGlobalSideEffects(t *testing.T) {
+	// test packages may register their own -update flag
+	assert.Nil(t, flag.Lookup("update"))
+
+	Snapshot(dat.Select("id").From("people").Where("id = $1", 1))
+	assert.Nil(t, dat.Dialect)
+}
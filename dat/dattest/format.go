@@ -0,0 +1,202 @@
+package dattest
+
+import (
+	"bytes"
+	"strings"
+)
+
+// clauseKeywords start a new line when pretty formatting SQL.
+var clauseKeywords = map[string]bool{
+	"DELETE":    true,
+	"EXCEPT":    true,
+	"FROM":      true,
+	"FULL":      true,
+	"GROUP":     true,
+	"HAVING":    true,
+	"INNER":     true,
+	"INSERT":    true,
+	"INTERSECT": true,
+	"JOIN":      true,
+	"LEFT":      true,
+	"LIMIT":     true,
+	"OFFSET":    true,
+	"ORDER":     true,
+	"RETURNING": true,
+	"RIGHT":     true,
+	"SELECT":    true,
+	"SET":       true,
+	"UNION":     true,
+	"UPDATE":    true,
+	"VALUES":    true,
+	"WHERE":     true,
+	"WITH":      true,
+}
+
+// continuationKeywords keep the following clause keyword on the same line,
+// e.g. LEFT JOIN, UNION ALL, DO UPDATE, FOR UPDATE.
+var continuationKeywords = map[string]bool{
+	"CROSS":    true,
+	"DISTINCT": true,
+	"DO":       true,
+	"FOR":      true,
+	"FULL":     true,
+	"INNER":    true,
+	"LEFT":     true,
+	"NATURAL":  true,
+	"OUTER":    true,
+	"RIGHT":    true,
+	"UNION":    true,
+}
+
+const indent = "    "
+
+// FormatSQL pretty formats SQL for readable golden file diffs. Each clause
+// starts on its own line and is indented by its parenthesis depth. String
+// literals, quoted identifiers and dollar quoted bodies are copied verbatim.
+//
+// FormatSQL does not validate SQL, it only rearranges whitespace.
+func FormatSQL(sql string) string {
+	var buf bytes.Buffer
+	depth := 0
+	prevWord := ""
+	pendingSpace := false
+	lineStart := true
+
+	newline := func() {
+		buf.WriteRune('\n')
+		for i := 0; i < depth; i++ {
+			buf.WriteString(indent)
+		}
+		lineStart = true
+		pendingSpace = false
+	}
+
+	write := func(s string) {
+		if pendingSpace && !lineStart {
+			buf.WriteRune(' ')
+		}
+		buf.WriteString(s)
+		pendingSpace = false
+		lineStart = false
+	}
+
+	n := len(sql)
+	for i := 0; i < n; {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pendingSpace = true
+			i++
+
+		case c == '\'' || c == '"':
+			end := scanQuoted(sql, i, c)
+			write(sql[i:end])
+			prevWord = ""
+			i = end
+
+		case c == '$' && dollarTag(sql, i) != "":
+			tag := dollarTag(sql, i)
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				end = n
+			} else {
+				end = i + len(tag) + end + len(tag)
+			}
+			write(sql[i:end])
+			prevWord = ""
+			i = end
+
+		case c == '-' && i+1 < n && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = n
+			} else {
+				end += i
+			}
+			write(sql[i:end])
+			newline()
+			i = end
+
+		case c == '(':
+			write("(")
+			depth++
+			prevWord = ""
+			i++
+
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+			write(")")
+			prevWord = ""
+			i++
+
+		case c == ',':
+			buf.WriteRune(',')
+			pendingSpace = true
+			lineStart = false
+			prevWord = ""
+			i++
+
+		case isWordChar(c):
+			end := i
+			for end < n && isWordChar(sql[end]) {
+				end++
+			}
+			word := sql[i:end]
+			upper := strings.ToUpper(word)
+			if clauseKeywords[upper] && !continuationKeywords[prevWord] && buf.Len() > 0 {
+				newline()
+			}
+			write(word)
+			prevWord = upper
+			i = end
+
+		default:
+			write(string(c))
+			prevWord = ""
+			i++
+		}
+	}
+
+	return buf.String()
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '.' ||
+		('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z') ||
+		('0' <= c && c <= '9') ||
+		c >= 0x80
+}
+
+// scanQuoted returns the index after the closing quote of the literal
+// starting at start. Doubled quotes are treated as escapes.
+func scanQuoted(sql string, start int, quote byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		if sql[i] == quote {
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// dollarTag returns the dollar quoting tag, e.g. "$$" or "$abc$", starting at
+// start or "" if start is not the beginning of a tag. Placeholders such as
+// "$1" are not tags.
+func dollarTag(sql string, start int) string {
+	for i := start + 1; i < len(sql); i++ {
+		c := sql[i]
+		if c == '$' {
+			return sql[start : i+1]
+		}
+		if !(c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (i > start+1 && '0' <= c && c <= '9')) {
+			return ""
+		}
+	}
+	return ""
+}
@@ -1,16 +1,24 @@
 package dat
 
-import "errors"
+import (
+	"errors"
+	"reflect"
+)
 
 // DeleteBuilder contains the clauses for a DELETE statement
 type DeleteBuilder struct {
 	Execer
 
+	dialect        SQLDialect
 	table          string
 	whereFragments []*whereFragment
 	isInterpolated bool
+	with           withClause
 	returnings     []string
+	record         interface{}
 	scope          Scope
+	tableScopes    *TableScopes
+	unscoped       bool
 	err            error
 }
 
@@ -58,45 +66,122 @@ func (b *DeleteBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *D
 	return b
 }
 
+// Record deletes the row of record, a pointer to a struct, whose columns
+// tagged pk equal those of record. If record has a field tagged version the
+// row is deleted only if its version is unchanged.
+func (b *DeleteBuilder) Record(record interface{}) *DeleteBuilder {
+	if b.err != nil {
+		return b
+	}
+	columns := primaryKeyColumns(record)
+	if len(columns) == 0 {
+		b.err = NewError("DeleteBuilder.Record requires fields tagged pk")
+		return b
+	}
+	values, err := recordValues(reflect.ValueOf(record), columns, nil)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	for i, column := range columns {
+		b.Where(column+" = $1", values[i])
+	}
+	if version := versionColumn(record); version != "" {
+		b.whereFragments = append(b.whereFragments, versionCondition("", version, record))
+	}
+	b.record = record
+	return b
+}
+
+// versionedRecords returns 1 if the record of Record has a version column.
+func (b *DeleteBuilder) versionedRecords() int {
+	if versionColumn(b.record) == "" {
+		return 0
+	}
+	return 1
+}
+
 // Returning sets the columns for the RETURNING clause
 func (b *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
 	b.returnings = columns
 	return b
 }
 
+// SetTableScopes sets the default scopes of tables, overriding those of the
+// connection.
+func (b *DeleteBuilder) SetTableScopes(scopes *TableScopes) *DeleteBuilder {
+	b.tableScopes = scopes
+	return b
+}
+
+// Unscoped bypasses the default scope of the table. Rows of soft deleted
+// tables are deleted.
+func (b *DeleteBuilder) Unscoped() *DeleteBuilder {
+	b.unscoped = true
+	return b
+}
+
+// softDeleteSQL returns the UPDATE setting the deleted column of the rows.
+func (b *DeleteBuilder) softDeleteSQL(d SQLDialect, scopes *TableScopes, column string) (string, []interface{}, error) {
+	ub := NewUpdateBuilder(b.table)
+	ub.with = b.with
+	ub.whereFragments = b.whereFragments
+	ub.scope = b.scope
+	ub.returnings = b.returnings
+	ub.Set(column, currentTimestamp)
+	return ub.toSQL(d, scopes)
+}
+
 // ToSQL serialized the DeleteBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *DeleteBuilder) ToSQL() (string, []interface{}, error) {
+	return b.toSQL(b.Dialect(), b.tableScopes)
+}
+
+// toSQL is ToSQL with the dialect d and the default scopes of tables
+// scopes.
+func (b *DeleteBuilder) toSQL(d SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
+	if len(b.returnings) > 0 {
+		if err := ErrUnsupported(d, FeatureReturning); err != nil {
+			return NewDatSQLErr(err)
+		}
+	}
 
 	if len(b.table) == 0 {
 		return NewDatSQLError("no table specified")
 	}
+	if b.record != nil && b.scope != nil {
+		return NewDatSQLError("Record cannot be combined with a scope")
+	}
+	var defaults []*whereFragment
+	if !b.unscoped {
+		if column := scopes.softDeleteColumn(b.table); column != "" {
+			return b.softDeleteSQL(d, scopes, column)
+		}
+		var err error
+		if defaults, err = scopes.fragments(b.table); err != nil {
+			return NewDatSQLErr(err)
+		}
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 
 	var args []interface{}
+	var placeholderStartPos int64 = 1
+
+	if err := writeWith(d, buf, &b.with, &args, &placeholderStartPos); err != nil {
+		return NewDatSQLErr(err)
+	}
 
 	buf.WriteString("DELETE FROM ")
 	buf.WriteString(b.table)
 
-	var placeholderStartPos int64 = 1
-
-	// Write WHERE clause if we have any fragments
-	if b.scope == nil {
-		if len(b.whereFragments) > 0 {
-			buf.WriteString(" WHERE ")
-			writeAndFragmentsToSQL(buf, b.whereFragments, &args, &placeholderStartPos)
-		}
-	} else {
-		whereFragment, err := newWhereFragment(b.scope.ToSQL(b.table))
-		if err != nil {
-			return NewDatSQLErr(err)
-		}
-		writeScopeCondition(buf, whereFragment, &args, &placeholderStartPos)
+	if err := writeScopedWhere(d, buf, b.table, b.scope, defaults, b.whereFragments, &args, &placeholderStartPos); err != nil {
+		return NewDatSQLErr(err)
 	}
 
 	// RETURNING clause
@@ -1,12 +1,14 @@
 package dat
 
 import (
+	"net"
 	"time"
 
 	"github.com/matcherino/dat/common"
 )
 
-// Dialect is the active SQLDialect.
+// Dialect is the default SQLDialect of builders which have not been given
+// one with SetDialect, such as builders not created by a runner connection.
 var Dialect SQLDialect
 
 // SQLDialect represents a vendor specific SQL dialect.
@@ -20,3 +22,228 @@ type SQLDialect interface {
 	// WriteReflectedType writes a dialect-specfic representation of a given Go type or value
 	WriteReflectedType(buf common.BufferWriter, t interface{})
 }
+
+// PlaceholderStyle is the bind parameter syntax a database driver expects.
+type PlaceholderStyle int
+
+const (
+	// OrdinalPlaceholders are Postgres placeholders $1, $2 ... $n.
+	OrdinalPlaceholders PlaceholderStyle = iota
+	// QuestionPlaceholders are positional ? placeholders as used by MySQL.
+	QuestionPlaceholders
+	// NumberedPlaceholders are SQLite placeholders ?1, ?2 ... ?n.
+	NumberedPlaceholders
+)
+
+// PlaceholderDialect is implemented by dialects which do not use
+// OrdinalPlaceholders.
+//
+// Builders always generate ordinal placeholders so fragments can be
+// renumbered and embedded in other builders. The placeholders are rewritten
+// to the dialect's style when a builder is interpolated for execution.
+type PlaceholderDialect interface {
+	PlaceholderStyle() PlaceholderStyle
+}
+
+// BoolDialect is implemented by dialects which do not accept 't' and 'f' as
+// boolean literals.
+type BoolDialect interface {
+	WriteBoolLiteral(buf common.BufferWriter, value bool)
+}
+
+// EscapeDialect is implemented by dialects which allow backslash escapes in
+// single quoted string literals, such as MySQL.
+type EscapeDialect interface {
+	BackslashEscapes() bool
+}
+
+// LiteralDialect is implemented by dialects which interpolate values other
+// than strings, numbers, booleans and times. Without it byte slices and IP
+// addresses are passed to the driver as arguments.
+type LiteralDialect interface {
+	// WriteBytesLiteral writes a binary string.
+	WriteBytesLiteral(buf common.BufferWriter, value []byte)
+	// WriteUUIDLiteral writes a UUID given by its 16 bytes.
+	WriteUUIDLiteral(buf common.BufferWriter, value [16]byte)
+	// WriteInetLiteral writes an IP address.
+	WriteInetLiteral(buf common.BufferWriter, value net.IP)
+	// WriteCIDRLiteral writes an IP network.
+	WriteCIDRLiteral(buf common.BufferWriter, value *net.IPNet)
+	// WriteIntervalLiteral writes a duration.
+	WriteIntervalLiteral(buf common.BufferWriter, value time.Duration)
+	// WriteHstoreLiteral writes a map of keys to values.
+	WriteHstoreLiteral(buf common.BufferWriter, value map[string]string)
+	// WriteDecimalLiteral writes an exact decimal number such as "-12.5".
+	WriteDecimalLiteral(buf common.BufferWriter, value string)
+	// WriteArrayLiteral writes an array of n elements, calling writeElem to
+	// write each one. Elements may be arrays.
+	WriteArrayLiteral(buf common.BufferWriter, n int, writeElem func(i int) error) error
+}
+
+// Feature is a SQL feature dat builders use which is not available in every
+// dialect.
+type Feature int
+
+const (
+	// FeatureReturning is the RETURNING clause of INSERT, UPDATE and DELETE.
+	FeatureReturning Feature = iota
+	// FeatureOnConflict is INSERT ... ON CONFLICT.
+	FeatureOnConflict
+	// FeatureDataModifyingCTE is INSERT/UPDATE inside WITH, used by Upsert and Insect.
+	FeatureDataModifyingCTE
+	// FeatureJSONDocuments is row_to_json and array_agg, used by SelectDoc, JSQL and QueryJSON.
+	FeatureJSONDocuments
+	// FeatureDistinctOn is SELECT DISTINCT ON.
+	FeatureDistinctOn
+	// FeatureQueryCancel is cancelling a running query on timeout.
+	FeatureQueryCancel
+	// FeatureArrays is array types and UNNEST, used by SelectDoc.With for slices.
+	FeatureArrays
+	// FeatureILike is the case insensitive ILIKE operator, used by ILike.
+	FeatureILike
+	// FeatureJSONContains is the jsonb @> operator, used by JSONContains.
+	FeatureJSONContains
+	// FeatureMaterializedCTE is WITH ... AS [NOT] MATERIALIZED.
+	FeatureMaterializedCTE
+	// FeatureNullsOrdering is ORDER BY ... NULLS FIRST and NULLS LAST, used by
+	// KeysetPaginate.
+	FeatureNullsOrdering
+	// FeatureUpdateFromValues is UPDATE ... FROM (VALUES ...) AS v(columns),
+	// used by UpdateBuilder.FromRecords.
+	FeatureUpdateFromValues
+	// FeatureMerge is the MERGE statement of Postgres 15, used by Merge.
+	FeatureMerge
+	// FeatureDefaultValues is the DEFAULT keyword in VALUES, used for zero
+	// omitempty fields of records inserted with other records.
+	FeatureDefaultValues
+)
+
+var featureNames = map[Feature]string{
+	FeatureReturning:        "RETURNING",
+	FeatureOnConflict:       "ON CONFLICT",
+	FeatureDataModifyingCTE: "data-modifying WITH queries",
+	FeatureJSONDocuments:    "JSON documents",
+	FeatureDistinctOn:       "DISTINCT ON",
+	FeatureQueryCancel:      "query cancellation",
+	FeatureArrays:           "array types",
+	FeatureILike:            "ILIKE",
+	FeatureJSONContains:     "jsonb containment",
+	FeatureMaterializedCTE:  "MATERIALIZED common table expressions",
+	FeatureNullsOrdering:    "NULLS FIRST and NULLS LAST",
+	FeatureUpdateFromValues: "UPDATE FROM VALUES",
+	FeatureMerge:            "MERGE",
+	FeatureDefaultValues:    "DEFAULT in VALUES",
+}
+
+func (f Feature) String() string {
+	return featureNames[f]
+}
+
+// JSONStyle is the set of JSON functions SelectDoc and JSQL build documents
+// with.
+type JSONStyle int
+
+const (
+	// PostgresJSON builds documents with row_to_json and array_agg.
+	PostgresJSON JSONStyle = iota
+	// SQLiteJSON builds documents with json_object and json_group_array.
+	// Column names are read from the select list so SELECT * is not allowed
+	// in sub queries.
+	SQLiteJSON
+)
+
+// JSONDialect is implemented by dialects which support FeatureJSONDocuments
+// with functions other than Postgres'.
+type JSONDialect interface {
+	JSONStyle() JSONStyle
+}
+
+// FeatureDialect is implemented by dialects which lack some Features.
+// Dialects which do not implement it are assumed to support every Feature.
+type FeatureDialect interface {
+	// Name is the dialect name used in error messages.
+	Name() string
+	// Supports returns whether the dialect supports feature.
+	Supports(feature Feature) bool
+}
+
+// dialectOrDefault returns d or the default Dialect if d is nil.
+func dialectOrDefault(d SQLDialect) SQLDialect {
+	if d == nil {
+		return Dialect
+	}
+	return d
+}
+
+// DialectSupports returns whether d supports feature. A nil d is the
+// default Dialect.
+func DialectSupports(d SQLDialect, feature Feature) bool {
+	if fd, ok := dialectOrDefault(d).(FeatureDialect); ok {
+		return fd.Supports(feature)
+	}
+	return true
+}
+
+// ErrUnsupported returns an error describing a feature d does not support
+// or nil if it is supported. A nil d is the default Dialect.
+func ErrUnsupported(d SQLDialect, feature Feature) error {
+	fd, ok := dialectOrDefault(d).(FeatureDialect)
+	if !ok || fd.Supports(feature) {
+		return nil
+	}
+	return NewError(feature.String() + " is not supported by the " + fd.Name() + " dialect")
+}
+
+// DialectJSONStyle returns the JSONStyle of d. A nil d is the default
+// Dialect.
+func DialectJSONStyle(d SQLDialect) JSONStyle {
+	if jd, ok := dialectOrDefault(d).(JSONDialect); ok {
+		return jd.JSONStyle()
+	}
+	return PostgresJSON
+}
+
+func backslashEscapes(d SQLDialect) bool {
+	if ed, ok := dialectOrDefault(d).(EscapeDialect); ok {
+		return ed.BackslashEscapes()
+	}
+	return false
+}
+
+func placeholderStyle(d SQLDialect) PlaceholderStyle {
+	if pd, ok := dialectOrDefault(d).(PlaceholderDialect); ok {
+		return pd.PlaceholderStyle()
+	}
+	return OrdinalPlaceholders
+}
+
+// dialecter is implemented by builders which carry their own SQLDialect.
+type dialecter interface {
+	Dialect() SQLDialect
+}
+
+// embeddedSQL returns the SQL of sub, a builder embedded in a builder of
+// dialect d and default scopes of tables scopes, which sub uses unless it
+// has its own. sub itself is not changed.
+func embeddedSQL(d SQLDialect, scopes *TableScopes, sub Builder) (string, []interface{}, error) {
+	if eb, ok := sub.(interface {
+		embeddedSQL(SQLDialect, *TableScopes) (string, []interface{}, error)
+	}); ok {
+		return eb.embeddedSQL(d, scopes)
+	}
+	return sub.ToSQL()
+}
+
+// BuilderDialect returns the SQLDialect builder generates SQL for.
+func BuilderDialect(builder Builder) SQLDialect {
+	if db, ok := builder.(dialecter); ok {
+		return db.Dialect()
+	}
+	return Dialect
+}
+
+// ToSQLWithDialect returns the SQL of builder for d unless builder was
+// given its own dialect with SetDialect. builder is not changed.
+func ToSQLWithDialect(d SQLDialect, builder Builder) (string, []interface{}, error) {
+	return embeddedSQL(d, nil, builder)
+}
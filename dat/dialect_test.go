@@ -0,0 +1,210 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/matcherino/dat/common"
+	"github.com/matcherino/dat/postgres"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+// questionDialect is a minimal non-Postgres dialect with ? placeholders
+// and no optional features.
+type questionDialect struct {
+	*postgres.Postgres
+}
+
+func (d questionDialect) Name() string                       { return "question" }
+func (d questionDialect) Supports(feature Feature) bool      { return false }
+func (d questionDialect) PlaceholderStyle() PlaceholderStyle { return QuestionPlaceholders }
+func (d questionDialect) WriteBoolLiteral(buf common.BufferWriter, value bool) {
+	if value {
+		buf.WriteString("TRUE")
+	} else {
+		buf.WriteString("FALSE")
+	}
+}
+
+func withQuestionDialect(fn func()) {
+	old := Dialect
+	Dialect = questionDialect{postgres.New()}
+	defer func() { Dialect = old }()
+	fn()
+}
+
+func TestQuestionPlaceholders(t *testing.T) {
+	withQuestionDialect(func() {
+		b := Select("a").From("t").Where("b = ? AND c = ?", 1, "x").Where("d = $1", true)
+		b.SetIsInterpolated(false)
+		sql, args, err := b.ToSQL()
+		assert.NoError(t, err)
+		assert.Equal(t, stripWS("SELECT a FROM t WHERE (b = $1 AND c = $2) AND (d = $3)"), stripWS(sql))
+		checkSliceEqual(t, []interface{}{1, "x", true}, args)
+
+		sql, args, err = b.Interpolate()
+		assert.NoError(t, err)
+		assert.Equal(t, stripWS("SELECT a FROM t WHERE (b = ? AND c = ?) AND (d = ?)"), stripWS(sql))
+		checkSliceEqual(t, []interface{}{1, "x", true}, args)
+	})
+}
+
+func TestQuestionPlaceholdersInterpolate(t *testing.T) {
+	withQuestionDialect(func() {
+		sql, args, err := Interpolate("a = ? AND b = '?' AND c = ?", []interface{}{1, true})
+		assert.NoError(t, err)
+		assert.Equal(t, "a = 1 AND b = '?' AND c = TRUE", sql)
+		assert.Nil(t, args)
+
+		sql, args, err = Interpolate("a = ? AND b = ?", []interface{}{1, JSON([]byte("{}"))})
+		assert.NoError(t, err)
+		assert.Equal(t, "a = 1 AND b = ?", sql)
+		assert.Equal(t, 1, len(args))
+	})
+}
+
+func TestQuestionPlaceholdersRepeated(t *testing.T) {
+	sql, args, err := ordinalToQuestion(questionDialect{postgres.New()}, "a = $2 OR b = $1 OR c = $2", []interface{}{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "a = ? OR b = ? OR c = ?", sql)
+	checkSliceEqual(t, []interface{}{2, 1, 2}, args)
+
+	_, _, err = ordinalToQuestion(questionDialect{postgres.New()}, "a = $3", []interface{}{1})
+	assert.Equal(t, ErrArgumentMismatch, err)
+}
+
+func TestUnsupportedFeatures(t *testing.T) {
+	withQuestionDialect(func() {
+		_, _, err := InsertInto("t").Columns("a").Values(1).Returning("id").ToSQL()
+		assert.EqualError(t, err, "RETURNING is not supported by the question dialect")
+
+		_, _, err = Update("t").Set("a", 1).Returning("id").ToSQL()
+		assert.Error(t, err)
+
+		_, _, err = DeleteFrom("t").Returning("id").ToSQL()
+		assert.Error(t, err)
+
+		_, _, err = InsertInto("t").Columns("a").Values(1).OnConflictColumns("a").ToSQL()
+		assert.EqualError(t, err, "ON CONFLICT is not supported by the question dialect")
+
+		_, _, err = Upsert("t").Columns("a").Values(1).Where("a = $1", 1).ToSQL()
+		assert.Error(t, err)
+
+		_, _, err = SelectDoc("a").From("t").ToSQL()
+		assert.Error(t, err)
+
+		_, _, err = Select("a").DistinctOn("a").From("t").ToSQL()
+		assert.Error(t, err)
+
+		_, _, err = Select("a").Distinct().From("t").ToSQL()
+		assert.NoError(t, err)
+	})
+
+	assert.True(t, DialectSupports(nil, FeatureReturning))
+	assert.NoError(t, ErrUnsupported(nil, FeatureReturning))
+}
+
+// numberedDialect is a minimal SQLite-like dialect.
+type numberedDialect struct {
+	*postgres.Postgres
+}
+
+func (d numberedDialect) Name() string                       { return "numbered" }
+func (d numberedDialect) PlaceholderStyle() PlaceholderStyle { return NumberedPlaceholders }
+func (d numberedDialect) JSONStyle() JSONStyle               { return SQLiteJSON }
+func (d numberedDialect) Supports(feature Feature) bool {
+	return feature == FeatureReturning || feature == FeatureOnConflict || feature == FeatureJSONDocuments
+}
+
+func withNumberedDialect(fn func()) {
+	old := Dialect
+	Dialect = numberedDialect{postgres.New()}
+	defer func() { Dialect = old }()
+	fn()
+}
+
+func TestNumberedPlaceholders(t *testing.T) {
+	withNumberedDialect(func() {
+		b := Select("a").From("t").Where("b = $1 AND c IN (?, ?1)", 1, "x").Where("d = ?", true)
+		b.SetIsInterpolated(false)
+		sql, args, err := b.Interpolate()
+		assert.NoError(t, err)
+		assert.Equal(t, stripWS("SELECT a FROM t WHERE (b = ?1 AND c IN (?2, ?1)) AND (d = ?3)"), stripWS(sql))
+		checkSliceEqual(t, []interface{}{1, "x", true}, args)
+
+		sql, args, err = Interpolate("a = ? AND b = 'it''s ?'", []interface{}{"it's"})
+		assert.NoError(t, err)
+		assert.Equal(t, "a = 'it''s' AND b = 'it''s ?'", sql)
+		assert.Nil(t, args)
+	})
+}
+
+func TestSelectColumnNames(t *testing.T) {
+	names, err := selectColumnNames(`WITH x AS (SELECT 1 AS z) SELECT DISTINCT id, p.name, "Full Name", count(*) AS n, a + b, c d, CASE WHEN e THEN 1 END, (SELECT 1 FROM y) AS "q""r" FROM people p`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "Full Name", "n", "a + b", "d", "CASE WHEN e THEN 1 END", `q"r`}, names)
+
+	_, err = selectColumnNames("SELECT p.* FROM people p")
+	assert.Error(t, err)
+}
+
+func TestSQLiteSelectDoc(t *testing.T) {
+	withNumberedDialect(func() {
+		sql, args, err := SelectDoc("id").
+			Many("posts", `SELECT id, title FROM posts WHERE user_id = people.id`).
+			One("first", `SELECT id FROM posts WHERE user_id = people.id`).
+			From("people").
+			Where("id = $1", 1).
+			ToSQL()
+		assert.NoError(t, err)
+		expected := `
+		SELECT json_object('id', dat__item."id", 'posts', json(dat__item."posts"), 'first', json(dat__item."first"))
+		FROM (
+			SELECT id,
+				(SELECT CASE WHEN count(*) = 0 THEN NULL ELSE json_group_array(json_object('id', dat__posts."id", 'title', dat__posts."title")) END
+				FROM (SELECT id, title FROM posts WHERE user_id = people.id) AS dat__posts) AS "posts",
+				(SELECT json_object('id', dat__first."id")
+				FROM (SELECT id FROM posts WHERE user_id = people.id) AS dat__first LIMIT 1) AS "first"
+			FROM people
+			WHERE (id = $1)
+		) AS dat__item
+		`
+		assert.Equal(t, stripWS(expected), stripWS(sql))
+		checkSliceEqual(t, []interface{}{1}, args)
+
+		_, _, err = SelectDoc("id").Many("posts", `SELECT * FROM posts`).From("people").ToSQL()
+		assert.Error(t, err)
+
+		_, _, err = SelectDoc("id").With("ids", []int{1, 2}).From("people").ToSQL()
+		assert.Error(t, err)
+	})
+}
+
+func TestBuilderDialect(t *testing.T) {
+	b := Select("a").From("t").Where("b = ?", 1).SetDialect(questionDialect{postgres.New()})
+	b.SetIsInterpolated(false)
+	sql, args, err := b.Interpolate()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a FROM t WHERE (b = ?)"), stripWS(sql))
+	checkSliceEqual(t, []interface{}{1}, args)
+
+	_, _, err = DeleteFrom("t").Returning("id").SetDialect(questionDialect{postgres.New()}).ToSQL()
+	assert.EqualError(t, err, "RETURNING is not supported by the question dialect")
+
+	// the package default is unchanged
+	_, _, err = DeleteFrom("t").Returning("id").ToSQL()
+	assert.NoError(t, err)
+}
+
+func TestBuilderDialectInherited(t *testing.T) {
+	sub := Select("id", "title").From("posts").Where("user_id = ?", 2)
+	sql, args, err := SelectDoc("id").
+		SetDialect(numberedDialect{postgres.New()}).
+		Many("posts", sub).
+		From("people").
+		Where("id = ?", 1).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Nil(t, sub.dialect)
+	assert.Contains(t, sql, "json_group_array")
+	checkSliceEqual(t, []interface{}{2, 1}, args)
+}
@@ -16,6 +16,9 @@ var (
 	// ErrInvalidOperation occurs when an invalid operation occurs like cancelling
 	// an operation without a procPID.
 	ErrInvalidOperation = NewError("invalid operation")
+	// ErrStaleRecord is returned when a record with a field tagged version
+	// is updated or deleted after its row was changed or deleted.
+	ErrStaleRecord = NewError("stale record: the row was changed or deleted")
 	// ErrDisconnectedExecer is returned when a dat builder is used directly instead of through sqlx-runner
 	ErrDisconnectedExecer = NewError("dat builders are disconnected, use sqlx-runner package")
 )
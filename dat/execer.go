@@ -22,6 +22,9 @@ type Execer interface {
 	QueryStructs(dest interface{}) error
 	QueryObject(dest interface{}) error
 	QueryJSON() ([]byte, error)
+	QueryKeyset(dest interface{}) (*KeysetPage, error)
+	QueryPage(dest interface{}, page, perPage uint64) (*Page, error)
+	FillRecords() error
 }
 
 var nullExecer = &disconnectedExecer{}
@@ -76,3 +79,18 @@ func (nop *disconnectedExecer) QueryObject(dest interface{}) error {
 func (nop *disconnectedExecer) QueryJSON() ([]byte, error) {
 	return nil, ErrDisconnectedExecer
 }
+
+// QueryKeyset panics when QueryKeyset is called.
+func (nop *disconnectedExecer) QueryKeyset(dest interface{}) (*KeysetPage, error) {
+	return nil, ErrDisconnectedExecer
+}
+
+// QueryPage panics when QueryPage is called.
+func (nop *disconnectedExecer) QueryPage(dest interface{}, page, perPage uint64) (*Page, error) {
+	return nil, ErrDisconnectedExecer
+}
+
+// FillRecords panics when FillRecords is called.
+func (nop *disconnectedExecer) FillRecords() error {
+	return ErrDisconnectedExecer
+}
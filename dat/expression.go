@@ -15,7 +15,11 @@ func Expr(sql string, values ...interface{}) *Expression {
 
 // WriteRelativeArgs writes the args to buf adjusting the placeholder to start at pos.
 func (exp *Expression) WriteRelativeArgs(buf common.BufferWriter, args *[]interface{}, pos *int64) {
-	remapPlaceholders(buf, exp.Sql, *pos)
+	exp.writeRelativeArgs(Dialect, buf, args, pos)
+}
+
+func (exp *Expression) writeRelativeArgs(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) {
+	remapPlaceholders(d, buf, exp.Sql, *pos)
 	*args = append(*args, exp.Args...)
 	*pos += int64(len(exp.Args))
 }
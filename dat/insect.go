@@ -1,7 +1,5 @@
 package dat
 
-import "reflect"
-
 // InsectBuilder inserts or selects an existing row when executed.
 //
 //	// Inserts new row unless there exists a record where
@@ -18,18 +16,29 @@ import "reflect"
 //		Values("mario", "mario@acme.com").
 //		Where("id=$1", 1).
 //		Returning("id", "name", "email")
+//
+//	// Inserts unless there exists a record with the same email, which
+//	// is safe under concurrency.
+//	conn.Insect("people").
+//		Columns("name", "email").
+//		Values("mario", "mario@acme.com").
+//		OnConflict("email").
+//		Returning("id", "name", "email")
 type InsectBuilder struct {
 	Execer
 
-	cols           []string
-	err            error
-	isBlacklist    bool
-	isInterpolated bool
-	record         interface{}
-	returnings     []string
-	table          string
-	vals           []interface{}
-	whereFragments []*whereFragment
+	dialect         SQLDialect
+	cols            []string
+	conflictColumns []string
+	err             error
+	isBlacklist     bool
+	isInterpolated  bool
+	records         []interface{}
+	returnings      []string
+	table           string
+	timestamps      *Timestamps
+	vals            [][]interface{}
+	whereFragments  []*whereFragment
 }
 
 // NewInsectBuilder creates a new InsectBuilder for the given table.
@@ -55,21 +64,40 @@ func (b *InsectBuilder) Blacklist(columns ...string) *InsectBuilder {
 }
 
 // Whitelist defines a whitelist of columns to be inserted. To
-// specify all columsn of a record use "*".
+// specify all columns of a record, except those tagged readonly, use "*".
 func (b *InsectBuilder) Whitelist(columns ...string) *InsectBuilder {
 	b.cols = columns
 	return b
 }
 
-// Values appends a set of values to the statement
+// Values appends a set of values to the statement. More than one row
+// requires OnConflict.
 func (b *InsectBuilder) Values(vals ...interface{}) *InsectBuilder {
-	b.vals = vals
+	b.vals = append(b.vals, vals)
 	return b
 }
 
-// Record pulls in values to match Columns from the record
+// Record pulls in values to match Columns from the record. More than one
+// record requires OnConflict.
 func (b *InsectBuilder) Record(record interface{}) *InsectBuilder {
-	b.record = record
+	b.records = append(b.records, record)
+	return b
+}
+
+// OnConflict sets the columns of the unique index identifying existing
+// rows in place of Where. Rows are then inserted with INSERT ... ON
+// CONFLICT (columns) DO UPDATE, which sets the columns to their existing
+// values so the existing row is returned. Servers without ON CONFLICT
+// select the row whose columns equal the values instead.
+//
+// Records without Where or OnConflict conflict on their inserted columns
+// tagged pk.
+func (b *InsectBuilder) OnConflict(columns ...string) *InsectBuilder {
+	if len(columns) == 0 {
+		b.err = NewError("OnConflict requires 1 or more columns")
+		return b
+	}
+	b.conflictColumns = columns
 	return b
 }
 
@@ -79,50 +107,62 @@ func (b *InsectBuilder) Returning(columns ...string) *InsectBuilder {
 	return b
 }
 
+// SetTimestamps sets the timestamp columns of records, overriding those of
+// the connection. With nil only fields tagged autocreate and autoupdate are
+// set.
+func (b *InsectBuilder) SetTimestamps(timestamps *Timestamps) *InsectBuilder {
+	b.timestamps = timestamps
+	return b
+}
+
 // ToSQL serialized the InsectBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
+	return b.toSQL(b.Dialect())
+}
+
+// toSQL is ToSQL with the dialect d.
+func (b *InsectBuilder) toSQL(d SQLDialect) (string, []interface{}, error) {
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
-	var err error
-
 	if len(b.table) == 0 {
 		return NewDatSQLError("no table specified")
 	}
-	lenCols := len(b.cols)
-	if lenCols == 0 {
-		return NewDatSQLError("no columns specified")
-	}
-	if len(b.vals) == 0 && b.record == nil {
-		return NewDatSQLError("no values or records specified")
-	}
-
-	if b.record == nil && b.cols[0] == "*" {
-		return NewDatSQLError(`"*" can only be used in conjunction with Record`)
-	}
-	if b.record == nil && b.isBlacklist {
-		return NewDatSQLError(`Blacklist can only be used in conjunction with Record`)
+	cols, rows, err := conflictRows(d, b.cols, b.isBlacklist, b.vals, b.records, b.timestamps.insertColumns(b.records))
+	if err != nil {
+		return NewDatSQLErr(err)
 	}
-
-	cols := b.cols
-	vals := b.vals
 	returnings := b.returnings
 	whereFragments := b.whereFragments
+	conflictColumns := recordConflictColumns(b.conflictColumns, whereFragments, cols, b.records)
+	if len(conflictColumns) > 0 && len(whereFragments) > 0 {
+		return NewDatSQLError("Insect accepts either OnConflict or Where")
+	}
+	if len(conflictColumns) > 0 && DialectSupports(d, FeatureOnConflict) {
+		if len(returnings) == 0 {
+			returnings = cols
+		}
+		return b.onConflictSQL(d, cols, rows, conflictColumns, returnings)
+	}
 
-	// reflect fields removing blacklisted columns
-	if b.record != nil && b.isBlacklist {
-		cols = reflectExcludeColumns(b.record, cols)
+	if err := ErrUnsupported(d, FeatureDataModifyingCTE); err != nil {
+		return NewDatSQLErr(err)
 	}
-	// reflect all fields
-	if b.record != nil && cols[0] == "*" {
-		cols = reflectColumns(b.record)
+	if len(rows) > 1 {
+		return NewDatSQLError("insecting more than one row requires ON CONFLICT")
+	}
+	vals := rows[0]
+	if len(conflictColumns) > 0 {
+		if whereFragments, err = conflictWhere(conflictColumns, cols, vals); err != nil {
+			return NewDatSQLErr(err)
+		}
 	}
 
 	whereAdded := false
 
 	// build where clause from columns and values
-	if len(whereFragments) == 0 && b.record == nil {
+	if len(whereFragments) == 0 && len(b.records) == 0 {
 		whereAdded = true
 		for i, column := range cols {
 			fragment, err := newWhereFragment(column+"=$1", vals[i:i+1])
@@ -154,14 +194,6 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
 	   	   UNION ALL
 	   	   SELECT * FROM sel
 	*/
-	if b.record != nil {
-		ind := reflect.Indirect(reflect.ValueOf(b.record))
-		vals, err = valuesFor(ind.Type(), ind, cols)
-		if err != nil {
-			return NewDatSQLErr(err)
-		}
-	}
-
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 	var args []interface{}
@@ -171,6 +203,7 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
 
 	sb := NewSelectBuilder(returnings...).
 		From(b.table)
+	sb.dialect = d
 	sb.whereFragments = whereFragments
 	selectSQL, args, err = sb.ToSQL()
 	if err != nil {
@@ -189,8 +222,15 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
 	if whereAdded {
 		writePlaceholders(buf, len(args), ",", 1)
 	} else {
-		writePlaceholders(buf, len(vals), ",", len(args)+1)
-		args = append(args, vals...)
+		placeholderStartPos := int64(len(args) + 1)
+		for i, val := range vals {
+			if i > 0 {
+				buf.WriteRune(',')
+			}
+			if err := writeValue(d, buf, val, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+		}
 	}
 
 	buf.WriteString(" WHERE NOT EXISTS (SELECT 1 FROM sel) RETURNING ")
@@ -201,6 +241,29 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
 	return buf.String(), args, nil
 }
 
+// onConflictSQL returns
+//
+//	INSERT INTO t (a, b) VALUES ($1, $2) ON CONFLICT (a) DO UPDATE SET a = EXCLUDED.a RETURNING a, b
+//
+// DO NOTHING would not return existing rows.
+func (b *InsectBuilder) onConflictSQL(d SQLDialect, cols []string, rows [][]interface{}, conflictColumns []string, returnings []string) (string, []interface{}, error) {
+	if err := ErrUnsupported(d, FeatureReturning); err != nil {
+		return NewDatSQLErr(err)
+	}
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	var args []interface{}
+	var placeholderStartPos int64 = 1
+
+	if err := writeConflictInsert(d, buf, b.table, cols, rows, conflictColumns, &args, &placeholderStartPos); err != nil {
+		return NewDatSQLErr(err)
+	}
+	writeExcluded(buf, conflictColumns)
+	buf.WriteString(" RETURNING ")
+	writeIdentifiers(buf, returnings, ",")
+	return buf.String(), args, nil
+}
+
 // Where appends a WHERE clause to the statement for the given string and args
 // or map of column/value pairs
 func (b *InsectBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *InsectBuilder {
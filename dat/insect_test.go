@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"gopkg.in/stretchr/testify.v1/assert"
+
+	"github.com/matcherino/dat/postgres"
 )
 
 var regexpWS = regexp.MustCompile(`\s`)
@@ -111,3 +113,40 @@ func TestInsectSqlRecord(t *testing.T) {
 	assert.Equal(t, stripWS(expected), stripWS(sql))
 	assert.Equal(t, args, []interface{}{3, 1, 2, 4})
 }
+
+func TestInsectSqlOnConflict(t *testing.T) {
+	sql, args, err := Insect("tab").
+		Columns("b", "c").
+		Values(1, 2).
+		Values(3, 4).
+		OnConflict("b").
+		Returning("id", "b").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO tab (b,c) VALUES ($1,$2),($3,$4) ON CONFLICT (b) "+
+		"DO UPDATE SET b = EXCLUDED.b RETURNING id,b", sql)
+	assert.Equal(t, []interface{}{1, 2, 3, 4}, args)
+
+	sql, args, err = Insect("tab").
+		SetDialect(preConflictDialect{postgres.New()}).
+		Columns("b", "c").
+		Values(1, 2).
+		OnConflict("b").
+		ToSQL()
+	assert.NoError(t, err)
+	expected := `
+	WITH sel AS (SELECT b, c FROM tab WHERE (b = $1)),
+	ins AS (
+		INSERT INTO tab(b,c)
+		SELECT $2,$3
+		WHERE NOT EXISTS (SELECT 1 FROM sel)
+		RETURNING b,c
+	)
+	SELECT * FROM ins UNION ALL SELECT * FROM sel
+	`
+	assert.Equal(t, stripWS(expected), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 1, 2}, args)
+
+	_, _, err = Insect("tab").Columns("b").Values(1).OnConflict("b").Where("b = $1", 1).ToSQL()
+	assert.Error(t, err)
+}
@@ -3,7 +3,6 @@ package dat
 import (
 	"bytes"
 	"errors"
-	"reflect"
 	"strconv"
 	"strings"
 )
@@ -12,12 +11,16 @@ import (
 type InsertBuilder struct {
 	Execer
 
+	dialect          SQLDialect
 	isInterpolated   bool
+	with             withClause
 	table            string
+	timestamps       *Timestamps
 	cols             []string
 	isBlacklist      bool
 	vals             [][]interface{}
 	records          []interface{}
+	fromSelect       Builder
 	onConflictTarget *onConflictTargetType
 	onConflictAction *onConflictActionType
 	returnings       []string
@@ -65,6 +68,17 @@ func (b *InsertBuilder) Record(record interface{}) *InsertBuilder {
 	return b
 }
 
+// FromSelect inserts the rows of a query, such as a SelectBuilder or
+// RawBuilder, in place of Values and Record as in
+//
+//	INSERT INTO t (a, b) SELECT a, b FROM s WHERE c = $1
+//
+// The query inherits the dialect of the builder. Columns are optional.
+func (b *InsertBuilder) FromSelect(builder Builder) *InsertBuilder {
+	b.fromSelect = builder
+	return b
+}
+
 // The ON CONFLICT clause can be used to specify an alternative action to raising a unique constraint or exclusion constraint violation error
 //     [ ON CONFLICT [ conflict_target ] conflict_action ]
 //		where conflict_target can be one of:
@@ -254,26 +268,55 @@ func (b *InsertBuilder) Pair(column string, value interface{}) *InsertBuilder {
 	return b
 }
 
+// SetTimestamps sets the timestamp columns of records, overriding those of
+// the connection. With nil only fields tagged autocreate and autoupdate are
+// set.
+func (b *InsertBuilder) SetTimestamps(timestamps *Timestamps) *InsertBuilder {
+	b.timestamps = timestamps
+	return b
+}
+
 // ToSQL serialized the InsertBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
+	return b.toSQL(b.Dialect())
+}
+
+// toSQL is ToSQL with the dialect d.
+func (b *InsertBuilder) toSQL(d SQLDialect) (string, []interface{}, error) {
 	if b.err != nil {
 		return "", nil, b.err
 	}
+	if len(b.returnings) > 0 {
+		if err := ErrUnsupported(d, FeatureReturning); err != nil {
+			return "", nil, err
+		}
+	}
+	if b.onConflictTarget.hasOneConflictTarget() {
+		if err := ErrUnsupported(d, FeatureOnConflict); err != nil {
+			return "", nil, err
+		}
+	}
 
 	if len(b.table) == 0 {
 		return "", nil, NewError("no table specified")
 	}
 	lenCols := len(b.cols)
 	lenRecords := len(b.records)
-	if lenCols == 0 {
-		return "", nil, NewError("no columns specified")
-	}
-	if len(b.vals) == 0 && lenRecords == 0 {
-		return "", nil, NewError("no values or records specified")
+	if b.fromSelect != nil {
+		if len(b.vals) > 0 || lenRecords > 0 {
+			return "", nil, NewError("FromSelect cannot be combined with values or records")
+		}
+	} else {
+		if lenCols == 0 {
+			return "", nil, NewError("no columns specified")
+		}
+		if len(b.vals) == 0 && lenRecords == 0 {
+			return "", nil, NewError("no values or records specified")
+		}
 	}
 
-	if lenRecords == 0 && b.cols[0] == "*" {
+	if lenRecords == 0 && lenCols > 0 && b.cols[0] == "*" {
 		return "", nil, NewError(`"*" can only be used in conjunction with Record`)
 	}
 
@@ -291,54 +334,44 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
 	if lenRecords > 0 && cols[0] == "*" {
 		cols = reflectColumns(b.records[0])
 	}
+	cols, rows, err := recordRows(d, cols, b.vals, b.records, b.timestamps.insertColumns(b.records))
+	if err != nil {
+		return "", nil, err
+	}
 
 	var sql bytes.Buffer
 	var args []interface{}
+	var placeholderStartPos int64 = 1
+
+	if err := writeWith(d, &sql, &b.with, &args, &placeholderStartPos); err != nil {
+		return "", nil, err
+	}
 
 	sql.WriteString("INSERT INTO ")
 	sql.WriteString(b.table)
-	sql.WriteString(" (")
-
-	for i, c := range cols {
-		if i > 0 {
-			sql.WriteRune(',')
+	if len(cols) > 0 {
+		sql.WriteString(" (")
+		for i, c := range cols {
+			if i > 0 {
+				sql.WriteRune(',')
+			}
+			writeIdentifier(&sql, c)
 		}
-		writeIdentifier(&sql, c)
+		sql.WriteRune(')')
 	}
-	sql.WriteString(") VALUES ")
-
-	start := 1
-	// Go thru each value we want to insert. Write the placeholders, and collect args
-	for i, row := range b.vals {
-		if i > 0 {
-			sql.WriteRune(',')
-		}
-		buildPlaceholders(&sql, start, len(row))
-
-		for _, v := range row {
-			args = append(args, v)
-			start++
+	if b.fromSelect != nil {
+		sql.WriteRune(' ')
+		if err := writeSubquery(d, &sql, b.fromSelect, false, &args, &placeholderStartPos); err != nil {
+			return "", nil, err
 		}
+	} else {
+		sql.WriteString(" VALUES ")
 	}
-	anyVals := len(b.vals) > 0
-
-	// Go thru the records. Write the placeholders, and do reflection on the records to extract args
-	for i, rec := range b.records {
-		if i > 0 || anyVals {
-			sql.WriteRune(',')
-		}
 
-		ind := reflect.Indirect(reflect.ValueOf(rec))
-		vals, err := valuesFor(ind.Type(), ind, cols)
-		if err != nil {
-			return "", nil, err
-		}
-		buildPlaceholders(&sql, start, len(vals))
-		for _, v := range vals {
-			args = append(args, v)
-			start++
-		}
+	if err := writeRows(d, &sql, rows, &args, &placeholderStartPos); err != nil {
+		return "", nil, err
 	}
+	start := int(placeholderStartPos)
 
 	// On conflict clause
 	if b.onConflictTarget.hasOneConflictTarget() {
@@ -361,7 +394,7 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
 			sql.WriteString(" DO UPDATE SET ")
 
 			// Build DO UPDATE SET clause SQL with placeholders and add values to args
-			placeholderStartPos := int64(start)
+			placeholderStartPos = int64(start)
 			for i, c := range b.onConflictAction.setClauses {
 				if i > 0 {
 					sql.WriteString(", ")
@@ -373,7 +406,7 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
 					startPos := placeholderStartPos
 					sql.WriteString(" = ")
 					// map relative $1, $2 placeholders to absolute
-					remapPlaceholders(&sql, e.Sql, startPos)
+					remapPlaceholders(d, &sql, e.Sql, startPos)
 					args = append(args, e.Args...)
 					placeholderStartPos += int64(len(e.Args))
 				} else if s, ok := c.value.(UnsafeString); ok {
@@ -397,7 +430,9 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
 			// DO UPDATE SET .. WHERE clause
 			if len(b.onConflictAction.whereFragments) > 0 {
 				sql.WriteString(" WHERE ")
-				writeAndFragmentsToSQL(&sql, b.onConflictAction.whereFragments, &args, &placeholderStartPos)
+				if err := writeAndFragmentsToSQL(d, &sql, b.onConflictAction.whereFragments, &args, &placeholderStartPos); err != nil {
+					return NewDatSQLErr(err)
+				}
 			}
 		}
 	}
@@ -250,3 +250,30 @@ func TestInsertOnConflictSetExcludedWhere(t *testing.T) {
 	assert.Equal(t, quoteSQL("INSERT INTO a (%s,%s) VALUES ($1,$2) ON CONFLICT (%s) DO UPDATE SET %s = %s WHERE (%s = $3)", "b", "c", "b", "b", "EXCLUDED.b", "a.b"), sql)
 	assert.Equal(t, []interface{}{1, 2, 10}, args)
 }
+
+func TestInsertFromSelect(t *testing.T) {
+	sel := Select("b", "c").From("d").Where("e = $1", 1)
+	sql, args, err := InsertInto("a").
+		With("x", "SELECT $1", 0).
+		Columns("b", "c").
+		FromSelect(sel).
+		OnConflictColumns("b").
+		Set("c", 2).
+		Returning("b").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH x AS (SELECT $1) INSERT INTO a (b,c) SELECT b, c FROM d WHERE (e = $2)
+		ON CONFLICT (b) DO UPDATE SET c = $3 RETURNING b`), stripWS(sql))
+	assert.Equal(t, []interface{}{0, 1, 2}, args)
+
+	sql, args, err = InsertInto("a").FromSelect(SQL("SELECT * FROM d WHERE e = $1", 1)).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO a SELECT * FROM d WHERE e = $1", sql)
+	assert.Equal(t, []interface{}{1}, args)
+
+	_, _, err = InsertInto("a").Columns("b").Values(1).FromSelect(sel).ToSQL()
+	assert.Error(t, err)
+	_, _, err = InsertInto("a").Columns("*").FromSelect(sel).ToSQL()
+	assert.Error(t, err)
+}
@@ -1,7 +1,6 @@
 package dat
 
 import (
-	"bytes"
 	"database/sql/driver"
 	"reflect"
 	"strconv"
@@ -44,18 +43,32 @@ var typeOfTime = reflect.TypeOf(time.Time{})
 // Interpolate takes a SQL string with placeholders and a list of arguments to
 // replace them with. Returns a blank string and error if the number of placeholders
 // does not match the number of arguments.
+//
+// Values are written as literals of the default Dialect. Use InterpolateWith
+// for builders with their own dialect.
 func Interpolate(sql string, vals []interface{}) (string, []interface{}, error) {
-	// Get the number of arguments to add to this query
-	lenVals := len(vals)
+	return InterpolateWith(Dialect, sql, vals)
+}
 
-	// if there are any []byte types, just pass it through to save memory allocations
-	for i := 0; i < lenVals; i++ {
-		if _, ok := vals[i].([]byte); ok {
-			return sql, vals, nil
-		} else if _, ok := vals[i].(*[]byte); ok {
-			return sql, vals, nil
-		}
+// InterpolateWith interpolates sql like Interpolate, writing values as
+// literals of d.
+//
+// If d does not use OrdinalPlaceholders, sql may contain ? placeholders and
+// any placeholders remaining in the result are in d's style.
+func InterpolateWith(d SQLDialect, sql string, vals []interface{}) (string, []interface{}, error) {
+	if placeholderStyle(d) == OrdinalPlaceholders {
+		return interpolateOrdinal(d, sql, vals)
 	}
+	s, args, err := interpolateOrdinal(d, questionToOrdinal(d, sql), vals)
+	if err != nil {
+		return "", nil, err
+	}
+	return ordinalToDialect(d, s, args)
+}
+
+func interpolateOrdinal(d SQLDialect, sql string, vals []interface{}) (string, []interface{}, error) {
+	// Get the number of arguments to add to this query
+	lenVals := len(vals)
 
 	// If our query is blank and has no args return early
 	// Args with a blank query is an error
@@ -89,8 +102,6 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
-	var accumulateDigits bool
-	var digits bytes.Buffer
 
 	newPlaceholderIndex := 0
 	var newArgs []interface{}
@@ -129,7 +140,18 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 				return nil
 			}
 
-			s, args, err := valuer.Expression()
+			var s string
+			var args []interface{}
+			var err error
+			if exp, ok := v.(*Expression); ok {
+				expSQL := exp.Sql
+				if placeholderStyle(d) != OrdinalPlaceholders {
+					expSQL = questionToOrdinal(d, expSQL)
+				}
+				s, args, err = interpolateOrdinal(d, expSQL, exp.Args)
+			} else {
+				s, args, err = valuer.Expression()
+			}
 			if err != nil {
 				return err
 			}
@@ -150,7 +172,12 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			if err != nil {
 				return err
 			}
-			Dialect.WriteStringLiteral(buf, s)
+			d.WriteStringLiteral(buf, s)
+			return nil
+		} else if ok, err := writeLiteral(d, buf, v); ok || err != nil {
+			return err
+		} else if isBinary(v) {
+			passthroughArg(v)
 			return nil
 		} else if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
@@ -183,7 +210,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			if !utf8.ValidString(str) {
 				return ErrNotUTF8
 			}
-			Dialect.WriteStringLiteral(buf, str)
+			d.WriteStringLiteral(buf, str)
 		} else if isInt(kindOfV) {
 			var ival = valueOfV.Int()
 			writeInt64(buf, ival)
@@ -195,7 +222,9 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			buf.WriteString(strconv.FormatFloat(fval, 'f', -1, 64))
 		} else if kindOfV == reflect.Bool {
 			var bval = valueOfV.Bool()
-			if bval {
+			if bd, ok := d.(BoolDialect); ok {
+				bd.WriteBoolLiteral(buf, bval)
+			} else if bval {
 				buf.WriteString(`'t'`)
 			} else {
 				buf.WriteString(`'f'`)
@@ -203,7 +232,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 		} else if kindOfV == reflect.Struct {
 			if typeOfV := valueOfV.Type(); typeOfV == typeOfTime {
 				t := valueOfV.Interface().(time.Time)
-				Dialect.WriteFormattedTime(buf, t)
+				d.WriteFormattedTime(buf, t)
 			} else {
 				return ErrInvalidValue
 			}
@@ -213,6 +242,10 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			kindOfSubtype := subtype.Kind()
 			sliceLen := valueOfV.Len()
 
+			if ld, ok := d.(LiteralDialect); ok && isNestedSlice(typeOfV) {
+				return writeArrayLiteral(ld, d, buf, valueOfV)
+			}
+
 			if sliceLen == 0 {
 				return ErrInvalidSliceLength
 			}
@@ -243,7 +276,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 					if !utf8.ValidString(str) {
 						return ErrNotUTF8
 					}
-					Dialect.WriteStringLiteral(buf, str)
+					d.WriteStringLiteral(buf, str)
 				}
 			} else {
 				return ErrInvalidSliceValue
@@ -256,64 +289,130 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 		return nil
 	}
 
-	lenSQL := len(sql)
-	done := false
-	for i, r := range sql {
-		if accumulateDigits {
-			if '0' <= r && r <= '9' {
-				digits.WriteRune(r)
-				if i < lenSQL-1 {
-					continue
-				}
-				// last rune is part of a placeholder, fallthrough
-				done = true
-			}
-
-			digitsStr := digits.String()
-			// can be empty $ is followed by a non-digit
-			if digitsStr == "" {
-				buf.WriteRune('$')
-				buf.WriteRune(r)
-				accumulateDigits = false
-				continue
-			}
-
-			pos := 0
-			if len(digitsStr) > 2 {
-				pos, _ = strconv.Atoi(digitsStr)
-			} else {
-				pos, _ = atoiTab[digitsStr]
-			}
-			err := writeValue(pos - 1)
-			if err != nil {
-				return "", nil, err
-			}
-
-			if done {
-				break
-			}
-			accumulateDigits = false
+	// sql has only $n placeholders, ? is an operator
+	t := newSQLTokenizer(d, sql)
+	t.question = false
+	for {
+		kind, text := t.next()
+		if text == "" {
+			break
 		}
-
-		if r == '$' && i < lenSQL-1 {
-			digits.Reset()
-			accumulateDigits = true
+		if kind != tokPlaceholder {
+			buf.WriteString(text)
 			continue
 		}
 
-		buf.WriteRune(r)
+		digitsStr := text[1:]
+		pos := 0
+		if len(digitsStr) > 2 {
+			pos, _ = strconv.Atoi(digitsStr)
+		} else {
+			pos, _ = atoiTab[digitsStr]
+		}
+		err := writeValue(pos - 1)
+		if err != nil {
+			return "", nil, err
+		}
 	}
 
 	return buf.String(), newArgs, nil
 }
 
-func interpolate(builder Builder) (string, []interface{}, error) {
+func interpolate(d SQLDialect, builder Builder) (string, []interface{}, error) {
 	sql, args, err := builder.ToSQL()
 	if err != nil {
 		return "", nil, err
 	}
 	if builder.IsInterpolated() {
-		return Interpolate(sql, args)
+		return InterpolateWith(d, sql, args)
+	}
+	if placeholderStyle(d) != OrdinalPlaceholders {
+		return ordinalToDialect(d, questionToOrdinal(d, sql), args)
 	}
 	return sql, args, nil
 }
+
+// questionToOrdinal numbers ? placeholders as $1, $2 ... $n. Numbered ?n
+// placeholders become $n. As in SQLite, a ? is numbered one more than the
+// highest placeholder before it.
+func questionToOrdinal(d SQLDialect, sql string) string {
+	if !strings.Contains(sql, "?") {
+		return sql
+	}
+
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	remapPlaceholders(d, buf, sql, 1)
+	return buf.String()
+}
+
+// ordinalToDialect rewrites $n placeholders in d's PlaceholderStyle.
+func ordinalToDialect(d SQLDialect, sql string, args []interface{}) (string, []interface{}, error) {
+	switch placeholderStyle(d) {
+	case QuestionPlaceholders:
+		return ordinalToQuestion(d, sql, args)
+	case NumberedPlaceholders:
+		return ordinalToNumbered(d, sql, args)
+	}
+	return sql, args, nil
+}
+
+// ordinalToNumbered rewrites $n placeholders as ?n.
+func ordinalToNumbered(d SQLDialect, sql string, args []interface{}) (string, []interface{}, error) {
+	if !strings.Contains(sql, "$") {
+		return sql, args, nil
+	}
+
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	t := newSQLTokenizer(d, sql)
+	t.question = false
+	for {
+		kind, text := t.next()
+		if text == "" {
+			break
+		}
+		if kind != tokPlaceholder {
+			buf.WriteString(text)
+			continue
+		}
+		i, _ := strconv.Atoi(text[1:])
+		if i < 1 || i > len(args) {
+			return "", nil, ErrArgumentMismatch
+		}
+		buf.WriteRune('?')
+		buf.WriteString(text[1:])
+	}
+	return buf.String(), args, nil
+}
+
+// ordinalToQuestion rewrites $n placeholders as ? and orders args by
+// placeholder occurrence. An arg is repeated if its placeholder is.
+func ordinalToQuestion(d SQLDialect, sql string, args []interface{}) (string, []interface{}, error) {
+	if !strings.Contains(sql, "$") {
+		return sql, args, nil
+	}
+
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	var newArgs []interface{}
+	t := newSQLTokenizer(d, sql)
+	t.question = false
+	for {
+		kind, text := t.next()
+		if text == "" {
+			break
+		}
+		if kind != tokPlaceholder {
+			buf.WriteString(text)
+			continue
+		}
+		i, _ := strconv.Atoi(text[1:])
+		if i < 1 || i > len(args) {
+			return "", nil, ErrArgumentMismatch
+		}
+		newArgs = append(newArgs, args[i-1])
+		buf.WriteRune('?')
+	}
+	return buf.String(), newArgs, nil
+}
@@ -1,11 +1,15 @@
 package dat
 
 import (
+	"crypto/md5"
 	"database/sql/driver"
+	"math/big"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/lib/pq"
+	"github.com/matcherino/dat/postgres"
 
 	"gopkg.in/stretchr/testify.v1/assert"
 )
@@ -100,6 +104,91 @@ func TestInterpolateSlices(t *testing.T) {
 	assert.Equal(t, str, "SELECT * FROM x WHERE a = (1) AND b = (1,2,3) AND c = (5,6,7) AND d = ('wat','ok')")
 }
 
+func TestInterpolateNestedSlices(t *testing.T) {
+	args := []interface{}{[][]int{{1, 2}, {3, 4}}, [][]string{{"a", "it's"}}, [][]bool{}}
+
+	str, _, err := Interpolate("SELECT $1, $2, $3", args)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT ARRAY[ARRAY[1,2],ARRAY[3,4]], ARRAY[ARRAY['a','it''s']], '{}'", str)
+
+	_, _, err = Interpolate("SELECT $1", []interface{}{[][]struct{}{{{}}}})
+	assert.Equal(t, ErrInvalidSliceValue, err)
+}
+
+func TestInterpolateBytes(t *testing.T) {
+	b := []byte{0xde, 0xad, 0xbe, 0xef}
+	var nilBytes []byte
+	str, args, err := Interpolate("SELECT * FROM x WHERE a = $1 AND b = $2 AND c = $3", []interface{}{b, &b, nilBytes})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM x WHERE a = '\xdeadbeef'::bytea AND b = '\xdeadbeef'::bytea AND c = NULL`, str)
+	assert.Nil(t, args)
+
+	// only the bytes are passed through without a LiteralDialect
+	plain := struct{ SQLDialect }{postgres.New()}
+	str, args, err = InterpolateWith(plain, "a = $1 AND b = $2", []interface{}{1, b})
+	assert.NoError(t, err)
+	assert.Equal(t, "a = 1 AND b = $1", str)
+	checkSliceEqual(t, []interface{}{b}, args)
+}
+
+// UUID is named as the UUID types of common packages.
+type UUID [16]byte
+
+func TestInterpolateUUID(t *testing.T) {
+	u := UUID{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	var nilUUID *UUID
+	str, _, err := Interpolate("SELECT $1, $2, $3", []interface{}{u, &u, nilUUID})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '6ba7b810-9dad-11d1-80b4-00c04fd430c8'::uuid, '6ba7b810-9dad-11d1-80b4-00c04fd430c8'::uuid, NULL", str)
+}
+
+func TestInterpolateNotUUID(t *testing.T) {
+	// other 16 byte arrays are passed to the driver
+	sum := md5.Sum([]byte("a"))
+	str, args, err := Interpolate("SELECT $1", []interface{}{sum})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT $1", str)
+	checkSliceEqual(t, []interface{}{sum}, args)
+}
+
+func TestInterpolateNet(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("10.1.0.0/16")
+	str, _, err := Interpolate("SELECT $1, $2, $3", []interface{}{net.ParseIP("192.168.0.1"), net.ParseIP("::1"), ipnet})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '192.168.0.1'::inet, '::1'::inet, '10.1.0.0/16'::cidr", str)
+}
+
+func TestInterpolateDuration(t *testing.T) {
+	args := []interface{}{90 * time.Minute, -1500 * time.Millisecond, time.Duration(1), time.Duration(0)}
+	str, _, err := Interpolate("SELECT $1, $2, $3, $4", args)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '5400 seconds'::interval, '-1.5 seconds'::interval, '0.000000001 seconds'::interval, '0 seconds'::interval", str)
+
+	// durations were nanosecond integers before; convert them for bigint columns
+	d := 2 * time.Second
+	str, _, err = Interpolate("SELECT $1, $2", []interface{}{d, int64(d)})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '2 seconds'::interval, 2000000000", str)
+}
+
+func TestInterpolateHstore(t *testing.T) {
+	m := map[string]string{"b": `say "hi"`, "a": "it's", `c\d`: ""}
+	str, _, err := Interpolate("SELECT $1, $2", []interface{}{m, map[string]string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT '"a"=>"it''s", "b"=>"say \"hi\"", "c\\d"=>""'::hstore, ''::hstore`, str)
+}
+
+func TestInterpolateDecimals(t *testing.T) {
+	i, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	args := []interface{}{i, big.NewRat(1, 8), big.NewRat(-5, 2), big.NewRat(42, 1), (*big.Int)(nil)}
+	str, _, err := Interpolate("SELECT $1, $2, $3, $4, $5", args)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT -123456789012345678901234567890::numeric, 0.125::numeric, -2.5::numeric, 42::numeric, NULL", str)
+
+	_, _, err = Interpolate("SELECT $1", []interface{}{big.NewRat(1, 3)})
+	assert.Equal(t, ErrInvalidValue, err)
+}
+
 type myString struct {
 	Present bool
 	Val     string
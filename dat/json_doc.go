@@ -0,0 +1,326 @@
+package dat
+
+import (
+	"strings"
+
+	"github.com/matcherino/dat/common"
+)
+
+// SQLite has no row_to_json(alias.*) so the SQLiteJSON documents built by
+// SelectDocBuilder and JSQLBuilder name each column in json_object. Column
+// names are read from the select list of each query.
+
+// selectListEnd are the keywords which end a select list.
+var selectListEnd = map[string]bool{
+	"EXCEPT":    true,
+	"FROM":      true,
+	"GROUP":     true,
+	"HAVING":    true,
+	"INTERSECT": true,
+	"LIMIT":     true,
+	"ORDER":     true,
+	"UNION":     true,
+	"WHERE":     true,
+	"WINDOW":    true,
+}
+
+// exprKeywords are trailing words which do not alias an expression.
+var exprKeywords = map[string]bool{
+	"END":   true,
+	"FALSE": true,
+	"NULL":  true,
+	"TRUE":  true,
+}
+
+func isSQLWordChar(c byte) bool {
+	return c == '_' ||
+		('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z') ||
+		('0' <= c && c <= '9') ||
+		c >= 0x80
+}
+
+// skipQuoted returns the index after the closing quote of the quoted string
+// or identifier starting at start. Doubled quotes are escapes.
+func skipQuoted(sql string, start int, quote byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		if sql[i] == quote {
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// selectColumnNames returns the names of the result columns of the first
+// top level SELECT in sql.
+func selectColumnNames(sql string) ([]string, error) {
+	var items []string
+	begin := -1
+	depth := 0
+	t := sqlTokenizer{sql: sql, words: true}
+	for {
+		start := t.pos
+		kind, text := t.next()
+		if text == "" {
+			break
+		}
+		switch kind {
+		case tokText:
+			for i := 0; i < len(text); i++ {
+				switch text[i] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				case ',':
+					if depth == 0 && begin >= 0 {
+						items = append(items, sql[begin:start+i])
+						begin = start + i + 1
+					}
+				}
+			}
+		case tokWord:
+			if depth != 0 {
+				continue
+			}
+			word := strings.ToUpper(text)
+			if begin < 0 {
+				if word == "SELECT" {
+					begin = t.pos
+				}
+			} else if selectListEnd[word] {
+				items = append(items, sql[begin:start])
+				return columnNames(items)
+			} else if (word == "DISTINCT" || word == "ALL") && strings.TrimSpace(sql[begin:start]) == "" {
+				begin = t.pos
+			}
+		}
+	}
+	if begin < 0 {
+		return nil, NewError("JSON document query has no select list: " + sql)
+	}
+	items = append(items, sql[begin:])
+	return columnNames(items)
+}
+
+func columnNames(items []string) ([]string, error) {
+	names := make([]string, len(items))
+	for i, item := range items {
+		name, err := columnName(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		names[i] = name
+	}
+	return names, nil
+}
+
+// columnName returns the name of a select list item: its alias, the column
+// of a column reference or the expression itself.
+func columnName(item string) (string, error) {
+	if item == "*" || strings.HasSuffix(item, ".*") {
+		return "", NewError("JSON documents cannot select " + item + ", list the columns")
+	}
+
+	var last string
+	var prefix string
+	if q := item[len(item)-1]; q == '"' || q == '`' {
+		start := -1
+		for i := 0; i < len(item); i++ {
+			if c := item[i]; c == '\'' || c == '"' || c == '`' {
+				end := skipQuoted(item, i, c)
+				if end == len(item) && c == q {
+					start = i
+				}
+				i = end - 1
+			}
+		}
+		if start < 0 {
+			return item, nil
+		}
+		last = strings.Replace(item[start+1:len(item)-1], string(q)+string(q), string(q), -1)
+		prefix = item[:start]
+	} else {
+		start := len(item)
+		for start > 0 && isSQLWordChar(item[start-1]) {
+			start--
+		}
+		if start == len(item) || exprKeywords[strings.ToUpper(item[start:])] {
+			return item, nil
+		}
+		last = item[start:]
+		prefix = item[:start]
+	}
+
+	rest := strings.TrimRight(prefix, " \t\r\n")
+	switch {
+	case rest == "":
+		return last, nil
+	case strings.HasSuffix(prefix, "."):
+		if isColumnRef(item) {
+			return last, nil
+		}
+	case len(rest) < len(prefix):
+		upper := strings.ToUpper(rest)
+		if strings.HasSuffix(upper, "AS") && (len(rest) == 2 || !isSQLWordChar(rest[len(rest)-3])) {
+			return last, nil
+		}
+		if c := rest[len(rest)-1]; isSQLWordChar(c) || c == ')' || c == '"' || c == '`' || c == '\'' {
+			return last, nil
+		}
+	}
+	return item, nil
+}
+
+// isColumnRef returns whether item is a possibly qualified column name.
+func isColumnRef(item string) bool {
+	for i := 0; i < len(item); i++ {
+		c := item[i]
+		if c == '"' || c == '`' {
+			i = skipQuoted(item, i, c) - 1
+			continue
+		}
+		if !isSQLWordChar(c) && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// subAliases returns the aliases of non-nil sub queries.
+func subAliases(lists ...[]*subInfo) []string {
+	var aliases []string
+	for _, subs := range lists {
+		for _, sub := range subs {
+			if sub != nil {
+				aliases = append(aliases, sub.alias)
+			}
+		}
+	}
+	return aliases
+}
+
+// writeJSONObject writes json_object('name', alias."name", ...) for the
+// columns of alias. Columns which are themselves documents are wrapped in
+// json() so they are embedded rather than quoted.
+func writeJSONObject(d SQLDialect, buf common.BufferWriter, alias string, columns []string, jsonColumns []string) {
+	buf.WriteString("json_object(")
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		d.WriteStringLiteral(buf, column)
+		buf.WriteString(", ")
+		isJSON := false
+		for _, c := range jsonColumns {
+			if c == column {
+				isJSON = true
+				break
+			}
+		}
+		if isJSON {
+			buf.WriteString("json(")
+		}
+		buf.WriteString(alias)
+		buf.WriteRune('.')
+		writeQuotedIdentifier(buf, column)
+		if isJSON {
+			buf.WriteRune(')')
+		}
+	}
+	buf.WriteRune(')')
+}
+
+// writeSQLiteMany writes a sub query resulting in an array of objects.
+func writeSQLiteMany(d SQLDialect, buf common.BufferWriter, sub *subInfo, args *[]interface{}, pos *int64) error {
+	columns, err := selectColumnNames(sub.Sql)
+	if err != nil {
+		return err
+	}
+	buf.WriteString("(SELECT CASE WHEN count(*) = 0 THEN NULL ELSE json_group_array(")
+	writeJSONObject(d, buf, "dat__"+sub.alias, columns, sub.jsonColumns)
+	buf.WriteString(") END FROM (")
+	sub.writeRelativeArgs(d, buf, args, pos)
+	buf.WriteString(") AS dat__")
+	buf.WriteString(sub.alias)
+	buf.WriteString(") AS ")
+	d.WriteIdentifier(buf, sub.alias)
+	return nil
+}
+
+// writeSQLiteVector writes a sub query resulting in an array of scalars.
+func writeSQLiteVector(d SQLDialect, buf common.BufferWriter, sub *subInfo, args *[]interface{}, pos *int64) error {
+	columns, err := selectColumnNames(sub.Sql)
+	if err != nil {
+		return err
+	}
+	buf.WriteString("(SELECT CASE WHEN count(*) = 0 THEN NULL ELSE json_group_array(dat__")
+	buf.WriteString(sub.alias)
+	buf.WriteRune('.')
+	writeQuotedIdentifier(buf, columns[0])
+	buf.WriteString(") END FROM (")
+	sub.writeRelativeArgs(d, buf, args, pos)
+	buf.WriteString(") AS dat__")
+	buf.WriteString(sub.alias)
+	buf.WriteString(") AS ")
+	d.WriteIdentifier(buf, sub.alias)
+	return nil
+}
+
+// writeSQLiteOne writes a sub query resulting in a single object.
+func writeSQLiteOne(d SQLDialect, buf common.BufferWriter, sub *subInfo, args *[]interface{}, pos *int64) error {
+	columns, err := selectColumnNames(sub.Sql)
+	if err != nil {
+		return err
+	}
+	buf.WriteString("(SELECT ")
+	writeJSONObject(d, buf, "dat__"+sub.alias, columns, sub.jsonColumns)
+	buf.WriteString(" FROM (")
+	sub.writeRelativeArgs(d, buf, args, pos)
+	buf.WriteString(") AS dat__")
+	buf.WriteString(sub.alias)
+	buf.WriteString(" LIMIT 1) AS ")
+	d.WriteIdentifier(buf, sub.alias)
+	return nil
+}
+
+// writeSQLiteScalar writes a sub query resulting in the first column of its
+// first row.
+func writeSQLiteScalar(d SQLDialect, buf common.BufferWriter, sub *subInfo, args *[]interface{}, pos *int64) error {
+	columns, err := selectColumnNames(sub.Sql)
+	if err != nil {
+		return err
+	}
+	buf.WriteString("(SELECT dat__")
+	buf.WriteString(sub.alias)
+	buf.WriteRune('.')
+	writeQuotedIdentifier(buf, columns[0])
+	buf.WriteString(" FROM (")
+	sub.writeRelativeArgs(d, buf, args, pos)
+	buf.WriteString(") AS dat__")
+	buf.WriteString(sub.alias)
+	buf.WriteString(" LIMIT 1) AS ")
+	d.WriteIdentifier(buf, sub.alias)
+	return nil
+}
+
+// wrapSQLiteDoc wraps the query of a parent document so each row is a
+// single JSON object.
+func wrapSQLiteDoc(d SQLDialect, inner string, jsonColumns []string) (string, error) {
+	columns, err := selectColumnNames(inner)
+	if err != nil {
+		return "", err
+	}
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	buf.WriteString("SELECT ")
+	writeJSONObject(d, buf, "dat__item", columns, jsonColumns)
+	buf.WriteString(" FROM (")
+	buf.WriteString(inner)
+	buf.WriteString(") AS dat__item")
+	return buf.String(), nil
+}
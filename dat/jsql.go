@@ -2,14 +2,13 @@ package dat
 
 import (
 	"strings"
-
-	"github.com/matcherino/str"
 )
 
 // JSQLBuilder builds SQL that returns a JSON row.
 type JSQLBuilder struct {
 	Execer
 
+	dialect          SQLDialect
 	isInterpolated   bool
 	args             []interface{}
 	query            string
@@ -25,46 +24,57 @@ type JSQLBuilder struct {
 // NewJSQLBuilder creates an instance of JSQLBuilder.
 func NewJSQLBuilder(q string, args ...interface{}) *JSQLBuilder {
 	// remove "select" from start of string
-	index := str.IndexOf(strings.ToLower(q), "select", 0)
-	if index < 0 {
-		return &JSQLBuilder{err: logger.Error("Expected query to start with 'select'")}
+	t := sqlTokenizer{sql: q, words: true}
+	for {
+		kind, text := t.next()
+		if text == "" {
+			return &JSQLBuilder{err: logger.Error("Expected query to start with 'select'")}
+		}
+		if kind == tokWord && strings.EqualFold(text, "select") {
+			break
+		}
 	}
 
-	// 7 == len("select") + 1
-	return &JSQLBuilder{query: q[index+7:], args: args, isParent: true, isInterpolated: EnableInterpolation}
+	// skip the space after select
+	index := t.pos
+	if index < len(q) {
+		index++
+	}
+	return &JSQLBuilder{query: q[index:], args: args, isParent: true, isInterpolated: EnableInterpolation}
 }
 
 // Many loads a sub query resulting in an array of rows as an alias.
 func (b *JSQLBuilder) Many(column string, sqlOrBuilder interface{}, a ...interface{}) *JSQLBuilder {
-	b.err = storeExpr(&b.subQueriesMany, "JSQLBuilder.Many", column, sqlOrBuilder, a...)
+	b.err = storeExpr(b.Dialect(), nil, &b.subQueriesMany, "JSQLBuilder.Many", column, sqlOrBuilder, a...)
 	return b
 }
 
 // Vector loads a sub query resulting in an array of homogeneous scalars as an alias.
 func (b *JSQLBuilder) Vector(column string, sqlOrBuilder interface{}, a ...interface{}) *JSQLBuilder {
-	b.err = storeExpr(&b.subQueriesVector, "JSQLBuilder.Vector", column, sqlOrBuilder, a...)
+	b.err = storeExpr(b.Dialect(), nil, &b.subQueriesVector, "JSQLBuilder.Vector", column, sqlOrBuilder, a...)
 	return b
 }
 
 // One loads a query resulting in a single row as an alias.
 func (b *JSQLBuilder) One(column string, sqlOrBuilder interface{}, a ...interface{}) *JSQLBuilder {
-	b.err = storeExpr(&b.subQueriesOne, "JSQLBuilder.One", column, sqlOrBuilder, a...)
+	b.err = storeExpr(b.Dialect(), nil, &b.subQueriesOne, "JSQLBuilder.One", column, sqlOrBuilder, a...)
 	return b
 }
 
 // Scalar loads a query resulting in a single scalar as an alias and embeds the scalar in the parent object, rather than as a child object
 func (b *JSQLBuilder) Scalar(column string, sqlOrBuilder interface{}, a ...interface{}) *JSQLBuilder {
-	b.err = storeExpr(&b.subQueriesScalar, "JSQLBuilder.Scalar", column, sqlOrBuilder, a...)
+	b.err = storeExpr(b.Dialect(), nil, &b.subQueriesScalar, "JSQLBuilder.Scalar", column, sqlOrBuilder, a...)
 	return b
 }
 
 func (b *JSQLBuilder) Union(sqlOrBuilder interface{}, a ...interface{}) *JSQLBuilder {
+	d := b.Dialect()
 	switch t := sqlOrBuilder.(type) {
 	default:
 		b.err = NewError("SelectDocBuilder.Union: sqlOrbuilder accepts only {string, Builder, *SelectDocBuilder} type")
 	case *JSQLBuilder:
 		t.isParent = false
-		sql, args, err := t.ToSQL()
+		sql, args, err := embeddedSQL(d, nil, t)
 		if err != nil {
 			b.err = err
 			return b
@@ -72,14 +82,14 @@ func (b *JSQLBuilder) Union(sqlOrBuilder interface{}, a ...interface{}) *JSQLBui
 		b.union = Expr(sql, args...)
 	case *SelectDocBuilder:
 		t.isParent = false
-		sql, args, err := t.ToSQL()
+		sql, args, err := embeddedSQL(d, nil, t)
 		if err != nil {
 			b.err = err
 			return b
 		}
 		b.union = Expr(sql, args...)
 	case Builder:
-		sql, args, err := t.ToSQL()
+		sql, args, err := embeddedSQL(d, nil, t)
 		if err != nil {
 			b.err = err
 			return b
@@ -94,9 +104,17 @@ func (b *JSQLBuilder) Union(sqlOrBuilder interface{}, a ...interface{}) *JSQLBui
 // ToSQL serialized the SelectBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *JSQLBuilder) ToSQL() (string, []interface{}, error) {
+	return b.toSQL(b.Dialect())
+}
+
+// toSQL is ToSQL with the dialect d.
+func (b *JSQLBuilder) toSQL(d SQLDialect) (string, []interface{}, error) {
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
+	if err := ErrUnsupported(d, FeatureJSONDocuments); err != nil {
+		return NewDatSQLErr(err)
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
@@ -122,57 +140,86 @@ func (b *JSQLBuilder) ToSQL() (string, []interface{}, error) {
 		) as item
 	*/
 
-	if b.isParent {
+	sqlite := DialectJSONStyle(d) == SQLiteJSON
+	if b.isParent && !sqlite {
 		buf.WriteString("SELECT row_to_json(dat__item.*) FROM ( SELECT ")
 	} else {
 		buf.WriteString("SELECT ")
 	}
 
 	for _, sub := range b.subQueriesMany {
+		if sqlite {
+			if err := writeSQLiteMany(d, buf, sub, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+			buf.WriteString(", ")
+			continue
+		}
 		buf.WriteString("(SELECT array_agg(dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(".*) FROM (")
-		sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(") AS ")
-		writeQuotedIdentifier(buf, sub.alias)
+		d.WriteIdentifier(buf, sub.alias)
 		buf.WriteString(", ")
 	}
 
 	for _, sub := range b.subQueriesVector {
+		if sqlite {
+			if err := writeSQLiteVector(d, buf, sub, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+			buf.WriteString(", ")
+			continue
+		}
 		buf.WriteString("(SELECT array_agg(dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(".dat__scalar) FROM (")
-		sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString("(dat__scalar)) AS ")
-		writeQuotedIdentifier(buf, sub.alias)
+		d.WriteIdentifier(buf, sub.alias)
 		buf.WriteString(", ")
 	}
 
 	for _, sub := range b.subQueriesOne {
+		if sqlite {
+			if err := writeSQLiteOne(d, buf, sub, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+			buf.WriteString(", ")
+			continue
+		}
 		buf.WriteString("(SELECT row_to_json(dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(".*) FROM (")
-		sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(") AS ")
-		writeQuotedIdentifier(buf, sub.alias)
+		d.WriteIdentifier(buf, sub.alias)
 		buf.WriteString(", ")
 	}
 
 	for _, sub := range b.subQueriesScalar {
+		if sqlite {
+			if err := writeSQLiteScalar(d, buf, sub, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+			buf.WriteString(", ")
+			continue
+		}
 		buf.WriteString("(SELECT dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(".dat__scalar FROM (")
-		sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString("(dat__scalar) limit 1) AS ")
-		writeQuotedIdentifier(buf, sub.alias)
+		d.WriteIdentifier(buf, sub.alias)
 		buf.WriteString(", ")
 	}
 
@@ -180,11 +227,23 @@ func (b *JSQLBuilder) ToSQL() (string, []interface{}, error) {
 
 	if b.union != nil {
 		buf.WriteString(" UNION ")
-		b.union.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		b.union.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 	}
 
 	if b.isParent {
+		if sqlite {
+			sql, err := wrapSQLiteDoc(d, buf.String(), b.jsonColumns())
+			if err != nil {
+				return NewDatSQLErr(err)
+			}
+			return sql, args, nil
+		}
 		buf.WriteString(`) as dat__item`)
 	}
 	return buf.String(), args, nil
 }
+
+// jsonColumns returns the aliases of sub queries which result in documents.
+func (b *JSQLBuilder) jsonColumns() []string {
+	return subAliases(b.subQueriesMany, b.subQueriesOne, b.subQueriesVector)
+}
@@ -0,0 +1,393 @@
+package dat
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/matcherino/dat/common"
+)
+
+// CursorSecret signs the cursors of KeysetPaginate with HMAC-SHA256 when
+// set. Cursors which are not signed with it are rejected with
+// ErrInvalidCursor.
+var CursorSecret []byte
+
+// ErrInvalidCursor is returned for a KeysetPaginate cursor which cannot be
+// decoded or has an invalid signature.
+var ErrInvalidCursor = NewError("invalid pagination cursor")
+
+// KeysetPage holds the cursors of a page fetched with KeysetPaginate.
+type KeysetPage struct {
+	// Next is the cursor of the following page, "" on the last page.
+	Next string
+	// Prev is the cursor of the preceding page, "" on the first page.
+	Prev string
+}
+
+// keysetColumn is a column of a keyset ordering such as
+// "p.created_at DESC NULLS LAST".
+type keysetColumn struct {
+	column string
+	// key is the db name of the column in result rows
+	key        string
+	desc       bool
+	nullsFirst bool
+}
+
+// keyset is the ordering, cursor and page size of KeysetPaginate.
+type keyset struct {
+	columns  []keysetColumn
+	values   []interface{}
+	before   bool
+	hasPrev  bool
+	pageSize uint64
+}
+
+// cursorPayload is the JSON encoded in a cursor.
+type cursorPayload struct {
+	Values []interface{} `json:"v"`
+	Before bool          `json:"b,omitempty"`
+}
+
+// KeysetPaginate orders the statement by orderColumns and limits it to the
+// pageSize rows following the row cursor was made from, or preceding it
+// for a Prev cursor. An empty cursor is the first page.
+//
+// Each column is "column [ASC|DESC] [NULLS FIRST|NULLS LAST]". Nulls are
+// last in ascending order unless specified, as in Postgres. The last
+// column must be unique, such as the primary key. Rows must have a db field
+// for each column, named after the part of the column following any table
+// alias. Use QueryKeyset to fetch the page and its cursors.
+func (b *SelectBuilder) KeysetPaginate(orderColumns []string, cursor string, pageSize uint64) *SelectBuilder {
+	k, err := newKeyset(orderColumns, cursor, pageSize)
+	if err != nil {
+		b.err = err
+	}
+	b.keyset = k
+	return b
+}
+
+// keysetInfo returns the keyset of KeysetPaginate or nil.
+func (b *SelectBuilder) keysetInfo() *keyset {
+	return b.keyset
+}
+
+func newKeyset(orderColumns []string, cursor string, pageSize uint64) (*keyset, error) {
+	if len(orderColumns) == 0 {
+		return nil, NewError("KeysetPaginate requires 1 or more columns")
+	}
+	if pageSize == 0 {
+		return nil, NewError("KeysetPaginate requires a page size")
+	}
+	k := &keyset{pageSize: pageSize}
+	for _, s := range orderColumns {
+		c, err := parseKeysetColumn(s)
+		if err != nil {
+			return nil, err
+		}
+		k.columns = append(k.columns, c)
+	}
+	if cursor == "" {
+		return k, nil
+	}
+
+	payload, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload.Values) != len(k.columns) {
+		return nil, ErrInvalidCursor
+	}
+	k.values = payload.Values
+	k.before = payload.Before
+	k.hasPrev = !payload.Before
+	return k, nil
+}
+
+func parseKeysetColumn(s string) (keysetColumn, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return keysetColumn{}, NewError("KeysetPaginate: empty column")
+	}
+	c := keysetColumn{column: fields[0]}
+	c.key = c.column[strings.LastIndexByte(c.column, '.')+1:]
+	rest := strings.ToUpper(strings.Join(fields[1:], " "))
+	if strings.HasPrefix(rest, "DESC") {
+		c.desc = true
+		rest = strings.TrimSpace(rest[4:])
+	} else if strings.HasPrefix(rest, "ASC") {
+		rest = strings.TrimSpace(rest[3:])
+	}
+	switch rest {
+	case "":
+		c.nullsFirst = c.desc
+	case "NULLS FIRST":
+		c.nullsFirst = true
+	case "NULLS LAST":
+	default:
+		return keysetColumn{}, NewError("KeysetPaginate: invalid ordering " + s)
+	}
+	return c, nil
+}
+
+// orderColumns returns the columns in the order rows are fetched, which is
+// reversed when fetching the page before the cursor.
+func (k *keyset) orderColumns() []keysetColumn {
+	if !k.before {
+		return k.columns
+	}
+	cols := make([]keysetColumn, len(k.columns))
+	for i, c := range k.columns {
+		c.desc = !c.desc
+		c.nullsFirst = !c.nullsFirst
+		cols[i] = c
+	}
+	return cols
+}
+
+// condition returns the condition selecting the rows following the cursor
+// in fetch order or nil for the first page.
+//
+// Rows follow the cursor if they equal it in the first n-1 columns and
+// follow it in the nth, for any n. Columns are compared one at a time
+// since a row comparison cannot mix ASC, DESC and nulls.
+func (k *keyset) condition() Condition {
+	if k.values == nil {
+		return nil
+	}
+	cols := k.orderColumns()
+	var or []Condition
+	for i, c := range cols {
+		after := keysetAfter(c, k.values[i])
+		if after == nil {
+			continue
+		}
+		and := make([]Condition, 0, i+1)
+		for j := 0; j < i; j++ {
+			if k.values[j] == nil {
+				and = append(and, IsNull(cols[j].column))
+			} else {
+				and = append(and, &compareCondition{column: cols[j].column, op: " = ", value: k.values[j]})
+			}
+		}
+		or = append(or, And(append(and, after)...))
+	}
+	return Or(or...)
+}
+
+// keysetAfter returns the condition selecting the values of c following
+// value or nil if there are none.
+func keysetAfter(c keysetColumn, value interface{}) Condition {
+	if value == nil {
+		if c.nullsFirst {
+			return Not(IsNull(c.column))
+		}
+		return nil
+	}
+	var cmp Condition
+	if c.desc {
+		cmp = Lt(c.column, value)
+	} else {
+		cmp = Gt(c.column, value)
+	}
+	if c.nullsFirst {
+		return cmp
+	}
+	return Or(cmp, IsNull(c.column))
+}
+
+// whereFragments returns fragments and the condition of the keyset, if
+// any.
+func (k *keyset) whereFragments(fragments []*whereFragment) []*whereFragment {
+	cond := k.condition()
+	if cond == nil {
+		return fragments
+	}
+	return append(append([]*whereFragment{}, fragments...), &whereFragment{Cond: cond})
+}
+
+// writeOrderBy writes the columns of k in fetch order, if k is not nil,
+// followed by orderBys.
+func writeOrderBy(d SQLDialect, buf common.BufferWriter, k *keyset, orderBys []*whereFragment, args *[]interface{}, pos *int64) error {
+	if k == nil {
+		return writeCommaFragmentsToSQL(d, buf, orderBys, args, pos)
+	}
+	nullsOrdering := DialectSupports(d, FeatureNullsOrdering)
+	for i, c := range k.orderColumns() {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if !nullsOrdering {
+			// false sorts before true
+			buf.WriteString(c.column)
+			if c.nullsFirst {
+				buf.WriteString(" IS NULL DESC, ")
+			} else {
+				buf.WriteString(" IS NULL ASC, ")
+			}
+		}
+		buf.WriteString(c.column)
+		if c.desc {
+			buf.WriteString(" DESC")
+		} else {
+			buf.WriteString(" ASC")
+		}
+		if nullsOrdering {
+			if c.nullsFirst {
+				buf.WriteString(" NULLS FIRST")
+			} else {
+				buf.WriteString(" NULLS LAST")
+			}
+		}
+	}
+	if len(orderBys) == 0 {
+		return nil
+	}
+	buf.WriteString(", ")
+	return writeCommaFragmentsToSQL(d, buf, orderBys, args, pos)
+}
+
+// KeysetResult trims the extra row fetched by KeysetPaginate from the rows
+// dest points to, restores their order and returns the page cursors.
+// Runners call it after querying a builder using KeysetPaginate.
+func KeysetResult(builder Builder, dest interface{}) (*KeysetPage, error) {
+	kb, ok := builder.(interface {
+		keysetInfo() *keyset
+	})
+	if !ok || kb.keysetInfo() == nil {
+		return nil, NewError("KeysetResult requires a builder using KeysetPaginate")
+	}
+	k := kb.keysetInfo()
+
+	ptr := reflect.ValueOf(dest)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Slice {
+		return nil, NewError("KeysetResult requires a pointer to a slice")
+	}
+	rows := ptr.Elem()
+	more := uint64(rows.Len()) > k.pageSize
+	if more {
+		rows.Set(rows.Slice(0, int(k.pageSize)))
+	}
+	if k.before {
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	page := &KeysetPage{}
+	if rows.Len() == 0 {
+		return page, nil
+	}
+	hasNext, hasPrev := more, k.hasPrev
+	if k.before {
+		hasNext, hasPrev = true, more
+	}
+	var err error
+	if hasNext {
+		if page.Next, err = k.cursor(rows.Index(rows.Len()-1), false); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if page.Prev, err = k.cursor(rows.Index(0), true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// cursor returns the cursor of the rows following or, if before is set,
+// preceding row.
+func (k *keyset) cursor(row reflect.Value, before bool) (string, error) {
+	row = reflect.Indirect(row)
+	if row.Kind() != reflect.Struct {
+		return "", NewError("KeysetResult requires a slice of structs")
+	}
+	keys := make([]string, len(k.columns))
+	for i, c := range k.columns {
+		keys[i] = c.key
+	}
+	values, err := valuesFor(row.Type(), row, keys)
+	if err != nil {
+		return "", err
+	}
+	for i, v := range values {
+		if values[i], err = cursorValue(v); err != nil {
+			return "", err
+		}
+	}
+	return encodeCursor(&cursorPayload{Values: values, Before: before})
+}
+
+// cursorValue returns the value of a key as it is passed to the driver so
+// types such as sql.NullString encode as their value or null.
+func cursorValue(v interface{}) (interface{}, error) {
+	valuer, ok := v.(driver.Valuer)
+	if !ok {
+		return v, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	return valuer.Value()
+}
+
+func encodeCursor(payload *cursorPayload) (string, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	cursor := base64.RawURLEncoding.EncodeToString(b)
+	if len(CursorSecret) > 0 {
+		cursor += "." + base64.RawURLEncoding.EncodeToString(signCursor(b))
+	}
+	return cursor, nil
+}
+
+func decodeCursor(cursor string) (*cursorPayload, error) {
+	data, sig := cursor, ""
+	if i := strings.IndexByte(cursor, '.'); i >= 0 {
+		data, sig = cursor[:i], cursor[i+1:]
+	}
+	b, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if len(CursorSecret) > 0 {
+		mac, err := base64.RawURLEncoding.DecodeString(sig)
+		if err != nil || !hmac.Equal(mac, signCursor(b)) {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	var payload cursorPayload
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	// numbers keep their precision as int64 where possible
+	for i, v := range payload.Values {
+		if n, ok := v.(json.Number); ok {
+			if iv, err := n.Int64(); err == nil {
+				payload.Values[i] = iv
+			} else if fv, err := n.Float64(); err == nil {
+				payload.Values[i] = fv
+			}
+		}
+	}
+	return &payload, nil
+}
+
+func signCursor(b []byte) []byte {
+	mac := hmac.New(sha256.New, CursorSecret)
+	mac.Write(b)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,179 @@
+package dat
+
+import (
+	"database/sql"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+
+	"github.com/matcherino/dat/postgres"
+)
+
+type keysetRow struct {
+	ID    int64     `db:"id"`
+	Score NullInt64 `db:"score"`
+	Name  string    `db:"name"`
+}
+
+func TestKeysetFirstPage(t *testing.T) {
+	sql, args, err := Select("id", "score").From("t").
+		Where("x = $1", 1).
+		KeysetPaginate([]string{"t.score DESC", "id"}, "", 2).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT id, score FROM t WHERE (x = $1)
+		ORDER BY t.score DESC NULLS FIRST, id ASC NULLS LAST LIMIT 3`), stripWS(sql))
+	assert.Equal(t, []interface{}{1}, args)
+}
+
+func TestKeysetCursors(t *testing.T) {
+	order := []string{"score DESC NULLS LAST", "id"}
+	b := Select("id", "score").From("t").KeysetPaginate(order, "", 2)
+	rows := []keysetRow{
+		{ID: 1, Score: NullInt64From(30)},
+		{ID: 2, Score: NullInt64From(20)},
+		{ID: 3, Score: NullInt64From(20)},
+	}
+	page, err := KeysetResult(b, &rows)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "", page.Prev)
+	assert.NotEqual(t, "", page.Next)
+
+	sql, args, err := Select("id", "score").From("t").KeysetPaginate(order, page.Next, 2).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT id, score FROM t
+		WHERE (score < $1 OR score IS NULL OR (score = $2 AND (id > $3 OR id IS NULL)))
+		ORDER BY score DESC NULLS LAST, id ASC NULLS LAST LIMIT 3`), stripWS(sql))
+	assert.Equal(t, []interface{}{int64(20), int64(20), int64(2)}, args)
+
+	// the next page ends in a null score and has no more rows
+	b = Select("id", "score").From("t").KeysetPaginate(order, page.Next, 2)
+	rows = []keysetRow{{ID: 3, Score: NullInt64From(20)}, {ID: 4}}
+	page, err = KeysetResult(b, &rows)
+	assert.NoError(t, err)
+	assert.Equal(t, "", page.Next)
+	assert.NotEqual(t, "", page.Prev)
+
+	// the previous page is fetched in reverse
+	b = Select("id", "score").From("t").KeysetPaginate(order, page.Prev, 2)
+	sql, args, err = b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT id, score FROM t
+		WHERE (score > $1 OR (score = $2 AND id < $3))
+		ORDER BY score ASC NULLS FIRST, id DESC NULLS FIRST LIMIT 3`), stripWS(sql))
+	assert.Equal(t, []interface{}{int64(20), int64(20), int64(3)}, args)
+
+	rows = []keysetRow{{ID: 2, Score: NullInt64From(20)}, {ID: 1, Score: NullInt64From(30)}}
+	page, err = KeysetResult(b, &rows)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rows[0].ID)
+	assert.Equal(t, int64(2), rows[1].ID)
+	assert.Equal(t, "", page.Prev)
+	assert.NotEqual(t, "", page.Next)
+}
+
+func TestKeysetNullCursor(t *testing.T) {
+	order := []string{"score DESC NULLS LAST", "id"}
+	b := Select("id", "score").From("t").KeysetPaginate(order, "", 1)
+	rows := []keysetRow{{ID: 4}, {ID: 5}}
+	page, err := KeysetResult(b, &rows)
+	assert.NoError(t, err)
+
+	sql, args, err := Select("id", "score").From("t").KeysetPaginate(order, page.Next, 1).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT id, score FROM t WHERE (score IS NULL AND (id > $1 OR id IS NULL))
+		ORDER BY score DESC NULLS LAST, id ASC NULLS LAST LIMIT 2`), stripWS(sql))
+	assert.Equal(t, []interface{}{int64(4)}, args)
+}
+
+func TestKeysetNullableCursor(t *testing.T) {
+	type row struct {
+		ID   int64          `db:"id"`
+		Name sql.NullString `db:"name"`
+	}
+	order := []string{"name", "id"}
+	b := Select("id", "name").From("t").KeysetPaginate(order, "", 1)
+	rows := []row{{ID: 1, Name: sql.NullString{String: "a", Valid: true}}, {ID: 2}}
+	page, err := KeysetResult(b, &rows)
+	assert.NoError(t, err)
+
+	query, args, err := Select("id", "name").From("t").KeysetPaginate(order, page.Next, 1).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT id, name FROM t
+		WHERE (name > $1 OR name IS NULL OR (name = $2 AND (id > $3 OR id IS NULL)))
+		ORDER BY name ASC NULLS LAST, id ASC NULLS LAST LIMIT 2`), stripWS(query))
+	assert.Equal(t, []interface{}{"a", "a", int64(1)}, args)
+
+	// a null key
+	b = Select("id", "name").From("t").KeysetPaginate(order, "", 1)
+	rows = []row{{ID: 2}, {ID: 3}}
+	page, err = KeysetResult(b, &rows)
+	assert.NoError(t, err)
+
+	query, args, err = Select("id", "name").From("t").KeysetPaginate(order, page.Next, 1).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT id, name FROM t WHERE (name IS NULL AND (id > $1 OR id IS NULL))
+		ORDER BY name ASC NULLS LAST, id ASC NULLS LAST LIMIT 2`), stripWS(query))
+	assert.Equal(t, []interface{}{int64(2)}, args)
+}
+
+func TestKeysetSignedCursor(t *testing.T) {
+	CursorSecret = []byte("secret")
+	defer func() { CursorSecret = nil }()
+
+	order := []string{"id"}
+	b := Select("id").From("t").KeysetPaginate(order, "", 1)
+	rows := []keysetRow{{ID: 1}, {ID: 2}}
+	page, err := KeysetResult(b, &rows)
+	assert.NoError(t, err)
+
+	_, _, err = Select("id").From("t").KeysetPaginate(order, page.Next, 1).ToSQL()
+	assert.NoError(t, err)
+
+	unsigned := page.Next[:len(page.Next)-2]
+	_, _, err = Select("id").From("t").KeysetPaginate(order, unsigned, 1).ToSQL()
+	assert.Equal(t, ErrInvalidCursor, err)
+
+	CursorSecret = []byte("other")
+	_, _, err = Select("id").From("t").KeysetPaginate(order, page.Next, 1).ToSQL()
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestKeysetErrors(t *testing.T) {
+	_, _, err := Select("id").From("t").KeysetPaginate([]string{"id"}, "!!", 1).ToSQL()
+	assert.Equal(t, ErrInvalidCursor, err)
+	_, _, err = Select("id").From("t").KeysetPaginate([]string{"id SIDEWAYS"}, "", 1).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Select("id").From("t").KeysetPaginate(nil, "", 1).ToSQL()
+	assert.Error(t, err)
+	_, err = KeysetResult(Select("id").From("t"), &[]keysetRow{})
+	assert.Error(t, err)
+}
+
+func TestKeysetDialect(t *testing.T) {
+	sql, _, err := Select("id").From("t").SetDialect(questionDialect{postgres.New()}).
+		KeysetPaginate([]string{"score DESC", "id"}, "", 10).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT id FROM t
+		ORDER BY score IS NULL DESC, score DESC, id IS NULL ASC, id ASC LIMIT 11`), stripWS(sql))
+}
+
+func TestSelectDocKeyset(t *testing.T) {
+	sql, _, err := SelectDoc("id").From("t").
+		KeysetPaginate([]string{"id"}, "", 10).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT row_to_json(dat__item.*) FROM (
+			SELECT id FROM t ORDER BY id ASC NULLS LAST LIMIT 11
+		) as dat__item`), stripWS(sql))
+}
@@ -0,0 +1,195 @@
+package dat
+
+import (
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/matcherino/dat/common"
+)
+
+// writeLiteral writes the values interpolated with a LiteralDialect.
+// Returns false if v is not such a value or d is not a LiteralDialect.
+func writeLiteral(d SQLDialect, buf common.BufferWriter, v interface{}) (bool, error) {
+	ld, ok := d.(LiteralDialect)
+	if !ok {
+		return false, nil
+	}
+
+	switch t := v.(type) {
+	case []byte:
+		if t == nil {
+			buf.WriteString("NULL")
+		} else {
+			ld.WriteBytesLiteral(buf, t)
+		}
+		return true, nil
+	case *[]byte:
+		if t == nil || *t == nil {
+			buf.WriteString("NULL")
+		} else {
+			ld.WriteBytesLiteral(buf, *t)
+		}
+		return true, nil
+	case net.IP:
+		if t == nil {
+			buf.WriteString("NULL")
+		} else {
+			ld.WriteInetLiteral(buf, t)
+		}
+		return true, nil
+	case net.IPNet:
+		ld.WriteCIDRLiteral(buf, &t)
+		return true, nil
+	case *net.IPNet:
+		if t == nil {
+			buf.WriteString("NULL")
+		} else {
+			ld.WriteCIDRLiteral(buf, t)
+		}
+		return true, nil
+	case time.Duration:
+		ld.WriteIntervalLiteral(buf, t)
+		return true, nil
+	case *time.Duration:
+		if t == nil {
+			buf.WriteString("NULL")
+		} else {
+			ld.WriteIntervalLiteral(buf, *t)
+		}
+		return true, nil
+	case map[string]string:
+		if t == nil {
+			buf.WriteString("NULL")
+		} else {
+			ld.WriteHstoreLiteral(buf, t)
+		}
+		return true, nil
+	case *big.Int:
+		if t == nil {
+			buf.WriteString("NULL")
+		} else {
+			ld.WriteDecimalLiteral(buf, t.String())
+		}
+		return true, nil
+	case *big.Rat:
+		if t == nil {
+			buf.WriteString("NULL")
+			return true, nil
+		}
+		s, ok := ratDecimal(t)
+		if !ok {
+			return true, ErrInvalidValue
+		}
+		ld.WriteDecimalLiteral(buf, s)
+		return true, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.IsValid() && isUUID(rv.Type()) {
+		var uuid [16]byte
+		reflect.Copy(reflect.ValueOf(&uuid).Elem(), rv)
+		ld.WriteUUIDLiteral(buf, uuid)
+		return true, nil
+	}
+	return false, nil
+}
+
+// isUUID returns whether typ is a UUID type, a [16]byte type named UUID as
+// in every common package. Other 16 byte arrays such as an md5.Sum are not
+// UUIDs.
+func isUUID(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Array && typ.Len() == 16 && typ.Elem().Kind() == reflect.Uint8 &&
+		strings.EqualFold(typ.Name(), "UUID")
+}
+
+// isBinary returns whether v is passed to the driver as an argument when
+// the dialect does not write it as a literal.
+func isBinary(v interface{}) bool {
+	switch v.(type) {
+	case []byte, *[]byte, net.IP:
+		return true
+	}
+	return false
+}
+
+// isNestedSlice returns whether typ is a slice of slices other than
+// [][]byte.
+func isNestedSlice(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Slice {
+		return false
+	}
+	elem := typ.Elem()
+	return elem.Kind() == reflect.Slice && elem.Elem().Kind() != reflect.Uint8
+}
+
+// writeArrayLiteral writes a slice as a possibly multi-dimensional array.
+func writeArrayLiteral(ld LiteralDialect, d SQLDialect, buf common.BufferWriter, rv reflect.Value) error {
+	return ld.WriteArrayLiteral(buf, rv.Len(), func(i int) error {
+		elem := rv.Index(i)
+		kind := elem.Kind()
+		switch {
+		case kind == reflect.Slice:
+			return writeArrayLiteral(ld, d, buf, elem)
+		case kind == reflect.String:
+			str := elem.String()
+			if !utf8.ValidString(str) {
+				return ErrNotUTF8
+			}
+			d.WriteStringLiteral(buf, str)
+		case isInt(kind):
+			writeInt64(buf, elem.Int())
+		case isUint(kind):
+			writeUint64(buf, elem.Uint())
+		case isFloat(kind):
+			buf.WriteString(strconv.FormatFloat(elem.Float(), 'f', -1, 64))
+		case kind == reflect.Bool:
+			if elem.Bool() {
+				buf.WriteString("TRUE")
+			} else {
+				buf.WriteString("FALSE")
+			}
+		default:
+			return ErrInvalidSliceValue
+		}
+		return nil
+	})
+}
+
+// ratDecimal returns r as an exact decimal. Returns false if r has no
+// finite decimal representation, such as 1/3.
+func ratDecimal(r *big.Rat) (string, bool) {
+	if r.IsInt() {
+		return r.Num().String(), true
+	}
+
+	// the decimal is finite if the denominator only has factors 2 and 5
+	denom := new(big.Int).Set(r.Denom())
+	countFactor := func(factor int64) int {
+		n := 0
+		f := big.NewInt(factor)
+		for {
+			q, m := new(big.Int).QuoRem(denom, f, new(big.Int))
+			if m.Sign() != 0 {
+				return n
+			}
+			denom = q
+			n++
+		}
+	}
+	prec := countFactor(2)
+	if fives := countFactor(5); fives > prec {
+		prec = fives
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return "", false
+	}
+	return r.FloatString(prec), true
+}
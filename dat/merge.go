@@ -0,0 +1,289 @@
+package dat
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/matcherino/dat/common"
+)
+
+// MergeBuilder contains the clauses for a MERGE statement, which inserts,
+// updates or deletes rows of a table depending on whether they match the
+// rows of a source.
+//
+//	MERGE INTO people AS p
+//	USING (...) AS s ON p.id = s.id
+//	WHEN MATCHED AND s.deleted THEN DELETE
+//	WHEN MATCHED THEN UPDATE SET name = s.name
+//	WHEN NOT MATCHED THEN INSERT (id, name) VALUES (s.id, s.name)
+type MergeBuilder struct {
+	Execer
+
+	dialect        SQLDialect
+	err            error
+	isInterpolated bool
+	onFragments    []*whereFragment
+	source         interface{}
+	sourceAlias    string
+	table          string
+	whens          []*MergeWhen
+}
+
+// MergeWhen is a WHEN [NOT] MATCHED clause of a MERGE. Its action is set
+// with Update, Delete, Insert or DoNothing, which return the MergeBuilder.
+type MergeWhen struct {
+	builder    *MergeBuilder
+	matched    bool
+	condition  *whereFragment
+	action     string
+	setClauses []*setClause
+	columns    []string
+	values     []interface{}
+}
+
+// NewMergeBuilder creates a new MergeBuilder for the given table.
+func NewMergeBuilder(table string) *MergeBuilder {
+	if table == "" {
+		logger.Error("Merge requires a table name")
+		return nil
+	}
+	return &MergeBuilder{table: table, isInterpolated: EnableInterpolation}
+}
+
+// Using sets the source rows which are merged into the table. source is a
+// table name, a Builder or a slice of structs or scalars as in
+// SelectDocBuilder.With. Builders and slices require an alias.
+func (b *MergeBuilder) Using(source interface{}, alias string) *MergeBuilder {
+	switch source.(type) {
+	case string:
+	case Builder:
+		if alias == "" {
+			b.err = NewError("Using requires an alias for sub queries")
+		}
+	default:
+		if source == nil || reflect.TypeOf(source).Kind() != reflect.Slice {
+			b.err = NewError("Using accepts only {string, Builder, slice} type")
+		} else if alias == "" {
+			b.err = NewError("Using requires an alias for slices")
+		}
+	}
+	b.source = source
+	b.sourceAlias = alias
+	return b
+}
+
+// On appends a join condition of the table and source for the given string
+// and args, map of column/value pairs or Condition. Conditions are joined
+// with AND.
+func (b *MergeBuilder) On(onSQLOrMap interface{}, args ...interface{}) *MergeBuilder {
+	fragment, err := newWhereFragment(onSQLOrMap, args)
+	if err != nil {
+		b.err = err
+	} else {
+		b.onFragments = append(b.onFragments, fragment)
+	}
+	return b
+}
+
+// WhenMatched appends a WHEN MATCHED clause for rows of the table matching a
+// source row. The optional condition is a string followed by its args, a
+// map of column/value pairs or a Condition as accepted by Where.
+func (b *MergeBuilder) WhenMatched(condition ...interface{}) *MergeWhen {
+	return b.addWhen(true, condition)
+}
+
+// WhenNotMatched appends a WHEN NOT MATCHED clause for source rows matching
+// no row of the table. The optional condition is as in WhenMatched.
+func (b *MergeBuilder) WhenNotMatched(condition ...interface{}) *MergeWhen {
+	return b.addWhen(false, condition)
+}
+
+func (b *MergeBuilder) addWhen(matched bool, condition []interface{}) *MergeWhen {
+	w := &MergeWhen{builder: b, matched: matched}
+	if len(condition) > 0 {
+		fragment, err := newWhereFragment(condition[0], condition[1:])
+		if err != nil {
+			b.err = err
+		}
+		w.condition = fragment
+	}
+	b.whens = append(b.whens, w)
+	return w
+}
+
+// Update updates the matched row setting the columns of set. Values may be
+// Expressions referring to the source such as Expr("s.name").
+func (w *MergeWhen) Update(set map[string]interface{}) *MergeBuilder {
+	if !w.matched {
+		w.builder.err = NewError("Update requires WhenMatched")
+	}
+	if len(set) == 0 {
+		w.builder.err = NewError("Update requires 1 or more columns")
+	}
+	columns := make([]string, 0, len(set))
+	for column := range set {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		w.setClauses = append(w.setClauses, &setClause{column: column, value: set[column]})
+	}
+	w.action = "UPDATE"
+	return w.builder
+}
+
+// Delete deletes the matched row.
+func (w *MergeWhen) Delete() *MergeBuilder {
+	if !w.matched {
+		w.builder.err = NewError("Delete requires WhenMatched")
+	}
+	w.action = "DELETE"
+	return w.builder
+}
+
+// Insert inserts a row of values into columns. Without values the source
+// columns of the same names are inserted.
+func (w *MergeWhen) Insert(columns []string, values ...interface{}) *MergeBuilder {
+	if w.matched {
+		w.builder.err = NewError("Insert requires WhenNotMatched")
+	}
+	if len(columns) == 0 {
+		w.builder.err = NewError("Insert requires 1 or more columns")
+	}
+	if len(values) > 0 && len(values) != len(columns) {
+		w.builder.err = NewError("the number of values does not match the number of columns")
+	}
+	w.columns = columns
+	w.values = values
+	w.action = "INSERT"
+	return w.builder
+}
+
+// DoNothing skips the row.
+func (w *MergeWhen) DoNothing() *MergeBuilder {
+	w.action = "DO NOTHING"
+	return w.builder
+}
+
+// ToSQL serialized the MergeBuilder to a SQL string
+// It returns the string with placeholders and a slice of query arguments
+func (b *MergeBuilder) ToSQL() (string, []interface{}, error) {
+	return b.toSQL(b.Dialect())
+}
+
+// toSQL is ToSQL with the dialect d.
+func (b *MergeBuilder) toSQL(d SQLDialect) (string, []interface{}, error) {
+	if b.err != nil {
+		return NewDatSQLErr(b.err)
+	}
+	if err := ErrUnsupported(d, FeatureMerge); err != nil {
+		return NewDatSQLErr(err)
+	}
+	if b.source == nil {
+		return NewDatSQLError("no source specified, call Using")
+	}
+	if len(b.onFragments) == 0 {
+		return NewDatSQLError("no join condition specified, call On")
+	}
+	if len(b.whens) == 0 {
+		return NewDatSQLError("no WHEN clauses specified")
+	}
+
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	var args []interface{}
+	var placeholderStartPos int64 = 1
+
+	buf.WriteString("MERGE INTO ")
+	buf.WriteString(b.table)
+	buf.WriteString(" USING ")
+
+	switch t := b.source.(type) {
+	case string:
+		buf.WriteString(t)
+	case Builder:
+		if err := writeSubquery(d, buf, t, true, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
+	default:
+		sql, tableArgs, err := arrayToTable(d, t)
+		if err != nil {
+			return NewDatSQLErr(err)
+		}
+		buf.WriteRune('(')
+		if err := writeBuilderArgs(d, buf, sql, tableArgs, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
+		buf.WriteRune(')')
+	}
+	if b.sourceAlias != "" {
+		buf.WriteString(" AS ")
+		buf.WriteString(b.sourceAlias)
+	}
+
+	buf.WriteString(" ON ")
+	if err := writeAndFragmentsToSQL(d, buf, b.onFragments, &args, &placeholderStartPos); err != nil {
+		return NewDatSQLErr(err)
+	}
+
+	for _, w := range b.whens {
+		if w.matched {
+			buf.WriteString(" WHEN MATCHED")
+		} else {
+			buf.WriteString(" WHEN NOT MATCHED")
+		}
+		if w.condition != nil {
+			buf.WriteString(" AND ")
+			if err := writeAndFragmentsToSQL(d, buf, []*whereFragment{w.condition}, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+		}
+		buf.WriteString(" THEN ")
+
+		switch w.action {
+		case "":
+			return NewDatSQLError("WHEN clause requires Update, Delete, Insert or DoNothing")
+		case "UPDATE":
+			buf.WriteString("UPDATE SET ")
+			if err := writeSetClauses(d, buf, w.setClauses, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+		case "INSERT":
+			buf.WriteString("INSERT (")
+			writeIdentifiers(buf, w.columns, ", ")
+			buf.WriteString(") VALUES (")
+			if len(w.values) == 0 {
+				b.writeSourceColumns(buf, w.columns)
+			}
+			for i, value := range w.values {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				if err := writeValue(d, buf, value, &args, &placeholderStartPos); err != nil {
+					return NewDatSQLErr(err)
+				}
+			}
+			buf.WriteRune(')')
+		default:
+			buf.WriteString(w.action)
+		}
+	}
+
+	return buf.String(), args, nil
+}
+
+// writeSourceColumns writes columns qualified by the source alias or table.
+func (b *MergeBuilder) writeSourceColumns(buf common.BufferWriter, columns []string) {
+	qualifier := b.sourceAlias
+	if qualifier == "" {
+		qualifier = b.source.(string)
+	}
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(qualifier)
+		buf.WriteRune('.')
+		writeIdentifier(buf, column)
+	}
+}
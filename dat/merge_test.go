@@ -0,0 +1,113 @@
+package dat
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+
+	"github.com/matcherino/dat/postgres"
+)
+
+func TestMergeSQL(t *testing.T) {
+	sql, args, err := Merge("people AS p").
+		Using(Select("id, name, deleted").From("staging").Where("batch = $1", 7), "s").
+		On("p.id = s.id").
+		WhenMatched("s.deleted").Delete().
+		WhenMatched("p.name <> s.name").Update(M{"name": Expr("s.name"), "version": Expr("p.version + $1", 1)}).
+		WhenMatched().DoNothing().
+		WhenNotMatched("NOT s.deleted").Insert([]string{"id", "name", "kind"}, Expr("s.id"), Expr("s.name"), "person").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		MERGE INTO people AS p
+		USING (SELECT id, name, deleted FROM staging WHERE (batch = $1)) AS s
+		ON (p.id = s.id)
+		WHEN MATCHED AND (s.deleted) THEN DELETE
+		WHEN MATCHED AND (p.name <> s.name) THEN UPDATE SET name = s.name, version = p.version + $2
+		WHEN MATCHED THEN DO NOTHING
+		WHEN NOT MATCHED AND (NOT s.deleted) THEN INSERT (id, name, kind) VALUES (s.id, s.name, $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{7, 1, "person"}, args)
+}
+
+func TestMergeTableSource(t *testing.T) {
+	sql, args, err := Merge("people").
+		Using("staging", "").
+		On("people.id = staging.id").
+		On("staging.batch = $1", 7).
+		WhenNotMatched().Insert([]string{"id", "name"}).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		MERGE INTO people USING staging
+		ON (people.id = staging.id) AND (staging.batch = $1)
+		WHEN NOT MATCHED THEN INSERT (id, name) VALUES (staging.id, staging.name)`), stripWS(sql))
+	assert.Equal(t, []interface{}{7}, args)
+}
+
+func TestMergeSliceSource(t *testing.T) {
+	type person struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	sql, args, err := Merge("people AS p").
+		Using([]person{{1, "x"}, {2, "y"}}, "s").
+		On("p.id = s.id").
+		WhenMatched().Update(M{"name": Expr("s.name")}).
+		WhenNotMatched().Insert([]string{"id", "name"}).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		MERGE INTO people AS p
+		USING (SELECT UNNEST(ARRAY[$1,$2]::bigint[]) AS "id", UNNEST(ARRAY[$3,$4]::text[]) AS "name") AS s
+		ON (p.id = s.id)
+		WHEN MATCHED THEN UPDATE SET name = s.name
+		WHEN NOT MATCHED THEN INSERT (id, name) VALUES (s.id, s.name)`), stripWS(sql))
+	assert.Equal(t, []interface{}{int64(1), int64(2), "x", "y"}, args)
+
+	sql, args, err = Merge("people AS p").
+		Using([]person{{1, "x"}}, "s").
+		On("p.id = s.id").
+		WhenMatched().Update(M{"name": Expr("s.name")}).
+		SetIsInterpolated(true).
+		Interpolate()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		MERGE INTO people AS p
+		USING (SELECT UNNEST(ARRAY[1]::bigint[]) AS "id", UNNEST(ARRAY['x']::text[]) AS "name") AS s
+		ON (p.id = s.id)
+		WHEN MATCHED THEN UPDATE SET name = s.name`), stripWS(sql))
+	assert.Empty(t, args)
+}
+
+func TestMergeErrors(t *testing.T) {
+	_, _, err := Merge("people").On("a = b").WhenMatched().Delete().ToSQL()
+	assert.Error(t, err)
+	_, _, err = Merge("people").Using("staging", "").WhenMatched().Delete().ToSQL()
+	assert.Error(t, err)
+	_, _, err = Merge("people").Using("staging", "").On("a = b").ToSQL()
+	assert.Error(t, err)
+	_, _, err = Merge("people").Using(Select("a").From("b"), "").On("a = b").WhenMatched().Delete().ToSQL()
+	assert.Error(t, err)
+	_, _, err = Merge("people").Using(1, "s").On("a = b").WhenMatched().Delete().ToSQL()
+	assert.Error(t, err)
+	b := Merge("people").Using("staging", "").On("a = b")
+	b.WhenMatched()
+	_, _, err = b.ToSQL()
+	assert.Error(t, err)
+	_, _, err = Merge("people").Using("staging", "").On("a = b").WhenNotMatched().Delete().ToSQL()
+	assert.Error(t, err)
+	_, _, err = Merge("people").Using("staging", "").On("a = b").WhenMatched().Insert([]string{"a"}).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Merge("people").Using("staging", "").On("a = b").WhenNotMatched().Insert([]string{"a", "b"}, 1).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Merge("people").Using("staging", "").On("a = b").WhenMatched().Update(nil).ToSQL()
+	assert.Error(t, err)
+}
+
+func TestMergeDialect(t *testing.T) {
+	b := Merge("people").Using("staging", "").On("a = b").WhenMatched().Delete()
+	b.SetDialect(questionDialect{postgres.New()})
+	_, _, err := b.ToSQL()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "MERGE")
+}
@@ -0,0 +1,116 @@
+package dat
+
+// PageTotalColumn is the column added by QueryPage holding the count of all
+// rows of the query.
+const PageTotalColumn = "dat__total"
+
+// Page is a page of rows fetched with QueryPage.
+type Page struct {
+	// Items is the dest passed to QueryPage.
+	Items   interface{} `json:"items"`
+	Total   int64       `json:"total"`
+	Page    uint64      `json:"page"`
+	PerPage uint64      `json:"perPage"`
+	HasNext bool        `json:"hasNext"`
+}
+
+// NewPage returns the page of items numbered page out of total rows.
+func NewPage(items interface{}, total int64, page, perPage uint64) *Page {
+	return &Page{
+		Items:   items,
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+		HasNext: page*perPage < uint64(total),
+	}
+}
+
+// PaginateTotal returns a copy of builder, a Select or SelectDoc builder,
+// paginated with the count of all rows of the query, before LIMIT and
+// OFFSET, selected in the PageTotalColumn of each row. builder is not
+// changed. Runners call it to implement QueryPage.
+//
+// The count is a count(*) OVER() window. DISTINCT and set operations are
+// paginated in a sub query since the window would count rows before them.
+// A page past the last has no rows to hold the count; query PageCount for
+// it.
+func PaginateTotal(builder Builder, page, perPage uint64) (Builder, error) {
+	if page < 1 || perPage < 1 {
+		return nil, NewError("QueryPage requires page and perPage >= 1")
+	}
+	if doc, ok := builder.(*SelectDocBuilder); ok && (doc.isDistinct || len(doc.union) > 0) {
+		return nil, NewError("QueryPage does not support DISTINCT or UNION in SelectDoc")
+	}
+	paged, b, err := copySelect(builder)
+	if err != nil {
+		return nil, err
+	}
+	if b.keyset != nil {
+		return nil, NewError("QueryPage cannot be combined with KeysetPaginate")
+	}
+	b.Paginate(page, perPage)
+	b.pageTotal = true
+	return paged, nil
+}
+
+// PageCount returns a builder counting all rows of builder, as returned by
+// PaginateTotal, without LIMIT and OFFSET.
+func PageCount(builder Builder) (Builder, error) {
+	unpaged, b, err := copySelect(builder)
+	if err != nil {
+		return nil, err
+	}
+	b.pageTotal = false
+	b.limitValid = false
+	b.offsetValid = false
+	sql, args, err := unpaged.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	count := NewRawBuilder("SELECT count(*) FROM ("+sql+") AS dat__count", args...)
+	count.SetDialect(BuilderDialect(builder))
+	count.SetIsInterpolated(builder.IsInterpolated())
+	return count, nil
+}
+
+// copySelect returns a copy of a Select or SelectDoc builder and its
+// SelectBuilder, whose LIMIT and OFFSET may be changed without changing
+// builder.
+func copySelect(builder Builder) (Builder, *SelectBuilder, error) {
+	switch t := builder.(type) {
+	case *SelectBuilder:
+		b := *t
+		return &b, &b, nil
+	case *SelectDocBuilder:
+		doc := *t
+		b := *t.SelectBuilder
+		doc.SelectBuilder = &b
+		return &doc, &b, nil
+	}
+	return nil, nil, NewError("QueryPage requires a Select or SelectDoc builder")
+}
+
+// pageTotalSQL returns the statement of b, without LIMIT and OFFSET, in a
+// sub query which is paginated with the count of its rows.
+func (b *SelectBuilder) pageTotalSQL() (string, []interface{}, error) {
+	inner := *b
+	inner.pageTotal = false
+	inner.limitValid = false
+	inner.offsetValid = false
+	sql, args, err := inner.ToSQL()
+	if err != nil {
+		return NewDatSQLErr(err)
+	}
+
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	buf.WriteString("SELECT dat__page.*, count(*) OVER() AS ")
+	buf.WriteString(PageTotalColumn)
+	buf.WriteString(" FROM (")
+	buf.WriteString(sql)
+	buf.WriteString(") AS dat__page LIMIT ")
+	writeUint64(buf, b.limitCount)
+	buf.WriteString(" OFFSET ")
+	writeUint64(buf, b.offsetCount)
+	return buf.String(), args, nil
+}
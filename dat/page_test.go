@@ -0,0 +1,107 @@
+package dat
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestPaginateTotal(t *testing.T) {
+	b := Select("a", "b").From("c").Where("d = $1", 1).OrderBy("a")
+	paged, err := PaginateTotal(b, 3, 10)
+	assert.NoError(t, err)
+	sql, args, err := paged.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT a, b, count(*) OVER() AS dat__total FROM c WHERE (d = $1)
+		ORDER BY a LIMIT 10 OFFSET 20`), stripWS(sql))
+	assert.Equal(t, []interface{}{1}, args)
+
+	// b is not paginated
+	sql, _, err = b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`SELECT a, b FROM c WHERE (d = $1) ORDER BY a`), stripWS(sql))
+}
+
+func TestPageCount(t *testing.T) {
+	paged, err := PaginateTotal(Select("a", "b").From("c").Where("d = $1", 1).OrderBy("a"), 3, 10)
+	assert.NoError(t, err)
+	count, err := PageCount(paged)
+	assert.NoError(t, err)
+	sql, args, err := count.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT count(*) FROM (SELECT a, b FROM c WHERE (d = $1) ORDER BY a) AS dat__count`), stripWS(sql))
+	assert.Equal(t, []interface{}{1}, args)
+
+	paged, err = PaginateTotal(SelectDoc("a").From("c"), 2, 10)
+	assert.NoError(t, err)
+	count, err = PageCount(paged)
+	assert.NoError(t, err)
+	sql, _, err = count.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT count(*) FROM (
+			SELECT row_to_json(dat__item.*) FROM (SELECT a FROM c) as dat__item
+		) AS dat__count`), stripWS(sql))
+
+	_, err = PageCount(Update("a").Set("b", 1))
+	assert.Error(t, err)
+}
+
+func TestPaginateTotalSubquery(t *testing.T) {
+	b, err := PaginateTotal(Select("a").Distinct().From("c").Where("d = $1", 1).OrderBy("a"), 1, 5)
+	assert.NoError(t, err)
+	sql, args, err := b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT dat__page.*, count(*) OVER() AS dat__total FROM (
+			SELECT DISTINCT a FROM c WHERE (d = $1) ORDER BY a
+		) AS dat__page LIMIT 5 OFFSET 0`), stripWS(sql))
+	assert.Equal(t, []interface{}{1}, args)
+
+	b, err = PaginateTotal(Select("a").From("c").Union(Select("a").From("e")).OrderBy("a"), 2, 5)
+	assert.NoError(t, err)
+	sql, _, err = b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT dat__page.*, count(*) OVER() AS dat__total FROM (
+			SELECT a FROM c UNION SELECT a FROM e ORDER BY a
+		) AS dat__page LIMIT 5 OFFSET 5`), stripWS(sql))
+}
+
+func TestPaginateTotalSelectDoc(t *testing.T) {
+	b, err := PaginateTotal(SelectDoc("a").From("c").OrderBy("a"), 2, 10)
+	assert.NoError(t, err)
+	sql, _, err := b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT row_to_json(dat__item.*) FROM (
+			SELECT a, count(*) OVER() AS dat__total FROM c ORDER BY a LIMIT 10 OFFSET 10
+		) as dat__item`), stripWS(sql))
+}
+
+func TestPaginateTotalErrors(t *testing.T) {
+	for _, b := range []Builder{
+		Update("a").Set("b", 1),
+		Select("a").From("b").KeysetPaginate([]string{"a"}, "", 10),
+		SelectDoc("a").Distinct().From("b"),
+	} {
+		_, err := PaginateTotal(b, 1, 10)
+		assert.Error(t, err)
+	}
+	_, err := PaginateTotal(Select("a").From("b"), 0, 10)
+	assert.Error(t, err)
+	_, err = PaginateTotal(Select("a").From("b"), 1, 0)
+	assert.Error(t, err)
+}
+
+func TestNewPage(t *testing.T) {
+	items := []int{1, 2}
+	page := NewPage(&items, 12, 2, 5)
+	assert.Equal(t, &items, page.Items)
+	assert.Equal(t, int64(12), page.Total)
+	assert.True(t, page.HasNext)
+	assert.False(t, NewPage(&items, 12, 3, 5).HasNext)
+	assert.False(t, NewPage(&items, 10, 2, 5).HasNext)
+}
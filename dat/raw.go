@@ -4,6 +4,7 @@ package dat
 type RawBuilder struct {
 	Execer
 
+	dialect        SQLDialect
 	isInterpolated bool
 	sql            string
 	args           []interface{}
@@ -0,0 +1,100 @@
+package dat
+
+import "reflect"
+
+// recordsReturner is implemented by builders whose RETURNING rows can be
+// scanned back into the records they write.
+type recordsReturner interface {
+	returningRecords() ([]interface{}, error)
+}
+
+// ReturningRecords returns the records of an Insert, Upsert or Insect
+// builder, or the record of Update.SetWhitelist and SetBlacklist, in the
+// order of the rows of its RETURNING clause. Runners call it to implement
+// FillRecords. Timestamp and version columns missing from Returning are
+// added to it so the values set in SQL are written back to the records.
+func ReturningRecords(builder Builder) ([]interface{}, error) {
+	rb, ok := builder.(recordsReturner)
+	if !ok {
+		return nil, NewError("FillRecords requires an Insert, Upsert, Insect or Update builder")
+	}
+	records, err := rb.returningRecords()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, NewError("FillRecords requires records")
+	}
+	for _, record := range records {
+		v := reflect.ValueOf(record)
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return nil, NewError("FillRecords requires pointers to structs")
+		}
+	}
+	return records, nil
+}
+
+// errReturningValues is returned by FillRecords for builders with Values,
+// whose rows precede those of the records.
+var errReturningValues = NewError("FillRecords cannot be combined with Values")
+
+// errReturningColumns is returned by FillRecords for builders without a
+// RETURNING clause.
+var errReturningColumns = NewError("FillRecords requires Returning")
+
+func (b *InsertBuilder) returningRecords() ([]interface{}, error) {
+	if len(b.vals) > 0 {
+		return nil, errReturningValues
+	}
+	if len(b.returnings) == 0 {
+		return nil, errReturningColumns
+	}
+	b.returnings = appendReturning(b.returnings, b.timestamps.insertColumns(b.records))
+	return b.records, nil
+}
+
+// returningRecords of Upsert and Insect do not require Returning, which
+// defaults to the inserted columns.
+func (b *UpsertBuilder) returningRecords() ([]interface{}, error) {
+	if len(b.vals) > 0 {
+		return nil, errReturningValues
+	}
+	columns := b.timestamps.insertColumns(b.records)
+	if version := b.versionColumn(); version != "" {
+		columns = append(columns, version)
+	}
+	b.returnings = appendReturning(b.returnings, columns)
+	return b.records, nil
+}
+
+func (b *InsectBuilder) returningRecords() ([]interface{}, error) {
+	if len(b.vals) > 0 {
+		return nil, errReturningValues
+	}
+	b.returnings = appendReturning(b.returnings, b.timestamps.insertColumns(b.records))
+	return b.records, nil
+}
+
+func (b *UpdateBuilder) returningRecords() ([]interface{}, error) {
+	if b.record == nil {
+		return nil, NewError("FillRecords requires SetWhitelist or SetBlacklist")
+	}
+	if len(b.returnings) == 0 {
+		return nil, errReturningColumns
+	}
+	columns := b.timestamps.columns(b.record, false)
+	if version := versionColumn(b.record); version != "" {
+		columns = append(columns, version)
+	}
+	b.returnings = appendReturning(b.returnings, columns)
+	return []interface{}{b.record}, nil
+}
+
+// appendReturning returns returnings with the columns it does not contain
+// appended. An empty or "*" RETURNING clause is returned as is.
+func appendReturning(returnings []string, columns []string) []string {
+	if len(returnings) == 0 || returnings[0] == "*" {
+		return returnings
+	}
+	return appendMissing(append([]string{}, returnings...), columns)
+}
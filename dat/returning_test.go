@@ -0,0 +1,55 @@
+package dat
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type returningRecord struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestReturningRecords(t *testing.T) {
+	a, b := &returningRecord{Name: "a"}, &returningRecord{Name: "b"}
+
+	records, err := ReturningRecords(InsertInto("t").Columns("name").Record(a).Record(b).Returning("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{a, b}, records)
+
+	records, err = ReturningRecords(Upsert("t").Columns("name").Record(a).OnConflict("name"))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{a}, records)
+
+	records, err = ReturningRecords(Insect("t").Columns("name").Record(b))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{b}, records)
+
+	records, err = ReturningRecords(Update("t").SetWhitelist(a, "name").Where("id = $1", 1).Returning("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{a}, records)
+
+	records, err = ReturningRecords(Update("t").SetBlacklist(b, "id").Where("id = $1", 1).Returning("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{b}, records)
+}
+
+func TestReturningRecordsErrors(t *testing.T) {
+	_, err := ReturningRecords(Select("id").From("t"))
+	assert.Error(t, err)
+	_, err = ReturningRecords(InsertInto("t").Columns("name").Record(&returningRecord{}))
+	assert.Error(t, err)
+	_, err = ReturningRecords(InsertInto("t").Columns("name").Values("a").Returning("id"))
+	assert.Error(t, err)
+	_, err = ReturningRecords(InsertInto("t").Columns("name").Values("a").Record(&returningRecord{}).Returning("id"))
+	assert.Error(t, err)
+	_, err = ReturningRecords(InsertInto("t").Columns("name").Record(returningRecord{}).Returning("id"))
+	assert.Error(t, err)
+	_, err = ReturningRecords(Upsert("t").Columns("name").Values("a").OnConflict("name"))
+	assert.Error(t, err)
+	_, err = ReturningRecords(Update("t").Set("name", "a").Returning("id"))
+	assert.Error(t, err)
+	_, err = ReturningRecords(Update("t").SetWhitelist(&returningRecord{}, "name"))
+	assert.Error(t, err)
+}
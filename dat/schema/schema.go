@@ -0,0 +1,441 @@
+// Package schema reads the tables, views, enums and functions of Postgres
+// schemas from information_schema and pg_catalog. A Schema serializes to
+// JSON for code generators, drift checks and other tools.
+//
+//	s, err := schema.Read(DB, "public")
+//	if err != nil {
+//		return err
+//	}
+//	posts := s.Table("posts")
+//	b, err := json.MarshalIndent(s, "", "  ")
+package schema
+
+import (
+	"github.com/lib/pq"
+	"github.com/matcherino/dat/dat"
+	"github.com/matcherino/dat/sqlx-runner"
+)
+
+// Schema is the database objects of one or more Postgres schemas.
+type Schema struct {
+	Tables    []*Table    `json:"tables"`
+	Views     []*View     `json:"views"`
+	Enums     []*Enum     `json:"enums"`
+	Functions []*Function `json:"functions"`
+}
+
+// Table is a table and its columns, keys and indexes.
+type Table struct {
+	Schema  string    `json:"schema"`
+	Name    string    `json:"name"`
+	Columns []*Column `json:"columns"`
+	// PrimaryKey is nil if the table has no primary key.
+	PrimaryKey  *Key          `json:"primaryKey"`
+	UniqueKeys  []*Key        `json:"uniqueKeys"`
+	ForeignKeys []*ForeignKey `json:"foreignKeys"`
+	Indexes     []*Index      `json:"indexes"`
+}
+
+// Column is a column of a table or view.
+type Column struct {
+	Name string `db:"name" json:"name"`
+	// Type is the formatted type such as "character varying(64)".
+	Type     string `db:"type" json:"type"`
+	Nullable bool   `db:"nullable" json:"nullable"`
+	// Default is the default expression, if any.
+	Default  dat.NullString `db:"default" json:"default"`
+	Position int            `db:"position" json:"position"`
+}
+
+// Key is a primary key or unique constraint.
+type Key struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// ForeignKey is a foreign key constraint.
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefSchema  string   `json:"refSchema"`
+	RefTable   string   `json:"refTable"`
+	RefColumns []string `json:"refColumns"`
+	// OnUpdate and OnDelete are actions such as "NO ACTION" or "CASCADE".
+	OnUpdate string `json:"onUpdate"`
+	OnDelete string `json:"onDelete"`
+}
+
+// Index is an index of a table.
+type Index struct {
+	Name string `json:"name"`
+	// Columns are the indexed columns or expressions.
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary"`
+	// Definition is the CREATE INDEX statement.
+	Definition string `json:"definition"`
+}
+
+// View is a view or materialized view.
+type View struct {
+	Schema       string    `json:"schema"`
+	Name         string    `json:"name"`
+	Materialized bool      `json:"materialized"`
+	Definition   string    `json:"definition"`
+	Columns      []*Column `json:"columns"`
+}
+
+// Enum is an enum type.
+type Enum struct {
+	Schema string   `json:"schema"`
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Function is a function or procedure.
+type Function struct {
+	Schema string `db:"schema" json:"schema"`
+	Name   string `db:"name" json:"name"`
+	// Arguments are the arguments identifying overloaded functions, such as
+	// "id bigint, name text".
+	Arguments string `db:"arguments" json:"arguments"`
+	Result    string `db:"result" json:"result"`
+	Language  string `db:"language" json:"language"`
+}
+
+// Table returns the table named name, qualified by its schema or not, or
+// nil if there is none.
+func (s *Schema) Table(name string) *Table {
+	for _, t := range s.Tables {
+		if t.Name == name || t.Schema+"."+t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// View returns the view named name, qualified by its schema or not, or nil
+// if there is none.
+func (s *Schema) View(name string) *View {
+	for _, v := range s.Views {
+		if v.Name == name || v.Schema+"."+v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Enum returns the enum type named name, qualified by its schema or not, or
+// nil if there is none.
+func (s *Schema) Enum(name string) *Enum {
+	for _, e := range s.Enums {
+		if e.Name == name || e.Schema+"."+e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Column returns the column named name or nil if there is none.
+func (t *Table) Column(name string) *Column {
+	return findColumn(t.Columns, name)
+}
+
+// Column returns the column named name or nil if there is none.
+func (v *View) Column(name string) *Column {
+	return findColumn(v.Columns, name)
+}
+
+func findColumn(columns []*Column, name string) *Column {
+	for _, c := range columns {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Read reads the objects of schemas, "public" if none, using conn.
+func Read(conn runner.Connection, schemas ...string) (*Schema, error) {
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+	r := &reader{conn: conn, schemas: pq.StringArray(schemas), s: &Schema{}}
+	for _, read := range []func() error{
+		r.readTables,
+		r.readViews,
+		r.readColumns,
+		r.readConstraints,
+		r.readIndexes,
+		r.readEnums,
+		r.readFunctions,
+	} {
+		if err := read(); err != nil {
+			return nil, err
+		}
+	}
+	return r.s, nil
+}
+
+// reader reads a Schema. Tables and views are looked up by their
+// qualified names while their columns, keys and indexes are read.
+type reader struct {
+	conn    runner.Connection
+	schemas pq.StringArray
+	s       *Schema
+	tables  map[string]*Table
+	views   map[string]*View
+}
+
+func qualifiedName(schema string, name string) string {
+	return schema + "." + name
+}
+
+func (r *reader) readTables() error {
+	var rows []struct {
+		Schema string `db:"table_schema"`
+		Name   string `db:"table_name"`
+	}
+	err := r.conn.SQL(`
+		SELECT table_schema, table_name
+		FROM information_schema.tables
+		WHERE table_type = 'BASE TABLE' AND table_schema = ANY($1)
+		ORDER BY table_schema, table_name
+	`, r.schemas).QueryStructs(&rows)
+	if err != nil {
+		return err
+	}
+	r.tables = map[string]*Table{}
+	for _, row := range rows {
+		t := &Table{Schema: row.Schema, Name: row.Name}
+		r.s.Tables = append(r.s.Tables, t)
+		r.tables[qualifiedName(t.Schema, t.Name)] = t
+	}
+	return nil
+}
+
+func (r *reader) readViews() error {
+	var rows []struct {
+		Schema       string `db:"schema"`
+		Name         string `db:"name"`
+		Materialized bool   `db:"materialized"`
+		Definition   string `db:"definition"`
+	}
+	err := r.conn.SQL(`
+		SELECT n.nspname AS schema, c.relname AS name, c.relkind = 'm' AS materialized,
+			pg_get_viewdef(c.oid, true) AS definition
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('v', 'm') AND n.nspname = ANY($1)
+		ORDER BY n.nspname, c.relname
+	`, r.schemas).QueryStructs(&rows)
+	if err != nil {
+		return err
+	}
+	r.views = map[string]*View{}
+	for _, row := range rows {
+		v := &View{Schema: row.Schema, Name: row.Name, Materialized: row.Materialized, Definition: row.Definition}
+		r.s.Views = append(r.s.Views, v)
+		r.views[qualifiedName(v.Schema, v.Name)] = v
+	}
+	return nil
+}
+
+func (r *reader) readColumns() error {
+	var rows []struct {
+		Schema string `db:"schema"`
+		Table  string `db:"table_name"`
+		Column
+	}
+	err := r.conn.SQL(`
+		SELECT n.nspname AS schema, c.relname AS table_name, a.attname AS name,
+			format_type(a.atttypid, a.atttypmod) AS type, NOT a.attnotnull AS nullable,
+			pg_get_expr(d.adbin, d.adrelid) AS "default", a.attnum AS position
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attnum > 0 AND NOT a.attisdropped
+			AND c.relkind IN ('r', 'p', 'v', 'm') AND n.nspname = ANY($1)
+		ORDER BY n.nspname, c.relname, a.attnum
+	`, r.schemas).QueryStructs(&rows)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		column := rows[i].Column
+		name := qualifiedName(rows[i].Schema, rows[i].Table)
+		if t := r.tables[name]; t != nil {
+			t.Columns = append(t.Columns, &column)
+		} else if v := r.views[name]; v != nil {
+			v.Columns = append(v.Columns, &column)
+		}
+	}
+	return nil
+}
+
+// foreignKeyActions are the actions of pg_constraint.confupdtype and
+// confdeltype.
+var foreignKeyActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+func (r *reader) readConstraints() error {
+	var rows []struct {
+		Schema     string         `db:"schema"`
+		Table      string         `db:"table_name"`
+		Name       string         `db:"name"`
+		Kind       string         `db:"kind"`
+		Columns    pq.StringArray `db:"columns"`
+		RefSchema  string         `db:"ref_schema"`
+		RefTable   string         `db:"ref_table"`
+		RefColumns pq.StringArray `db:"ref_columns"`
+		OnUpdate   string         `db:"on_update"`
+		OnDelete   string         `db:"on_delete"`
+	}
+	err := r.conn.SQL(`
+		SELECT n.nspname AS schema, t.relname AS table_name, c.conname AS name, c.contype::text AS kind,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(c.conkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			) AS columns,
+			COALESCE(rn.nspname, '') AS ref_schema, COALESCE(rt.relname, '') AS ref_table,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(c.confkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			) AS ref_columns,
+			c.confupdtype::text AS on_update, c.confdeltype::text AS on_delete
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		LEFT JOIN pg_class rt ON rt.oid = c.confrelid
+		LEFT JOIN pg_namespace rn ON rn.oid = rt.relnamespace
+		WHERE c.contype IN ('p', 'u', 'f') AND n.nspname = ANY($1)
+		ORDER BY n.nspname, t.relname, c.conname
+	`, r.schemas).QueryStructs(&rows)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		t := r.tables[qualifiedName(row.Schema, row.Table)]
+		if t == nil {
+			continue
+		}
+		switch row.Kind {
+		case "p":
+			t.PrimaryKey = &Key{Name: row.Name, Columns: row.Columns}
+		case "u":
+			t.UniqueKeys = append(t.UniqueKeys, &Key{Name: row.Name, Columns: row.Columns})
+		case "f":
+			t.ForeignKeys = append(t.ForeignKeys, &ForeignKey{
+				Name:       row.Name,
+				Columns:    row.Columns,
+				RefSchema:  row.RefSchema,
+				RefTable:   row.RefTable,
+				RefColumns: row.RefColumns,
+				OnUpdate:   foreignKeyActions[row.OnUpdate],
+				OnDelete:   foreignKeyActions[row.OnDelete],
+			})
+		}
+	}
+	return nil
+}
+
+func (r *reader) readIndexes() error {
+	var rows []struct {
+		Schema     string         `db:"schema"`
+		Table      string         `db:"table_name"`
+		Name       string         `db:"name"`
+		Columns    pq.StringArray `db:"columns"`
+		Unique     bool           `db:"is_unique"`
+		Primary    bool           `db:"is_primary"`
+		Definition string         `db:"definition"`
+	}
+	err := r.conn.SQL(`
+		SELECT n.nspname AS schema, t.relname AS table_name, i.relname AS name,
+			ARRAY(
+				SELECT pg_get_indexdef(x.indexrelid, k, true)
+				FROM generate_series(1, x.indnatts) k
+				ORDER BY k
+			) AS columns,
+			x.indisunique AS is_unique, x.indisprimary AS is_primary,
+			pg_get_indexdef(x.indexrelid) AS definition
+		FROM pg_index x
+		JOIN pg_class i ON i.oid = x.indexrelid
+		JOIN pg_class t ON t.oid = x.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = ANY($1)
+		ORDER BY n.nspname, t.relname, i.relname
+	`, r.schemas).QueryStructs(&rows)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if t := r.tables[qualifiedName(row.Schema, row.Table)]; t != nil {
+			t.Indexes = append(t.Indexes, &Index{
+				Name:       row.Name,
+				Columns:    row.Columns,
+				Unique:     row.Unique,
+				Primary:    row.Primary,
+				Definition: row.Definition,
+			})
+		}
+	}
+	return nil
+}
+
+func (r *reader) readEnums() error {
+	var rows []struct {
+		Schema string         `db:"schema"`
+		Name   string         `db:"name"`
+		Values pq.StringArray `db:"enum_values"`
+	}
+	err := r.conn.SQL(`
+		SELECT n.nspname AS schema, t.typname AS name,
+			ARRAY(
+				SELECT e.enumlabel::text
+				FROM pg_enum e
+				WHERE e.enumtypid = t.oid
+				ORDER BY e.enumsortorder
+			) AS enum_values
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.typtype = 'e' AND n.nspname = ANY($1)
+		ORDER BY n.nspname, t.typname
+	`, r.schemas).QueryStructs(&rows)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		r.s.Enums = append(r.s.Enums, &Enum{Schema: row.Schema, Name: row.Name, Values: row.Values})
+	}
+	return nil
+}
+
+func (r *reader) readFunctions() error {
+	var rows []*Function
+	err := r.conn.SQL(`
+		SELECT n.nspname AS schema, p.proname AS name,
+			pg_get_function_identity_arguments(p.oid) AS arguments,
+			COALESCE(pg_get_function_result(p.oid), '') AS result,
+			l.lanname AS language
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		JOIN pg_language l ON l.oid = p.prolang
+		WHERE n.nspname = ANY($1)
+		ORDER BY n.nspname, p.proname, 3
+	`, r.schemas).QueryStructs(&rows)
+	if err != nil {
+		return err
+	}
+	r.s.Functions = rows
+	return nil
+}
@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/matcherino/dat/dat"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func testSchema() *Schema {
+	return &Schema{
+		Tables: []*Table{{
+			Schema: "public",
+			Name:   "posts",
+			Columns: []*Column{
+				{Name: "id", Type: "integer", Default: dat.NullStringFrom("nextval('posts_id_seq'::regclass)"), Position: 1},
+				{Name: "title", Type: "text", Nullable: true, Position: 2},
+			},
+			PrimaryKey: &Key{Name: "posts_pkey", Columns: []string{"id"}},
+		}},
+		Views: []*View{{Schema: "app", Name: "titles", Definition: " SELECT posts.title FROM posts;"}},
+		Enums: []*Enum{{Schema: "public", Name: "state", Values: []string{"draft", "published"}}},
+	}
+}
+
+func TestLookup(t *testing.T) {
+	s := testSchema()
+	assert.Equal(t, s.Tables[0], s.Table("posts"))
+	assert.Equal(t, s.Tables[0], s.Table("public.posts"))
+	assert.Nil(t, s.Table("app.posts"))
+	assert.Equal(t, s.Views[0], s.View("app.titles"))
+	assert.Equal(t, s.Enums[0], s.Enum("state"))
+	assert.Equal(t, "text", s.Table("posts").Column("title").Type)
+	assert.Nil(t, s.Table("posts").Column("body"))
+}
+
+func TestJSON(t *testing.T) {
+	b, err := json.Marshal(testSchema().Tables[0].Columns)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"name":"id","type":"integer","nullable":false,"default":"nextval('posts_id_seq'::regclass)","position":1},`+
+		`{"name":"title","type":"text","nullable":true,"default":null,"position":2}]`, string(b))
+
+	var s Schema
+	b, err = json.Marshal(testSchema())
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, &s))
+	assert.Equal(t, testSchema(), &s)
+}
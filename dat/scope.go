@@ -2,15 +2,12 @@ package dat
 
 import (
 	"bytes"
-	"regexp"
 	"strings"
 )
 
 // M is a generic map from string to interface{}
 type M map[string]interface{}
 
-var reField = regexp.MustCompile(`\B:[A-Za-z_]\w*`)
-
 // Scope predefines parameterized JOIN and WHERE conditions.
 type Scope interface {
 	ToSQL(table string) (string, []interface{})
@@ -59,23 +56,30 @@ func (scope *MapScope) ToSQL(table string) (string, []interface{}) {
 
 	var n = 1
 	var args []interface{}
-	sql := reField.ReplaceAllStringFunc(scope.SQL, func(found string) string {
-		buf.Reset()
-		if found == ":TABLE" {
+	t := sqlTokenizer{sql: scope.SQL}
+	for {
+		kind, text := t.next()
+		if text == "" {
+			break
+		}
+		if kind != tokParam {
+			buf.WriteString(text)
+			continue
+		}
+		if text == ":TABLE" {
 			writeIdentifier(buf, table)
-			return buf.String()
+			continue
 		}
 		if args == nil {
 			args = []interface{}{}
 		}
-		field := found[1:]
+		field := text[1:]
 		args = append(args, scope.Fields[field])
 		writePlaceholder(buf, n)
 		n++
-		return buf.String()
-	})
+	}
 
-	return sql, args
+	return buf.String(), args
 }
 
 // escapeScopeTable escapes :TABLE in sql using Dialect.WriteIdentifer.
@@ -85,30 +89,36 @@ func escapeScopeTable(sql string, table string) string {
 	}
 
 	var buf bytes.Buffer
-	writeIdentifier(&buf, table)
-	quoted := buf.String()
-	return strings.Replace(sql, ":TABLE", quoted, -1)
+	t := sqlTokenizer{sql: sql}
+	for {
+		kind, text := t.next()
+		if text == "" {
+			break
+		}
+		if kind == tokParam && text == ":TABLE" {
+			writeIdentifier(&buf, table)
+		} else {
+			buf.WriteString(text)
+		}
+	}
+	return buf.String()
 }
 
-var reWhereClause = regexp.MustCompile(`\s*(WHERE|where)\b`)
-
-// splitWhere splits a query on the word WHERE
+// splitWhere splits a query on the first WHERE outside of parentheses.
 func splitWhere(query string) (sql string, where string) {
-	indices := reWhereClause.FindStringIndex(query)
-	// grab only the first location
-	if len(indices) == 0 {
-		return query, ""
-	}
-
-	// may have leading spaces
-	where = query[indices[0]:]
-	idx := strings.Index(where, "WHERE")
-	if idx == -1 {
-		idx = strings.Index(where, "where")
+	depth := 0
+	t := sqlTokenizer{sql: query, words: true}
+	for {
+		start := t.pos
+		kind, text := t.next()
+		switch {
+		case text == "":
+			return query, ""
+		case kind == tokText:
+			depth += strings.Count(text, "(") - strings.Count(text, ")")
+		case kind == tokWord && depth == 0 && strings.EqualFold(text, "WHERE"):
+			// leading spaces belong to neither part
+			return strings.TrimRight(query[:start], " \t\n\f\r"), query[t.pos:]
+		}
 	}
-	// 5 == len("WHERE")
-	where = where[idx+5:]
-
-	sql = query[0:indices[0]]
-	return sql, where
 }
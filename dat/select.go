@@ -1,14 +1,20 @@
 package dat
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/matcherino/dat/common"
+)
 
 // SelectBuilder contains the clauses for a SELECT statement
 type SelectBuilder struct {
 	Execer
 
+	dialect         SQLDialect
 	isDistinct      bool
 	distinctColumns []string
 	isInterpolated  bool
+	with            withClause
 	columns         []string
 	fors            []string
 	tableFragments  []*whereFragment
@@ -16,12 +22,17 @@ type SelectBuilder struct {
 	whereFragments  []*whereFragment
 	groupBys        []string
 	havingFragments []*whereFragment
+	setOps          []*setOp
+	keyset          *keyset
+	pageTotal       bool
 	orderBys        []*whereFragment
 	limitCount      uint64
 	limitValid      bool
 	offsetCount     uint64
 	offsetValid     bool
 	scope           Scope
+	tableScopes     *TableScopes
+	unscoped        bool
 	err             error
 }
 
@@ -57,8 +68,20 @@ func (b *SelectBuilder) DistinctOn(columns ...string) *SelectBuilder {
 }
 
 // From sets the table to SELECT FROM. JOINs may also be defined here.
-func (b *SelectBuilder) From(fromStr string, args ...interface{}) *SelectBuilder {
-	fragment, err := newWhereFragment(fromStr, args)
+// Builders in args are inlined as sub queries, as in From("$1 AS t", sub).
+// A Builder may also be given in place of fromSQL followed by an optional
+// alias, as in From(sub, "t").
+func (b *SelectBuilder) From(fromSQLOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	var fragment *whereFragment
+	var err error
+	switch t := fromSQLOrBuilder.(type) {
+	case string:
+		fragment, err = newWhereFragment(t, args)
+	case Builder:
+		fragment, err = newSubqueryFragment(t, args)
+	default:
+		err = NewError("From accepts only {string, Builder} type")
+	}
 	if err != nil {
 		b.err = err
 		return b
@@ -117,6 +140,36 @@ func (b *SelectBuilder) Scope(sql string, args ...interface{}) *SelectBuilder {
 	return b
 }
 
+// SetTableScopes sets the default scopes of tables, overriding those of the
+// connection.
+func (b *SelectBuilder) SetTableScopes(scopes *TableScopes) *SelectBuilder {
+	b.tableScopes = scopes
+	return b
+}
+
+// Unscoped bypasses the default scopes of the tables of From.
+func (b *SelectBuilder) Unscoped() *SelectBuilder {
+	b.unscoped = true
+	return b
+}
+
+// tableScopeFragments returns whereFragments with the default scopes of the
+// tables of From in scopes appended.
+func (b *SelectBuilder) tableScopeFragments(scopes *TableScopes, whereFragments []*whereFragment) ([]*whereFragment, error) {
+	if b.unscoped || scopes == nil {
+		return whereFragments, nil
+	}
+	from := make([]string, len(b.tableFragments))
+	for i, f := range b.tableFragments {
+		from[i] = f.Condition
+	}
+	fragments, err := scopes.fragments(from...)
+	if err != nil || len(fragments) == 0 {
+		return whereFragments, err
+	}
+	return append(append([]*whereFragment{}, whereFragments...), fragments...), nil
+}
+
 // Where appends a WHERE clause to the statement for the given string and args
 // or map of column/value pairs
 func (b *SelectBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *SelectBuilder {
@@ -146,6 +199,49 @@ func (b *SelectBuilder) Having(whereSQLOrMap interface{}, args ...interface{}) *
 	return b
 }
 
+// Union combines the rows of the statement with those of sqlOrBuilder,
+// removing duplicates. OrderBy, Limit and Offset apply to the combined rows.
+func (b *SelectBuilder) Union(sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	return b.addSetOp("UNION", sqlOrBuilder, args)
+}
+
+// UnionAll combines the rows of the statement with those of sqlOrBuilder.
+func (b *SelectBuilder) UnionAll(sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	return b.addSetOp("UNION ALL", sqlOrBuilder, args)
+}
+
+// Intersect keeps the distinct rows also returned by sqlOrBuilder.
+func (b *SelectBuilder) Intersect(sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	return b.addSetOp("INTERSECT", sqlOrBuilder, args)
+}
+
+// IntersectAll keeps the rows also returned by sqlOrBuilder, including
+// duplicates.
+func (b *SelectBuilder) IntersectAll(sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	return b.addSetOp("INTERSECT ALL", sqlOrBuilder, args)
+}
+
+// Except removes the rows returned by sqlOrBuilder, returning distinct rows.
+func (b *SelectBuilder) Except(sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	return b.addSetOp("EXCEPT", sqlOrBuilder, args)
+}
+
+// ExceptAll removes the rows returned by sqlOrBuilder, including
+// duplicates.
+func (b *SelectBuilder) ExceptAll(sqlOrBuilder interface{}, args ...interface{}) *SelectBuilder {
+	return b.addSetOp("EXCEPT ALL", sqlOrBuilder, args)
+}
+
+func (b *SelectBuilder) addSetOp(op string, sqlOrBuilder interface{}, args []interface{}) *SelectBuilder {
+	switch sqlOrBuilder.(type) {
+	case string, Builder:
+		b.setOps = append(b.setOps, &setOp{op: op, sqlOrBuilder: sqlOrBuilder, args: args})
+	default:
+		b.err = NewError(op + " accepts only {string, Builder} type")
+	}
+	return b
+}
+
 // OrderBy appends a column to ORDER the statement by
 func (b *SelectBuilder) OrderBy(whereSQLOrMap interface{}, args ...interface{}) *SelectBuilder {
 	fragment, err := newWhereFragment(whereSQLOrMap, args)
@@ -182,9 +278,20 @@ func (b *SelectBuilder) Paginate(page, perPage uint64) *SelectBuilder {
 // ToSQL serialized the SelectBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
+	return b.toSQL(b.Dialect(), b.tableScopes)
+}
+
+// toSQL is ToSQL with the dialect d and the default scopes of tables
+// scopes.
+func (b *SelectBuilder) toSQL(d SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
+	if len(b.distinctColumns) > 0 {
+		if err := ErrUnsupported(d, FeatureDistinctOn); err != nil {
+			return NewDatSQLErr(err)
+		}
+	}
 
 	if len(b.columns) == 0 {
 		return NewDatSQLError("no columns specified")
@@ -192,10 +299,18 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
 	if len(b.tableFragments) == 0 && len(b.joinFragments) > 0 {
 		return NewDatSQLError("joins may only be attached if a from target is specified")
 	}
+	if b.pageTotal && (b.isDistinct || len(b.setOps) > 0) {
+		return b.pageTotalSQL()
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 	var args []interface{}
+	var placeholderStartPos int64 = 1
+
+	if err := writeWith(d, buf, &b.with, &args, &placeholderStartPos); err != nil {
+		return NewDatSQLErr(err)
+	}
 
 	buf.WriteString("SELECT ")
 
@@ -220,16 +335,23 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
 		}
 		buf.WriteString(s)
 	}
+	if b.pageTotal {
+		buf.WriteString(", count(*) OVER() AS ")
+		buf.WriteString(PageTotalColumn)
+	}
 
-	var placeholderStartPos int64 = 1
 	from := ""
 	fromBuf := bufPool.Get()
 	defer bufPool.Put(fromBuf)
 	if len(b.tableFragments) > 0 {
 		buf.WriteString(" FROM ")
-		writeCommaFragmentsToSQL(fromBuf, b.tableFragments, &args, &placeholderStartPos)
+		if err := writeCommaFragmentsToSQL(d, fromBuf, b.tableFragments, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
 		fromBuf.WriteString(" ")
-		writeConcatFragmentsToSQL(fromBuf, b.joinFragments, &args, &placeholderStartPos)
+		if err := writeConcatFragmentsToSQL(d, fromBuf, b.joinFragments, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
 		from = fromBuf.String()
 		buf.WriteString(from)
 	}
@@ -249,10 +371,19 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
 			whereFragments = append(whereFragments, fragment)
 		}
 	}
+	whereFragments, err := b.tableScopeFragments(scopes, whereFragments)
+	if err != nil {
+		return NewDatSQLErr(err)
+	}
+	if b.keyset != nil {
+		whereFragments = b.keyset.whereFragments(whereFragments)
+	}
 
 	if len(whereFragments) > 0 {
 		buf.WriteString(" WHERE ")
-		writeAndFragmentsToSQL(buf, whereFragments, &args, &placeholderStartPos)
+		if err := writeAndFragmentsToSQL(d, buf, whereFragments, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
 	}
 
 	if len(b.groupBys) > 0 {
@@ -267,20 +398,37 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
 
 	if len(b.havingFragments) > 0 {
 		buf.WriteString(" HAVING ")
-		writeAndFragmentsToSQL(buf, b.havingFragments, &args, &placeholderStartPos)
+		if err := writeAndFragmentsToSQL(d, buf, b.havingFragments, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
+	}
+
+	for _, op := range b.setOps {
+		buf.WriteRune(' ')
+		buf.WriteString(op.op)
+		buf.WriteRune(' ')
+		if err := op.writeOperand(d, buf, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
 	}
 
-	if len(b.orderBys) > 0 {
+	if b.keyset != nil || len(b.orderBys) > 0 {
 		buf.WriteString(" ORDER BY ")
-		writeCommaFragmentsToSQL(buf, b.orderBys, &args, &placeholderStartPos)
+		if err := writeOrderBy(d, buf, b.keyset, b.orderBys, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
 	}
 
-	if b.limitValid {
+	if b.keyset != nil {
+		// the extra row tells whether there is a next page
+		buf.WriteString(" LIMIT ")
+		writeUint64(buf, b.keyset.pageSize+1)
+	} else if b.limitValid {
 		buf.WriteString(" LIMIT ")
 		writeUint64(buf, b.limitCount)
 	}
 
-	if b.offsetValid {
+	if b.offsetValid && b.keyset == nil {
 		buf.WriteString(" OFFSET ")
 		writeUint64(buf, b.offsetCount)
 	}
@@ -296,3 +444,26 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
 
 	return buf.String(), args, nil
 }
+
+// setOp is a UNION, INTERSECT or EXCEPT of a SelectBuilder.
+type setOp struct {
+	op           string
+	sqlOrBuilder interface{}
+	args         []interface{}
+}
+
+// writeOperand writes the query combined with the statement. A
+// SelectBuilder which orders, limits or combines its own rows is
+// parenthesized.
+func (op *setOp) writeOperand(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	switch t := op.sqlOrBuilder.(type) {
+	case *SelectBuilder:
+		parens := len(t.orderBys) > 0 || t.limitValid || t.offsetValid || len(t.setOps) > 0
+		return writeSubquery(d, buf, t, parens, args, pos)
+	case Builder:
+		return writeSubquery(d, buf, t, false, args, pos)
+	case string:
+		return writeBuilderArgs(d, buf, t, op.args, args, pos)
+	}
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 type subInfo struct {
 	*Expression
 	alias string
+	// jsonColumns are the columns of a nested document which are documents.
+	jsonColumns []string
 }
 
 // SelectDocBuilder builds SQL that returns a JSON row.
@@ -19,7 +21,7 @@ type SelectDocBuilder struct {
 	subQueriesVector []*subInfo
 	subQueriesScalar []*subInfo
 	innerSQL         *Expression
-	union            []*subInfo // alias is used to encode whether or not this is a union or union all - This lets us easily preserve ordering and reuses code
+	union            []*subInfo // alias is used to encode the set operation such as UNION ALL - This lets us easily preserve ordering and reuses code
 	isParent         bool
 	err              error
 }
@@ -55,6 +57,8 @@ func (b *SelectDocBuilder) RemoveData() *SelectDocBuilder {
 func (b *SelectDocBuilder) Copy() *SelectDocBuilder {
 	return &SelectDocBuilder{
 		SelectBuilder: &SelectBuilder{
+			dialect:         b.dialect,
+			with:            b.with.copy(),
 			isDistinct:      b.isDistinct,
 			distinctColumns: append([]string{}, b.distinctColumns...),
 			isInterpolated:  b.isInterpolated,
@@ -70,7 +74,11 @@ func (b *SelectDocBuilder) Copy() *SelectDocBuilder {
 			limitValid:      b.limitValid,
 			offsetCount:     b.offsetCount,
 			offsetValid:     b.offsetValid,
+			keyset:          b.keyset,
+			pageTotal:       b.pageTotal,
 			scope:           b.scope,
+			tableScopes:     b.tableScopes,
+			unscoped:        b.unscoped,
 			err:             b.err,
 		},
 		subQueriesWith:   append([]*subInfo{}, b.subQueriesWith...),
@@ -85,33 +93,41 @@ func (b *SelectDocBuilder) Copy() *SelectDocBuilder {
 	}
 }
 
-func storeExpr(destination *[]*subInfo, name string, column string, sqlOrBuilder interface{}, a ...interface{}) error {
+// storeExpr stores a sub query which inherits the dialect and default
+// scopes of b.
+func (b *SelectDocBuilder) storeExpr(destination *[]*subInfo, name string, column string, sqlOrBuilder interface{}, a ...interface{}) error {
+	return storeExpr(b.Dialect(), b.tableScopes, destination, name, column, sqlOrBuilder, a...)
+}
+
+// storeExpr stores a sub query which inherits the dialect d and default
+// scopes of tables scopes unless it has its own.
+func storeExpr(d SQLDialect, scopes *TableScopes, destination *[]*subInfo, name string, column string, sqlOrBuilder interface{}, a ...interface{}) error {
 	var err error
 	switch t := sqlOrBuilder.(type) {
 	default:
 		err = NewError(name + ": sqlOrbuilder accepts only {string, Builder, *SelectDocBuilder} type")
 	case *JSQLBuilder:
 		t.isParent = false
-		sql, args, err := t.ToSQL()
+		sql, args, err := embeddedSQL(d, scopes, t)
 		if err != nil {
 			return err
 		}
-		*destination = append(*destination, &subInfo{Expr(sql, args...), column})
+		*destination = append(*destination, &subInfo{Expr(sql, args...), column, t.jsonColumns()})
 	case *SelectDocBuilder:
 		t.isParent = false
-		sql, args, err := t.ToSQL()
+		sql, args, err := embeddedSQL(d, scopes, t)
 		if err != nil {
 			return err
 		}
-		*destination = append(*destination, &subInfo{Expr(sql, args...), column})
+		*destination = append(*destination, &subInfo{Expr(sql, args...), column, t.jsonColumns()})
 	case Builder:
-		sql, args, err := t.ToSQL()
+		sql, args, err := embeddedSQL(d, scopes, t)
 		if err != nil {
 			return err
 		}
-		*destination = append(*destination, &subInfo{Expr(sql, args...), column})
+		*destination = append(*destination, &subInfo{Expr(sql, args...), column, nil})
 	case string:
-		*destination = append(*destination, &subInfo{Expr(t, a...), column})
+		*destination = append(*destination, &subInfo{Expr(t, a...), column, nil})
 	}
 	return err
 }
@@ -119,47 +135,71 @@ func storeExpr(destination *[]*subInfo, name string, column string, sqlOrBuilder
 // With loads a sub query that will be inserted as a "with" table
 func (b *SelectDocBuilder) With(column string, sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
 	if reflect.TypeOf(sqlOrBuilder).Kind() == reflect.Slice {
-		sqlOrBuilder, a, b.err = arrayToTable(sqlOrBuilder)
+		sqlOrBuilder, a, b.err = arrayToTable(b.Dialect(), sqlOrBuilder)
 	}
 	if b.err == nil {
-		b.err = storeExpr(&b.subQueriesWith, "SelectDocBuilder.With", column, sqlOrBuilder, a...)
+		b.err = b.storeExpr(&b.subQueriesWith, "SelectDocBuilder.With", column, sqlOrBuilder, a...)
 	}
 	return b
 }
 
 // Many loads a sub query resulting in an array of rows as an alias.
 func (b *SelectDocBuilder) Many(column string, sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
-	b.err = storeExpr(&b.subQueriesMany, "SelectDocBuilder.Many", column, sqlOrBuilder, a...)
+	b.err = b.storeExpr(&b.subQueriesMany, "SelectDocBuilder.Many", column, sqlOrBuilder, a...)
 	return b
 }
 
 // Vector loads a sub query resulting in an array of homogeneous scalars as an alias.
 func (b *SelectDocBuilder) Vector(column string, sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
-	b.err = storeExpr(&b.subQueriesVector, "SelectDocBuilder.Vector", column, sqlOrBuilder, a...)
+	b.err = b.storeExpr(&b.subQueriesVector, "SelectDocBuilder.Vector", column, sqlOrBuilder, a...)
 	return b
 }
 
 // One loads a query resulting in a single row as an alias.
 func (b *SelectDocBuilder) One(column string, sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
-	b.err = storeExpr(&b.subQueriesOne, "SelectDocBuilder.One", column, sqlOrBuilder, a...)
+	b.err = b.storeExpr(&b.subQueriesOne, "SelectDocBuilder.One", column, sqlOrBuilder, a...)
 	return b
 }
 
 // Scalar loads a query resulting in a single scalar as an alias and embeds the scalar in the parent object, rather than as a child object
 func (b *SelectDocBuilder) Scalar(column string, sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
-	b.err = storeExpr(&b.subQueriesScalar, "SelectDocBuilder.Scalar", column, sqlOrBuilder, a...)
+	b.err = b.storeExpr(&b.subQueriesScalar, "SelectDocBuilder.Scalar", column, sqlOrBuilder, a...)
 	return b
 }
 
 // Union will add a SQL expression to the query with a UNION directive
 func (b *SelectDocBuilder) Union(sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
-	b.err = storeExpr(&b.union, "SelectDocBuilder.Union", " ", sqlOrBuilder, a...)
+	b.err = b.storeExpr(&b.union, "SelectDocBuilder.Union", "UNION", sqlOrBuilder, a...)
 	return b
 }
 
 // UnionAll will add a SQL expression to the query with a UNION ALL directive
 func (b *SelectDocBuilder) UnionAll(sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
-	b.err = storeExpr(&b.union, "SelectDocBuilder.UnionAll", " ALL ", sqlOrBuilder, a...)
+	b.err = b.storeExpr(&b.union, "SelectDocBuilder.UnionAll", "UNION ALL", sqlOrBuilder, a...)
+	return b
+}
+
+// Intersect will add a SQL expression to the query with an INTERSECT directive
+func (b *SelectDocBuilder) Intersect(sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
+	b.err = b.storeExpr(&b.union, "SelectDocBuilder.Intersect", "INTERSECT", sqlOrBuilder, a...)
+	return b
+}
+
+// IntersectAll will add a SQL expression to the query with an INTERSECT ALL directive
+func (b *SelectDocBuilder) IntersectAll(sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
+	b.err = b.storeExpr(&b.union, "SelectDocBuilder.IntersectAll", "INTERSECT ALL", sqlOrBuilder, a...)
+	return b
+}
+
+// Except will add a SQL expression to the query with an EXCEPT directive
+func (b *SelectDocBuilder) Except(sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
+	b.err = b.storeExpr(&b.union, "SelectDocBuilder.Except", "EXCEPT", sqlOrBuilder, a...)
+	return b
+}
+
+// ExceptAll will add a SQL expression to the query with an EXCEPT ALL directive
+func (b *SelectDocBuilder) ExceptAll(sqlOrBuilder interface{}, a ...interface{}) *SelectDocBuilder {
+	b.err = b.storeExpr(&b.union, "SelectDocBuilder.ExceptAll", "EXCEPT ALL", sqlOrBuilder, a...)
 	return b
 }
 
@@ -222,9 +262,18 @@ func (b *SelectDocBuilder) Whitelist(columns ...string) *SelectDocBuilder {
 // ToSQL serialized the SelectBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
+	return b.toSQL(b.Dialect(), b.tableScopes)
+}
+
+// toSQL is ToSQL with the dialect d and the default scopes of tables
+// scopes.
+func (b *SelectDocBuilder) toSQL(d SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
+	if err := ErrUnsupported(d, FeatureJSONDocuments); err != nil {
+		return NewDatSQLErr(err)
+	}
 
 	if len(b.columns)+len(b.subQueriesMany)+len(b.subQueriesOne)+len(b.subQueriesScalar)+len(b.subQueriesVector) == 0 {
 		return NewDatSQLError("no columns specified")
@@ -258,19 +307,23 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 		) as item
 	*/
 
+	if err := writeWith(d, buf, &b.with, &args, &placeholderStartPos); err != nil {
+		return NewDatSQLErr(err)
+	}
 	for i, sub := range b.subQueriesWith {
-		if i == 0 {
+		if i == 0 && len(b.with.ctes) == 0 {
 			buf.WriteString("WITH ")
 		} else {
 			buf.WriteString(", ")
 		}
 		buf.WriteString(sub.alias)
 		buf.WriteString(" AS (")
-		sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 		buf.WriteString(") ")
 	}
 
-	if b.isParent {
+	sqlite := DialectJSONStyle(d) == SQLiteJSON
+	if b.isParent && !sqlite {
 		//buf.WriteString("SELECT convert_to(row_to_json(dat__item.*)::text, 'UTF8') FROM ( SELECT ")
 		buf.WriteString("SELECT row_to_json(dat__item.*) FROM ( SELECT ")
 	} else {
@@ -298,6 +351,10 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 		}
 		buf.WriteString(s)
 	}
+	if b.pageTotal {
+		buf.WriteString(", count(*) OVER() AS ")
+		buf.WriteString(PageTotalColumn)
+	}
 
 	/*
 		(
@@ -312,60 +369,88 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 		if sub == nil {
 			continue
 		}
+		if sqlite {
+			buf.WriteString(", ")
+			if err := writeSQLiteMany(d, buf, sub, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+			continue
+		}
 		buf.WriteString(", (SELECT array_agg(dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(".*) FROM (")
-		sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(") AS ")
-		writeQuotedIdentifier(buf, sub.alias)
+		d.WriteIdentifier(buf, sub.alias)
 	}
 
 	for _, sub := range b.subQueriesVector {
 		if sub == nil {
 			continue
 		}
+		if sqlite {
+			buf.WriteString(", ")
+			if err := writeSQLiteVector(d, buf, sub, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+			continue
+		}
 		buf.WriteString(", (SELECT array_agg(dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(".dat__scalar) FROM (")
-		sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString("(dat__scalar)) AS ")
-		Dialect.WriteIdentifier(buf, sub.alias)
+		d.WriteIdentifier(buf, sub.alias)
 	}
 
 	for _, sub := range b.subQueriesOne {
 		if sub == nil {
 			continue
 		}
+		if sqlite {
+			buf.WriteString(", ")
+			if err := writeSQLiteOne(d, buf, sub, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+			continue
+		}
 		buf.WriteString(", (SELECT row_to_json(dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(".*) FROM (")
-		sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(") AS ")
-		writeQuotedIdentifier(buf, sub.alias)
+		d.WriteIdentifier(buf, sub.alias)
 	}
 
 	for _, sub := range b.subQueriesScalar {
 		if sub == nil {
 			continue
 		}
+		if sqlite {
+			buf.WriteString(", ")
+			if err := writeSQLiteScalar(d, buf, sub, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
+			continue
+		}
 		buf.WriteString(", (SELECT dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(".dat__scalar FROM (")
-		sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString("(dat__scalar) limit 1) AS ")
-		writeQuotedIdentifier(buf, sub.alias)
+		d.WriteIdentifier(buf, sub.alias)
 	}
 
 	if b.innerSQL != nil {
-		b.innerSQL.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+		b.innerSQL.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 	} else {
 		whereFragments := b.whereFragments
 		from := ""
@@ -373,9 +458,13 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 		defer bufPool.Put(fromBuf)
 		if len(b.tableFragments) > 0 {
 			buf.WriteString(" FROM ")
-			writeCommaFragmentsToSQL(fromBuf, b.tableFragments, &args, &placeholderStartPos)
+			if err := writeCommaFragmentsToSQL(d, fromBuf, b.tableFragments, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 			fromBuf.WriteString(" ")
-			writeConcatFragmentsToSQL(fromBuf, b.joinFragments, &args, &placeholderStartPos)
+			if err := writeConcatFragmentsToSQL(d, fromBuf, b.joinFragments, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 			from = fromBuf.String()
 			buf.WriteString(from)
 		}
@@ -393,10 +482,19 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 				whereFragments = append(whereFragments, fragment)
 			}
 		}
+		whereFragments, err := b.tableScopeFragments(scopes, whereFragments)
+		if err != nil {
+			return NewDatSQLErr(err)
+		}
+		if b.keyset != nil {
+			whereFragments = b.keyset.whereFragments(whereFragments)
+		}
 
 		if len(whereFragments) > 0 {
 			buf.WriteString(" WHERE ")
-			writeAndFragmentsToSQL(buf, whereFragments, &args, &placeholderStartPos)
+			if err := writeAndFragmentsToSQL(d, buf, whereFragments, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 		}
 
 		// if b.scope == nil {
@@ -406,17 +504,17 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 		// 	}
 		// } else {
 		// 	whereFragment := newWhereFragment(b.scope.ToSQL(b.table))
-		// 	writeScopeCondition(buf, whereFragment, &args, &placeholderStartPos)
+		// 	writeScopeCondition(d, buf, whereFragment, &args, &placeholderStartPos)
 		// }
 
 		for _, sub := range b.union {
 			if sub == nil {
 				continue
 			}
-			buf.WriteString(" UNION ")
+			buf.WriteString(" ")
 			buf.WriteString(sub.alias)
 			buf.WriteString(" ")
-			sub.WriteRelativeArgs(buf, &args, &placeholderStartPos)
+			sub.writeRelativeArgs(d, buf, &args, &placeholderStartPos)
 			buf.WriteString(" ")
 		}
 
@@ -432,20 +530,27 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 
 		if len(b.havingFragments) > 0 {
 			buf.WriteString(" HAVING ")
-			writeAndFragmentsToSQL(buf, b.havingFragments, &args, &placeholderStartPos)
+			if err := writeAndFragmentsToSQL(d, buf, b.havingFragments, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 		}
 
-		if len(b.orderBys) > 0 {
+		if b.keyset != nil || len(b.orderBys) > 0 {
 			buf.WriteString(" ORDER BY ")
-			writeCommaFragmentsToSQL(buf, b.orderBys, &args, &placeholderStartPos)
+			if err := writeOrderBy(d, buf, b.keyset, b.orderBys, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 		}
 
-		if b.limitValid {
+		if b.keyset != nil {
+			buf.WriteString(" LIMIT ")
+			writeUint64(buf, b.keyset.pageSize+1)
+		} else if b.limitValid {
 			buf.WriteString(" LIMIT ")
 			writeUint64(buf, b.limitCount)
 		}
 
-		if b.offsetValid {
+		if b.offsetValid && b.keyset == nil {
 			buf.WriteString(" OFFSET ")
 			writeUint64(buf, b.offsetCount)
 		}
@@ -461,11 +566,23 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 	}
 
 	if b.isParent {
+		if sqlite {
+			sql, err := wrapSQLiteDoc(d, buf.String(), b.jsonColumns())
+			if err != nil {
+				return NewDatSQLErr(err)
+			}
+			return sql, args, nil
+		}
 		buf.WriteString(`) as dat__item`)
 	}
 	return buf.String(), args, nil
 }
 
+// jsonColumns returns the aliases of sub queries which result in documents.
+func (b *SelectDocBuilder) jsonColumns() []string {
+	return subAliases(b.subQueriesMany, b.subQueriesOne, b.subQueriesVector)
+}
+
 //// Override functions from SelectBuilder to return an instance of SelectDocBuilder.
 
 // Columns adds additional select columns to the builder.
@@ -487,8 +604,8 @@ func (b *SelectDocBuilder) DistinctOn(columns ...string) *SelectDocBuilder {
 }
 
 // From sets the table to SELECT FROM. JOINs may also be defined here.
-func (b *SelectDocBuilder) From(fromStr string, args ...interface{}) *SelectDocBuilder {
-	b.SelectBuilder.From(fromStr, args...)
+func (b *SelectDocBuilder) From(fromSQLOrBuilder interface{}, args ...interface{}) *SelectDocBuilder {
+	b.SelectBuilder.From(fromSQLOrBuilder, args...)
 	return b
 }
 
@@ -534,6 +651,19 @@ func (b *SelectDocBuilder) Scope(sql string, args ...interface{}) *SelectDocBuil
 	return b
 }
 
+// SetTableScopes sets the default scopes of tables, overriding those of the
+// connection. Sub queries added afterwards inherit them.
+func (b *SelectDocBuilder) SetTableScopes(scopes *TableScopes) *SelectDocBuilder {
+	b.SelectBuilder.SetTableScopes(scopes)
+	return b
+}
+
+// Unscoped bypasses the default scopes of the tables of From.
+func (b *SelectDocBuilder) Unscoped() *SelectDocBuilder {
+	b.SelectBuilder.Unscoped()
+	return b
+}
+
 // Where appends a WHERE clause to the statement for the given string and args
 // or map of column/value pairs
 func (b *SelectDocBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *SelectDocBuilder {
@@ -571,6 +701,18 @@ func (b *SelectDocBuilder) Offset(offset uint64) *SelectDocBuilder {
 	return b
 }
 
+// KeysetPaginate orders the query by orderColumns and limits it to the
+// pageSize rows following the row cursor was made from. See
+// SelectBuilder.KeysetPaginate.
+func (b *SelectDocBuilder) KeysetPaginate(orderColumns []string, cursor string, pageSize uint64) *SelectDocBuilder {
+	k, err := newKeyset(orderColumns, cursor, pageSize)
+	if err != nil {
+		b.err = err
+	}
+	b.keyset = k
+	return b
+}
+
 // Paginate sets LIMIT/OFFSET for the statement based on the given page/perPage
 // Assumes page/perPage are valid. Page and perPage must be >= 1
 func (b *SelectDocBuilder) Paginate(page, perPage uint64) *SelectDocBuilder {
@@ -580,12 +722,15 @@ func (b *SelectDocBuilder) Paginate(page, perPage uint64) *SelectDocBuilder {
 
 // arrayToTable accepts an array of structs or scalars and returns a query + args that can be embedded in a sub-table or query. If a struct array is passed,
 // then `db` struct tags will inform the aliases for each column. Otherwise, the alias of the column will be `data`.
-func arrayToTable(contents interface{}) (string, []interface{}, error) {
+func arrayToTable(d SQLDialect, contents interface{}) (string, []interface{}, error) {
 	val := reflect.ValueOf(contents)
 	typ := val.Type()
 	if typ.Kind() != reflect.Slice {
 		return "", nil, NewError("arrayToTable can only take slices")
 	}
+	if err := ErrUnsupported(d, FeatureArrays); err != nil {
+		return "", nil, err
+	}
 	innerTyp := typ.Elem()
 	if innerTyp.Kind() == reflect.Ptr {
 		innerTyp = innerTyp.Elem()
@@ -606,7 +751,7 @@ func arrayToTable(contents interface{}) (string, []interface{}, error) {
 			placeholderStartPos++
 		}
 		buf.WriteString("]::")
-		Dialect.WriteReflectedType(buf, reflect.SliceOf(innerTyp))
+		d.WriteReflectedType(buf, reflect.SliceOf(innerTyp))
 		buf.WriteString(") AS data ")
 		return buf.String(), args, nil
 	}
@@ -642,9 +787,9 @@ func arrayToTable(contents interface{}) (string, []interface{}, error) {
 				args = append(args, value.Field(i).Interface())
 			}
 			buf.WriteString("]::")
-			Dialect.WriteReflectedType(buf, reflect.SliceOf(field.Type))
+			d.WriteReflectedType(buf, reflect.SliceOf(field.Type))
 			buf.WriteString(") AS ")
-			writeQuotedIdentifier(buf, alias)
+			d.WriteIdentifier(buf, alias)
 		}
 	}
 	return buf.String(), args, nil
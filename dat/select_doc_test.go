@@ -481,3 +481,19 @@ func TestSelectDocWithCustomTypeArray(t *testing.T) {
 	assert.Equal(t, stripWS(expected), stripWS(sql))
 	assert.Equal(t, []interface{}{1, 4, "John Doe", "Mary Jane", customType(82), customType(44), 9}, args)
 }
+
+func TestSelectDocSetOperations(t *testing.T) {
+	sql, args, err := SelectDoc("a").
+		From("b").
+		Intersect(Select("a").From("c").Where("d = $1", 1)).
+		ExceptAll("SELECT a FROM e WHERE f = $1", 2).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT row_to_json(dat__item.*) FROM (
+			SELECT a FROM b
+			INTERSECT SELECT a FROM c WHERE (d = $1)
+			EXCEPT ALL SELECT a FROM e WHERE f = $2
+		) as dat__item`), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 2}, args)
+}
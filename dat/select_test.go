@@ -363,3 +363,45 @@ func TestSelectComplexFromJoin(t *testing.T) {
 	`), stripWS(sql))
 	assert.Exactly(t, []interface{}{1, 30, 5}, args)
 }
+
+func TestSelectSetOperations(t *testing.T) {
+	sql, args, err := Select("a").From("b").Where("c = $1", 1).
+		Union(Select("a").From("d").Where("e = $1", 2)).
+		UnionAll("SELECT a FROM f WHERE g = $1", 3).
+		Intersect(Select("a").From("h")).
+		IntersectAll(Select("a").From("i")).
+		Except(Select("a").From("j").Where("k = $1", 4)).
+		ExceptAll("SELECT a FROM l").
+		OrderBy("a").
+		Limit(5).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT a FROM b WHERE (c = $1)
+		UNION SELECT a FROM d WHERE (e = $2)
+		UNION ALL SELECT a FROM f WHERE g = $3
+		INTERSECT SELECT a FROM h
+		INTERSECT ALL SELECT a FROM i
+		EXCEPT SELECT a FROM j WHERE (k = $4)
+		EXCEPT ALL SELECT a FROM l
+		ORDER BY a LIMIT 5`), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 2, 3, 4}, args)
+}
+
+func TestSelectSetOperationParens(t *testing.T) {
+	sql, args, err := Select("a").From("b").
+		Union(Select("a").From("c").OrderBy("a DESC").Limit(1)).
+		Union(Select("a").From("d").Where("e = $1", 1).Intersect(Select("a").From("f"))).
+		OrderBy("a").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT a FROM b
+		UNION (SELECT a FROM c ORDER BY a DESC LIMIT 1)
+		UNION (SELECT a FROM d WHERE (e = $1) INTERSECT SELECT a FROM f)
+		ORDER BY a`), stripWS(sql))
+	assert.Equal(t, []interface{}{1}, args)
+
+	_, _, err = Select("a").From("b").Union(1).ToSQL()
+	assert.Error(t, err)
+}
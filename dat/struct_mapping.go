@@ -19,33 +19,188 @@ func reflectFields(rec interface{}) *reflectx.StructMap {
 
 // ValuesFor ...
 func valuesFor(recordType reflect.Type, record reflect.Value, columns []string) ([]interface{}, error) {
-	vals := fieldMapper.FieldsByName(record, columns)
+	record = reflect.Indirect(record)
+	tm := fieldMapper.TypeMap(record.Type())
 	values := make([]interface{}, len(columns))
-	for i, val := range vals {
-		if !val.IsValid() {
-			return nil, fmt.Errorf("Could not find struct tag in type %s: `db:\"%s\"`", recordType.Name(), columns[i])
+	for i, column := range columns {
+		fi, ok := tm.Names[column]
+		if !ok {
+			return nil, fmt.Errorf("Could not find struct tag in type %s: `db:\"%s\"`", recordType.Name(), column)
 		}
-		values[i] = val.Interface()
+		values[i] = fieldValue(record, fi.Index)
 	}
 	return values, nil
 }
 
+// fieldValue returns the value of the field of v at indexes. Unlike
+// reflectx.FieldByIndexes it does not allocate nil pointers, which would
+// write zero values in place of NULL. Fields of a nil embedded struct are
+// nil.
+func fieldValue(v reflect.Value, indexes []int) interface{} {
+	for _, i := range indexes {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v.Interface()
+}
+
+// recordValues returns the values of columns of record to be written.
+// Fields tagged readonly cannot be written and timestamps are written with
+// the current time.
+func recordValues(record reflect.Value, columns []string, timestamps []string) ([]interface{}, error) {
+	record = reflect.Indirect(record)
+	tm := fieldMapper.TypeMap(record.Type())
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		fi, ok := tm.Names[column]
+		if !ok {
+			return nil, fmt.Errorf("Could not find struct tag in type %s: `db:\"%s\"`", record.Type().Name(), column)
+		}
+		switch {
+		case fi.ReadOnly:
+			return nil, fmt.Errorf("Cannot write readonly column %s of type %s", column, record.Type().Name())
+		case str.SliceContains(timestamps, column):
+			values[i] = currentTimestamp
+		default:
+			values[i] = fieldValue(record, fi.Index)
+		}
+	}
+	return values, nil
+}
+
+// recordRows returns the values of columns of each of records to be
+// inserted after vals. Timestamp columns are added to the columns of
+// records without vals. Columns of fields tagged omitempty which are zero
+// in every row are removed. Otherwise their zero values are inserted as
+// DEFAULT, which is an error if d does not support FeatureDefaultValues.
+func recordRows(d SQLDialect, columns []string, vals [][]interface{}, records []interface{}, timestamps []string) ([]string, [][]interface{}, error) {
+	if len(vals) == 0 {
+		columns = appendMissing(append([]string{}, columns...), timestamps)
+	}
+	rows := append([][]interface{}{}, vals...)
+	for _, rec := range records {
+		row, err := recordValues(reflect.ValueOf(rec), columns, timestamps)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, row)
+	}
+	if len(records) == 0 {
+		return columns, rows, nil
+	}
+
+	tm := reflectFields(records[0])
+	var omitted []int
+	for i, column := range columns {
+		if fi := tm.Names[column]; fi == nil || !fi.OmitEmpty {
+			continue
+		}
+		empty := 0
+		for _, row := range rows[len(vals):] {
+			if isZero(row[i]) {
+				row[i] = defaultValue
+				empty++
+			}
+		}
+		if len(vals) == 0 && empty == len(records) {
+			omitted = append(omitted, i)
+		} else if empty > 0 {
+			if err := ErrUnsupported(d, FeatureDefaultValues); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if len(omitted) == 0 {
+		return columns, rows, nil
+	}
+	return omitIndexes(columns, omitted), omitRowIndexes(rows, omitted), nil
+}
+
+// defaultValue is inserted in place of zero values of omitempty fields.
+var defaultValue = &Expression{Sql: "DEFAULT"}
+
+func isZero(value interface{}) bool {
+	return value == nil || reflect.ValueOf(value).IsZero()
+}
+
+func omitIndexes(columns []string, omitted []int) []string {
+	result := make([]string, 0, len(columns)-len(omitted))
+	for i, column := range columns {
+		if !containsInt(omitted, i) {
+			result = append(result, column)
+		}
+	}
+	return result
+}
+
+func omitRowIndexes(rows [][]interface{}, omitted []int) [][]interface{} {
+	result := make([][]interface{}, len(rows))
+	for r, row := range rows {
+		result[r] = make([]interface{}, 0, len(row)-len(omitted))
+		for i, value := range row {
+			if !containsInt(omitted, i) {
+				result[r] = append(result[r], value)
+			}
+		}
+	}
+	return result
+}
+
+func containsInt(a []int, n int) bool {
+	for _, v := range a {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// reflectColumns returns the columns of v which are written, excluding
+// fields tagged readonly.
 func reflectColumns(v interface{}) []string {
+	return reflectExcludeColumns(v, nil)
+}
+
+// reflectExcludeColumns returns the columns of v which are written,
+// excluding blacklist and fields tagged readonly.
+func reflectExcludeColumns(v interface{}, blacklist []string) []string {
+	sm := reflectFields(v)
 	cols := []string{}
-	for _, name := range reflectFields(v).DeclaredNames {
+	for _, name := range sm.DeclaredNames {
+		if sm.Names[name].ReadOnly || str.SliceContains(blacklist, name) {
+			continue
+		}
 		cols = append(cols, name)
 	}
 	return cols
 }
 
-func reflectExcludeColumns(v interface{}, blacklist []string) []string {
+// reflectSetColumns returns the columns of v which are updated, which also
+// excludes fields tagged pk.
+func reflectSetColumns(v interface{}, blacklist []string) []string {
+	sm := reflectFields(v)
 	cols := []string{}
-	for _, name := range reflectFields(v).DeclaredNames {
-		if str.SliceContains(blacklist, name) {
-			continue
+	for _, name := range reflectExcludeColumns(v, blacklist) {
+		if !sm.Names[name].PrimaryKey {
+			cols = append(cols, name)
 		}
-		cols = append(cols, name)
 	}
+	return cols
+}
 
+// primaryKeyColumns returns the columns of the fields of v tagged pk.
+func primaryKeyColumns(v interface{}) []string {
+	sm := reflectFields(v)
+	var cols []string
+	for _, name := range sm.DeclaredNames {
+		if sm.Names[name].PrimaryKey {
+			cols = append(cols, name)
+		}
+	}
 	return cols
 }
@@ -2,6 +2,7 @@ package dat
 
 import (
 	"testing"
+	"time"
 
 	"gopkg.in/stretchr/testify.v1/assert"
 )
@@ -42,3 +43,92 @@ func TestEmbeddedStructInvalidColumns(t *testing.T) {
 	_, _, err := InsertInto("groups").Columns("group_uuid", "realm_uuid").Record(g).ToSQL()
 	assert.Error(t, err)
 }
+
+type taggedRecord struct {
+	ID        int64      `db:"id,pk,omitempty"`
+	Name      string     `db:"name"`
+	Nick      *string    `db:"nick,omitempty"`
+	CreatedAt NullTime   `db:"created_at,readonly"`
+	UpdatedAt time.Time  `db:"updated_at,autoupdate"`
+	Secret    string     `db:"-"`
+	Tags      []string   `db:"tags,omitempty"`
+	Other     *time.Time `db:"other"`
+}
+
+func TestTagOptions(t *testing.T) {
+	fields := reflectFields(&taggedRecord{}).Names
+	assert.True(t, fields["id"].PrimaryKey)
+	assert.True(t, fields["id"].OmitEmpty)
+	assert.True(t, fields["created_at"].ReadOnly)
+	assert.True(t, fields["updated_at"].AutoUpdate)
+	assert.False(t, fields["name"].PrimaryKey || fields["name"].ReadOnly || fields["name"].AutoUpdate || fields["name"].OmitEmpty)
+	assert.Nil(t, fields["-"])
+	assert.Nil(t, fields["Secret"])
+
+	assert.Equal(t, []string{"id", "name", "nick", "updated_at", "tags", "other"}, reflectColumns(&taggedRecord{}))
+	assert.Equal(t, []string{"name", "nick", "tags", "other"}, reflectExcludeColumns(&taggedRecord{}, []string{"id", "updated_at"}))
+	assert.Equal(t, []string{"name", "nick", "updated_at", "tags", "other"}, reflectSetColumns(&taggedRecord{}, nil))
+}
+
+func TestTagOptionsInsert(t *testing.T) {
+	nick := "b"
+	sql, args, err := InsertInto("t").
+		Whitelist("*").
+		Record(&taggedRecord{Name: "a"}).
+		Record(&taggedRecord{Name: "b", Nick: &nick}).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		INSERT INTO t (name, nick, updated_at, other)
+		VALUES ($1, DEFAULT, CURRENT_TIMESTAMP, $2), ($3, $4, CURRENT_TIMESTAMP, $5)`), stripWS(sql))
+	assert.Equal(t, 5, len(args))
+	assert.Equal(t, "a", args[0])
+	assert.Equal(t, &nick, args[3])
+
+	sql, args, err = InsertInto("t").
+		Whitelist("*").
+		Record(&taggedRecord{ID: 1, Name: "a", Tags: []string{"x"}}).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		INSERT INTO t (id, name, updated_at, tags, other) VALUES ($1, $2, CURRENT_TIMESTAMP, $3, $4)`), stripWS(sql))
+	assert.Equal(t, int64(1), args[0])
+
+	_, _, err = InsertInto("t").Columns("name", "created_at").Record(&taggedRecord{}).ToSQL()
+	assert.Error(t, err)
+}
+
+func TestTagOptionsUpdate(t *testing.T) {
+	sql, args, err := Update("t").SetWhitelist(&taggedRecord{ID: 1, Name: "a"}, "name").Where("id = $1", 1).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`UPDATE t SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE (id = $2)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", 1}, args)
+
+	sql, _, err = Update("t").SetWhitelist(&taggedRecord{ID: 1}, "*").Where("id = $1", 1).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		UPDATE t SET name = $1, nick = $2, updated_at = CURRENT_TIMESTAMP, tags = $3, other = $4 WHERE (id = $5)`), stripWS(sql))
+
+	sql, _, err = Update("t").SetBlacklist(&taggedRecord{ID: 1}, "tags", "other").Where("id = $1", 1).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`UPDATE t SET name = $1, nick = $2, updated_at = CURRENT_TIMESTAMP WHERE (id = $3)`), stripWS(sql))
+
+	_, _, err = Update("t").SetWhitelist(&taggedRecord{}, "created_at").Where("id = $1", 1).ToSQL()
+	assert.Error(t, err)
+}
+
+func TestTagOptionsUpsert(t *testing.T) {
+	sql, _, err := Upsert("t").
+		Columns("id", "name").
+		Record(&taggedRecord{ID: 1, Name: "a"}).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		INSERT INTO t (id, name, updated_at) VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, updated_at = EXCLUDED.updated_at
+		RETURNING id, name, updated_at`), stripWS(sql))
+
+	// a new record has no id to conflict on
+	_, _, err = Upsert("t").Columns("id", "name").Record(&taggedRecord{Name: "a"}).ToSQL()
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package dat
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/matcherino/dat/common"
+)
+
+// hasBuilderArg returns whether any of values is a Builder to be inlined as
+// a sub query.
+func hasBuilderArg(values []interface{}) bool {
+	for _, v := range values {
+		if _, ok := v.(Builder); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newSubqueryFragment returns a FROM fragment for sub with the alias in
+// args, if any.
+func newSubqueryFragment(sub Builder, args []interface{}) (*whereFragment, error) {
+	switch len(args) {
+	case 0:
+		return &whereFragment{Condition: "$1", Values: []interface{}{sub}}, nil
+	case 1:
+		if alias, ok := args[0].(string); ok {
+			return &whereFragment{Condition: "$1 AS " + alias, Values: []interface{}{sub}}, nil
+		}
+	}
+	return nil, NewError("a sub query accepts only an alias")
+}
+
+// writeSubquery writes the SQL of sub, in parentheses if parens is set, with
+// its placeholders renumbered to begin at pos. sub inherits d unless it has
+// a dialect.
+func writeSubquery(d SQLDialect, buf common.BufferWriter, sub Builder, parens bool, args *[]interface{}, pos *int64) error {
+	sql, subArgs, err := embeddedSQL(d, nil, sub)
+	if err != nil {
+		return err
+	}
+	if parens {
+		buf.WriteRune('(')
+	}
+	remapPlaceholders(d, buf, sql, *pos)
+	if parens {
+		buf.WriteRune(')')
+	}
+	*args = append(*args, subArgs...)
+	*pos += int64(len(subArgs))
+	return nil
+}
+
+// writeBuilderArgs writes statement to buf with its placeholders renumbered
+// to begin at pos. Builder values are inlined as sub queries, in
+// parentheses unless the placeholder already is, such as "id IN ($1)".
+func writeBuilderArgs(d SQLDialect, buf common.BufferWriter, statement string, values []interface{}, args *[]interface{}, pos *int64) error {
+	t := newSQLTokenizer(d, statement)
+	// absolute positions of values already written
+	written := make([]int64, len(values))
+	highest := 0
+	for {
+		kind, text := t.next()
+		if text == "" {
+			break
+		}
+		if kind != tokPlaceholder {
+			buf.WriteString(text)
+			continue
+		}
+
+		var i int
+		if len(text) > 1 {
+			i, _ = strconv.Atoi(text[1:])
+		} else {
+			i = highest + 1
+		}
+		if i > highest {
+			highest = i
+		}
+		if i < 1 || i > len(values) {
+			return NewError("placeholder " + text + " has no argument")
+		}
+
+		v := values[i-1]
+		if sub, ok := v.(Builder); ok {
+			before := strings.TrimRight(statement[:t.pos-len(text)], " \t\r\n")
+			after := strings.TrimLeft(statement[t.pos:], " \t\r\n")
+			parens := !strings.HasSuffix(before, "(") || !strings.HasPrefix(after, ")")
+			if err := writeSubquery(d, buf, sub, parens, args, pos); err != nil {
+				return err
+			}
+			continue
+		}
+		if written[i-1] == 0 {
+			written[i-1] = *pos
+			*args = append(*args, v)
+			*pos++
+		}
+		writePlaceholder(buf, int(written[i-1]))
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package dat
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+
+	"github.com/matcherino/dat/postgres"
+)
+
+func TestSubqueryWhere(t *testing.T) {
+	sub := Select("user_id").From("bans").Where("reason = $1", "spam")
+	sql, args, err := Select("a").From("posts").
+		Where("state = $1", "published").
+		Where("user_id NOT IN ($1) AND likes > $2", sub, 10).
+		Where("EXISTS $1", Select("1").From("tags").Where("tags.post_id = posts.id AND tags.name = $1", "go")).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT a FROM posts WHERE (state = $1)
+		AND (user_id NOT IN (SELECT user_id FROM bans WHERE (reason = $2)) AND likes > $3)
+		AND (EXISTS (SELECT 1 FROM tags WHERE (tags.post_id = posts.id AND tags.name = $4)))`), stripWS(sql))
+	assert.Equal(t, []interface{}{"published", "spam", 10, "go"}, args)
+}
+
+func TestSubqueryRepeatedPlaceholder(t *testing.T) {
+	sub := Select("id").From("b").Where("c = $1", 1)
+	sql, args, err := Select("a").From("t").Where("x = $2 AND id IN ($1) AND y = $2", sub, 2).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a FROM t WHERE (x = $1 AND id IN (SELECT id FROM b WHERE (c = $2)) AND y = $1)"), stripWS(sql))
+	assert.Equal(t, []interface{}{2, 1}, args)
+
+	_, _, err = Select("a").From("t").Where("id IN ($2)", sub).ToSQL()
+	assert.Error(t, err)
+}
+
+func TestSubqueryFromJoin(t *testing.T) {
+	recent := Select("id, title").From("posts").Where("created_at > $1", "2020-01-01")
+	counts := Select("post_id, count(*) AS n").From("comments").Where("spam = $1", false).GroupBy("post_id")
+	sql, args, err := Select("p.title", "c.n").
+		From(recent, "p").
+		Join("$1 AS c ON c.post_id = p.id", counts).
+		Where("c.n > $1", 5).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT p.title, c.n
+		FROM (SELECT id, title FROM posts WHERE (created_at > $1)) AS p
+		INNER JOIN (SELECT post_id, count(*) AS n FROM comments WHERE (spam = $2) GROUP BY post_id) AS c ON c.post_id = p.id
+		WHERE (c.n > $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"2020-01-01", false, 5}, args)
+
+	_, _, err = Select("a").From(recent, 1).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Select("a").From(1).ToSQL()
+	assert.Error(t, err)
+}
+
+func TestSubqueryHavingSet(t *testing.T) {
+	avg := Select("avg(n)").From("totals").Where("year = $1", 2020)
+	sql, args, err := Select("user_id", "sum(n)").From("orders").
+		GroupBy("user_id").
+		Having(Gt("sum(n)", avg)).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT user_id, sum(n) FROM orders GROUP BY user_id
+		HAVING (sum(n) > (SELECT avg(n) FROM totals WHERE (year = $1)))`), stripWS(sql))
+	assert.Equal(t, []interface{}{2020}, args)
+
+	sql, args, err = Update("users").
+		Set("name", "x").
+		Set("post_count", Select("count(*)").From("posts").Where("posts.user_id = users.id AND state = $1", "published")).
+		Set("score", Expr("$1 + $2", Select("max(score)").From("scores"), 1)).
+		Where("id = $1", 3).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		UPDATE users SET name = $1,
+		post_count = (SELECT count(*) FROM posts WHERE (posts.user_id = users.id AND state = $2)),
+		score = (SELECT max(score) FROM scores) + $3
+		WHERE (id = $4)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"x", "published", 1, 3}, args)
+}
+
+func TestSubqueryDialect(t *testing.T) {
+	sub := Select("id").From("b").Where("c = $1", 1)
+	sql, args, err := Select("a").From("t").SetDialect(questionDialect{postgres.New()}).SetIsInterpolated(true).
+		Where("d = ? AND id IN (?)", 2, sub).
+		Interpolate()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a FROM t WHERE (d = 2 AND id IN (SELECT id FROM b WHERE (c = 1)))"), stripWS(sql))
+	assert.Equal(t, 0, len(args))
+	// the sub query inherits the dialect only while it is rendered
+	assert.Nil(t, sub.dialect)
+	sql, args, err = Select("a").From("t").Where("d = $1 AND id IN ($2)", 2, sub).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS("SELECT a FROM t WHERE (d = $1 AND id IN (SELECT id FROM b WHERE (c = $2)))"), stripWS(sql))
+	assert.Equal(t, []interface{}{2, 1}, args)
+}
@@ -0,0 +1,230 @@
+package dat
+
+import (
+	"strings"
+
+	"github.com/matcherino/dat/common"
+)
+
+// TableScopes are default scopes of tables which are applied to the
+// statements of builders for those tables. A default scope is a condition
+// such as "deleted_at IS NULL" or "tenant_id = :tenant" in which :TABLE is
+// replaced by the table or its alias.
+//
+// Default scopes are ANDed to the WHERE clause of Select and SelectDoc for
+// the tables of From, excluding joined tables, and of Update and Delete.
+// Builders bypass them with Unscoped.
+type TableScopes struct {
+	scopes map[string]Scope
+	// softDeletes are the deleted columns of tables deleted by an UPDATE
+	softDeletes map[string]string
+}
+
+// NewTableScopes creates an empty registry of default scopes.
+func NewTableScopes() *TableScopes {
+	return &TableScopes{scopes: map[string]Scope{}, softDeletes: map[string]string{}}
+}
+
+// Add sets the default scope of table.
+//
+//	scopes.Add("posts", dat.NewScope(":TABLE.tenant_id = :tenant", dat.M{"tenant": 1}))
+func (ts *TableScopes) Add(table string, scope Scope) *TableScopes {
+	ts.scopes[table] = scope
+	return ts
+}
+
+// SoftDelete sets the default scope of table to rows whose column is NULL
+// and deletes rows of table by setting column to the current time.
+func (ts *TableScopes) SoftDelete(table string, column string) *TableScopes {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	buf.WriteString(":TABLE.")
+	writeIdentifier(buf, column)
+	buf.WriteString(" IS NULL")
+	ts.softDeletes[table] = column
+	return ts.Add(table, NewScope(buf.String(), nil))
+}
+
+// softDeleteColumn returns the deleted column of table, if it is soft
+// deleted. ts may be nil.
+func (ts *TableScopes) softDeleteColumn(table string) string {
+	if ts == nil {
+		return ""
+	}
+	for _, ref := range fromTables(table) {
+		return ts.softDeletes[ref.name]
+	}
+	return ""
+}
+
+// fragments returns the conditions of the default scopes of the tables of
+// from. ts may be nil.
+func (ts *TableScopes) fragments(from ...string) ([]*whereFragment, error) {
+	if ts == nil || len(ts.scopes) == 0 {
+		return nil, nil
+	}
+	var fragments []*whereFragment
+	for _, s := range from {
+		for _, ref := range fromTables(s) {
+			scope := ts.scopes[ref.name]
+			if scope == nil {
+				continue
+			}
+			qualifier := ref.alias
+			if qualifier == "" {
+				qualifier = ref.name
+			}
+			fragment, err := newWhereFragment(scope.ToSQL(qualifier))
+			if err != nil {
+				return nil, err
+			}
+			fragments = append(fragments, fragment)
+		}
+	}
+	return fragments, nil
+}
+
+// tableRef is a table of a FROM list.
+type tableRef struct {
+	name  string
+	alias string
+}
+
+// fromJoinWords end the tables of a FROM list.
+var fromJoinWords = map[string]bool{
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"CROSS": true, "NATURAL": true, "WHERE": true, "ON": true, "USING": true,
+}
+
+// fromTables returns the tables of a FROM list, such as "people p, posts",
+// up to its first JOIN. Sub queries and functions are skipped.
+func fromTables(from string) []tableRef {
+	const (
+		expectTable = iota
+		expectAlias
+		expectName
+		skip
+	)
+	var refs []tableRef
+	var ref tableRef
+	state := expectTable
+	depth := 0
+	t := sqlTokenizer{sql: from, words: true}
+	for {
+		kind, text := t.next()
+		if text == "" {
+			break
+		}
+		switch kind {
+		case tokComment:
+			continue
+		case tokText:
+			trimmed := strings.TrimSpace(text)
+			if trimmed == "" {
+				continue
+			}
+			if depth == 0 && state == expectAlias && trimmed == "." {
+				// schema qualified name
+				ref.name += "."
+				state = expectName
+				continue
+			}
+			for _, c := range trimmed {
+				switch {
+				case c == '(':
+					depth++
+				case c == ')':
+					depth--
+				case c == ',' && depth == 0:
+					if ref.name != "" {
+						refs = append(refs, ref)
+					}
+					ref = tableRef{}
+					state = expectTable
+					continue
+				}
+				if depth > 0 && state != skip {
+					ref = tableRef{}
+					state = skip
+				}
+			}
+			if state == expectName {
+				ref = tableRef{}
+				state = skip
+			}
+			continue
+		case tokWord, tokIdent:
+			if depth > 0 {
+				continue
+			}
+			if kind == tokWord && fromJoinWords[strings.ToUpper(text)] {
+				if ref.name != "" {
+					refs = append(refs, ref)
+				}
+				return refs
+			}
+			switch state {
+			case expectTable:
+				ref.name = unquoteIdentifier(text)
+				state = expectAlias
+			case expectName:
+				ref.name += unquoteIdentifier(text)
+				state = expectAlias
+			case expectAlias:
+				if kind == tokWord && strings.EqualFold(text, "AS") {
+					continue
+				}
+				ref.alias = text
+				state = skip
+			}
+		default:
+			if depth == 0 && state == expectTable {
+				state = skip
+			}
+		}
+	}
+	if ref.name != "" {
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// unquoteIdentifier removes the double quotes or backticks of a quoted
+// identifier.
+func unquoteIdentifier(name string) string {
+	if len(name) >= 2 && (name[0] == '"' || name[0] == '`') {
+		return name[1 : len(name)-1]
+	}
+	return name
+}
+
+// writeScopedWhere writes the WHERE clause of an UPDATE or DELETE of table
+// for whereFragments, or scope in their place, ANDed with the conditions of
+// the default scope of table.
+func writeScopedWhere(d SQLDialect, buf common.BufferWriter, table string, scope Scope, defaults []*whereFragment, whereFragments []*whereFragment, args *[]interface{}, pos *int64) error {
+	if scope != nil {
+		fragment, err := newWhereFragment(scope.ToSQL(table))
+		if err != nil {
+			return err
+		}
+		if len(defaults) == 0 {
+			writeScopeCondition(d, buf, fragment, args, pos)
+			return nil
+		}
+		sql, where := splitWhere(fragment.Condition)
+		if sql != "" {
+			buf.WriteRune(' ')
+			buf.WriteString(sql)
+		}
+		whereFragments = nil
+		if where != "" {
+			whereFragments = []*whereFragment{{Condition: where, Values: fragment.Values}}
+		}
+	}
+	whereFragments = append(append([]*whereFragment{}, whereFragments...), defaults...)
+	if len(whereFragments) == 0 {
+		return nil
+	}
+	buf.WriteString(" WHERE ")
+	return writeAndFragmentsToSQL(d, buf, whereFragments, args, pos)
+}
@@ -0,0 +1,117 @@
+package dat
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestFromTables(t *testing.T) {
+	assert.Equal(t, []tableRef{{"people", ""}}, fromTables("people"))
+	assert.Equal(t, []tableRef{{"people", "p"}, {"posts", ""}}, fromTables("people p, posts"))
+	assert.Equal(t, []tableRef{{"people", "p"}}, fromTables("people AS p INNER JOIN posts ON posts.user_id = p.id"))
+	assert.Equal(t, []tableRef{{"public.people", "p"}}, fromTables(`public."people" p`))
+	assert.Equal(t, []tableRef{{"posts", ""}}, fromTables("generate_series(1, 3) AS g, posts"))
+	assert.Empty(t, fromTables("(SELECT 1) AS s"))
+	assert.Empty(t, fromTables("$1 AS s"))
+}
+
+func testTableScopes() *TableScopes {
+	return NewTableScopes().
+		SoftDelete("people", "deleted_at").
+		Add("posts", NewScope(":TABLE.tenant_id = :tenant", M{"tenant": 7}))
+}
+
+func TestTableScopesSelect(t *testing.T) {
+	sql, args, err := Select("*").
+		From("people p").
+		Join("posts ON posts.user_id = p.id").
+		Where("p.id = $1", 1).
+		SetTableScopes(testTableScopes()).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT * FROM people p INNER JOIN posts ON posts.user_id = p.id
+		WHERE (p.id = $1) AND (p.deleted_at IS NULL)`), stripWS(sql))
+	assert.Equal(t, []interface{}{1}, args)
+
+	sql, args, err = Select("*").
+		From("posts").
+		Where("id = $1", 1).
+		SetTableScopes(testTableScopes()).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`SELECT * FROM posts WHERE (id = $1) AND (posts.tenant_id = $2)`), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 7}, args)
+
+	sql, _, err = Select("*").From("people").SetTableScopes(testTableScopes()).Unscoped().ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`SELECT * FROM people`), stripWS(sql))
+}
+
+func TestTableScopesSelectDoc(t *testing.T) {
+	posts := Select("id").From("posts").Where("posts.user_id = people.id")
+	sql, args, err := SelectDoc("id").
+		SetTableScopes(testTableScopes()).
+		Many("posts", posts).
+		From("people").
+		Where("id = $1", 1).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		SELECT row_to_json(dat__item.*)
+		FROM (
+			SELECT id,
+				(SELECT array_agg(dat__posts.*) FROM (
+					SELECT id FROM posts WHERE (posts.user_id = people.id) AND (posts.tenant_id = $1)
+				) AS dat__posts) AS "posts"
+			FROM people
+			WHERE (id = $2) AND (people.deleted_at IS NULL)
+		) as dat__item`), stripWS(sql))
+	assert.Equal(t, []interface{}{7, 1}, args)
+	// the sub query inherits the scopes only while it is rendered
+	assert.Nil(t, posts.tableScopes)
+}
+
+func TestTableScopesUpdate(t *testing.T) {
+	sql, args, err := Update("posts").
+		Set("title", "a").
+		Where("id = $1", 1).
+		SetTableScopes(testTableScopes()).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`UPDATE posts SET title = $1 WHERE (id = $2) AND (posts.tenant_id = $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", 1, 7}, args)
+
+	sql, args, err = Update("posts").
+		Set("title", "a").
+		Scope("WHERE id = $1", 1).
+		SetTableScopes(testTableScopes()).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`UPDATE posts SET title = $1 WHERE (id = $2) AND (posts.tenant_id = $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", 1, 7}, args)
+
+	sql, _, err = Update("posts").Set("title", "a").SetTableScopes(testTableScopes()).Unscoped().ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`UPDATE posts SET title = $1`), stripWS(sql))
+}
+
+func TestTableScopesDelete(t *testing.T) {
+	sql, args, err := DeleteFrom("posts").Where("id = $1", 1).SetTableScopes(testTableScopes()).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`DELETE FROM posts WHERE (id = $1) AND (posts.tenant_id = $2)`), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 7}, args)
+
+	sql, args, err = DeleteFrom("people").Where("id = $1", 1).Returning("id").SetTableScopes(testTableScopes()).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		UPDATE people SET deleted_at = CURRENT_TIMESTAMP
+		WHERE (id = $1) AND (people.deleted_at IS NULL)
+		RETURNING id`), stripWS(sql))
+	assert.Equal(t, []interface{}{1}, args)
+
+	sql, _, err = DeleteFrom("people").Where("id = $1", 1).SetTableScopes(testTableScopes()).Unscoped().ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`DELETE FROM people WHERE (id = $1)`), stripWS(sql))
+}
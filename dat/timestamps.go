@@ -0,0 +1,80 @@
+package dat
+
+import "github.com/matcherino/str"
+
+// Timestamps names the columns of records which are set to the current
+// time in SQL when records are written by InsertBuilder.Record,
+// UpdateBuilder.SetWhitelist and SetBlacklist, Upsert and Insect. Fields
+// tagged autocreate and autoupdate are set even without Timestamps.
+//
+// Columns of fields tagged readonly or missing from a record are not set.
+type Timestamps struct {
+	// Created columns are set when a record is inserted.
+	Created []string
+	// Updated columns are set when a record is inserted or updated.
+	Updated []string
+}
+
+// DefaultTimestamps sets created_at on insert and updated_at on insert and
+// update.
+var DefaultTimestamps = &Timestamps{
+	Created: []string{"created_at"},
+	Updated: []string{"updated_at"},
+}
+
+// currentTimestamp is written in place of the values of timestamp columns.
+var currentTimestamp = &Expression{Sql: "CURRENT_TIMESTAMP"}
+
+// columns returns the columns of the fields of record set when it is
+// inserted or, if !insert, updated. ts may be nil.
+func (ts *Timestamps) columns(record interface{}, insert bool) []string {
+	sm := reflectFields(record)
+	var cols []string
+	for _, name := range sm.DeclaredNames {
+		fi := sm.Names[name]
+		if fi.ReadOnly {
+			continue
+		}
+		if fi.AutoUpdate || insert && fi.AutoCreate ||
+			ts != nil && (str.SliceContains(ts.Updated, name) || insert && str.SliceContains(ts.Created, name)) {
+			cols = append(cols, name)
+		}
+	}
+	return cols
+}
+
+// insertColumns returns the timestamp columns of records set when they are
+// inserted, or nil if there are no records.
+func (ts *Timestamps) insertColumns(records []interface{}) []string {
+	if len(records) == 0 {
+		return nil
+	}
+	return ts.columns(records[0], true)
+}
+
+// createdOnly returns the columns of timestamps set on insert but not on
+// update, which are not changed when an upsert updates a row.
+func (ts *Timestamps) createdOnly(records []interface{}) []string {
+	if len(records) == 0 {
+		return nil
+	}
+	updated := ts.columns(records[0], false)
+	var cols []string
+	for _, column := range ts.insertColumns(records) {
+		if !str.SliceContains(updated, column) {
+			cols = append(cols, column)
+		}
+	}
+	return cols
+}
+
+// appendMissing returns columns with the columns of more it does not
+// contain appended.
+func appendMissing(columns, more []string) []string {
+	for _, column := range more {
+		if !str.SliceContains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,170 @@
+package dat
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type stampedRecord struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func TestTimestampsInsert(t *testing.T) {
+	sql, args, err := InsertInto("t").
+		Columns("name").
+		Record(&stampedRecord{Name: "a"}).
+		SetTimestamps(DefaultTimestamps).
+		Returning("id").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		INSERT INTO t (name, created_at, updated_at)
+		VALUES ($1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a"}, args)
+
+	// values of timestamp columns are ignored
+	sql, args, err = InsertInto("t").
+		Whitelist("*").
+		Record(&stampedRecord{Name: "a", CreatedAt: time.Now()}).
+		SetTimestamps(DefaultTimestamps).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		INSERT INTO t (id, name, created_at, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`), stripWS(sql))
+	assert.Equal(t, []interface{}{int64(0), "a"}, args)
+
+	// without timestamps
+	sql, _, err = InsertInto("t").Columns("name").Record(&stampedRecord{Name: "a"}).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`INSERT INTO t (name) VALUES ($1)`), stripWS(sql))
+
+	// Values are not records
+	sql, _, err = InsertInto("t").Columns("name").Values("a").SetTimestamps(DefaultTimestamps).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`INSERT INTO t (name) VALUES ($1)`), stripWS(sql))
+}
+
+func TestTimestampsTags(t *testing.T) {
+	type record struct {
+		Name    string    `db:"name"`
+		Created time.Time `db:"created,autocreate"`
+		Changed time.Time `db:"changed,autoupdate"`
+	}
+	assert.Equal(t, []string{"created", "changed"}, (*Timestamps)(nil).columns(&record{}, true))
+	assert.Equal(t, []string{"changed"}, (*Timestamps)(nil).columns(&record{}, false))
+
+	sql, _, err := InsertInto("t").Columns("name").Record(&record{}).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		INSERT INTO t (name, created, changed)
+		VALUES ($1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`), stripWS(sql))
+}
+
+func TestTimestampsUpdate(t *testing.T) {
+	sql, args, err := Update("t").
+		SetWhitelist(&stampedRecord{ID: 1, Name: "a"}, "name").
+		SetTimestamps(DefaultTimestamps).
+		Where("id = $1", 1).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`UPDATE t SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE (id = $2)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", 1}, args)
+
+	sql, args, err = Update("t").
+		SetBlacklist(&stampedRecord{ID: 1, Name: "a"}, "id", "created_at").
+		SetTimestamps(DefaultTimestamps).
+		Where("id = $1", 1).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`UPDATE t SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE (id = $2)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", 1}, args)
+
+	// Set is not a record
+	sql, args, err = Update("t").Set("updated_at", "x").SetTimestamps(DefaultTimestamps).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`UPDATE t SET updated_at = $1`), stripWS(sql))
+	assert.Equal(t, []interface{}{"x"}, args)
+}
+
+func TestTimestampsUpsert(t *testing.T) {
+	sql, args, err := Upsert("t").
+		Columns("id", "name").
+		Record(&stampedRecord{ID: 1, Name: "a"}).
+		SetTimestamps(DefaultTimestamps).
+		OnConflict("id").
+		Returning("id").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		INSERT INTO t (id, name, created_at, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, updated_at = EXCLUDED.updated_at
+		RETURNING id`), stripWS(sql))
+	assert.Equal(t, []interface{}{int64(1), "a"}, args)
+
+	sql, args, err = Upsert("t").
+		Columns("name").
+		Record(&stampedRecord{Name: "a"}).
+		SetTimestamps(DefaultTimestamps).
+		Where("name = $1", "a").
+		Returning("id").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH upd AS (
+			UPDATE t SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE (name = $2) RETURNING id
+		), ins AS (
+			INSERT INTO t (name, created_at, updated_at)
+			SELECT $1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+			WHERE NOT EXISTS (SELECT 1 FROM upd) RETURNING id
+		)
+		SELECT * FROM ins UNION ALL SELECT * FROM upd`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", "a"}, args)
+}
+
+func TestTimestampsInsect(t *testing.T) {
+	sql, args, err := Insect("t").
+		Columns("name").
+		Record(&stampedRecord{Name: "a"}).
+		SetTimestamps(DefaultTimestamps).
+		Where("name = $1", "a").
+		Returning("id").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		WITH sel AS (
+			SELECT id FROM t WHERE (name = $1)
+		), ins AS (
+			INSERT INTO t (name, created_at, updated_at)
+			SELECT $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+			WHERE NOT EXISTS (SELECT 1 FROM sel) RETURNING id
+		)
+		SELECT * FROM ins UNION ALL SELECT * FROM sel`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", "a"}, args)
+}
+
+func TestTimestampsReturningRecords(t *testing.T) {
+	rec := &stampedRecord{Name: "a"}
+	b := InsertInto("t").Columns("name").Record(rec).SetTimestamps(DefaultTimestamps).Returning("id")
+	records, err := ReturningRecords(b)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{rec}, records)
+	assert.Equal(t, []string{"id", "created_at", "updated_at"}, b.returnings)
+
+	ub := Update("t").SetWhitelist(rec, "name").SetTimestamps(DefaultTimestamps).Returning("*")
+	_, err = ReturningRecords(ub)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*"}, ub.returnings)
+
+	ub = Update("t").SetWhitelist(rec, "name").SetTimestamps(DefaultTimestamps).Returning("name")
+	_, err = ReturningRecords(ub)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "updated_at"}, ub.returnings)
+}
@@ -0,0 +1,235 @@
+package dat
+
+import "strings"
+
+// sqlTokenKind is the kind of a token read by sqlTokenizer.
+type sqlTokenKind int
+
+const (
+	// tokText is whitespace, operators, punctuation and numbers.
+	tokText sqlTokenKind = iota
+	// tokWord is a keyword or unquoted identifier.
+	tokWord
+	// tokString is a '', E'' or dollar quoted $tag$ $tag$ string.
+	tokString
+	// tokIdent is a "" or `` quoted identifier.
+	tokIdent
+	// tokComment is a -- line comment or /* */ block comment.
+	tokComment
+	// tokPlaceholder is $n, or ?n and ? if the tokenizer accepts ?.
+	tokPlaceholder
+	// tokCast is the :: cast operator.
+	tokCast
+	// tokParam is a :name scope field.
+	tokParam
+)
+
+// sqlTokenizer splits SQL into tokens so placeholders, keywords and scope
+// fields are not mistaken for text inside strings, identifiers and
+// comments. Runs of text are returned as a single token.
+type sqlTokenizer struct {
+	sql string
+	pos int
+	// question accepts ? and ?n placeholders
+	question bool
+	// backslash treats backslashes in '' strings as escapes, as MySQL does
+	backslash bool
+	// words returns keywords and identifiers as tokWord instead of text
+	words bool
+}
+
+// newSQLTokenizer returns a tokenizer for the placeholders and strings of
+// d. A nil d is the default Dialect.
+func newSQLTokenizer(d SQLDialect, sql string) sqlTokenizer {
+	return sqlTokenizer{
+		sql:       sql,
+		question:  placeholderStyle(d) != OrdinalPlaceholders,
+		backslash: backslashEscapes(d),
+	}
+}
+
+// tokenStart are the characters other than letters which may begin a
+// token. E begins escape strings such as E'\n'.
+var tokenStart = [256]bool{
+	'\'': true, '"': true, '`': true, '$': true, '?': true,
+	'-': true, '/': true, ':': true, 'E': true, 'e': true,
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' ||
+		('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z') ||
+		c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// next returns the next token and its text. The text is empty at the end
+// of sql.
+func (t *sqlTokenizer) next() (sqlTokenKind, string) {
+	start := t.pos
+	if start >= len(t.sql) {
+		return tokText, ""
+	}
+	if kind, end := t.scan(start); end > start {
+		t.pos = end
+		return kind, t.sql[start:end]
+	}
+
+	// text continues until a character which begins another token
+	end := start + 1
+	for end < len(t.sql) {
+		if c := t.sql[end]; tokenStart[c] || (t.words && isWordStart(c)) {
+			if _, tokEnd := t.scan(end); tokEnd > end {
+				break
+			}
+		}
+		end++
+	}
+	t.pos = end
+	return tokText, t.sql[start:end]
+}
+
+// scan returns the kind and end of the token other than text beginning at
+// i, or end == i if there is none.
+func (t *sqlTokenizer) scan(i int) (sqlTokenKind, int) {
+	sql := t.sql
+	c := sql[i]
+	switch {
+	case c == '\'':
+		return tokString, t.skipString(i, t.backslash)
+	case c == '"' || c == '`':
+		return tokIdent, skipQuoted(sql, i, c)
+	case isWordStart(c):
+		// E'' strings allow backslash escapes
+		if (c == 'E' || c == 'e') && i+1 < len(sql) && sql[i+1] == '\'' &&
+			(i == 0 || !isSQLWordChar(sql[i-1])) {
+			return tokString, t.skipString(i+1, true)
+		}
+		if !t.words || (i > 0 && isSQLWordChar(sql[i-1])) {
+			// words are text or inside a number such as 1e5
+			return tokText, i
+		}
+		j := i + 1
+		for j < len(sql) && isSQLWordChar(sql[j]) {
+			j++
+		}
+		return tokWord, j
+	case c == '$':
+		if i+1 >= len(sql) {
+			return tokText, i
+		}
+		if isDigit(sql[i+1]) {
+			if i > 0 && isSQLWordChar(sql[i-1]) {
+				// Postgres identifiers may contain $
+				return tokText, i
+			}
+			j := i + 2
+			for j < len(sql) && isDigit(sql[j]) {
+				j++
+			}
+			return tokPlaceholder, j
+		}
+		return tokString, skipDollarQuoted(sql, i)
+	case c == '?' && t.question:
+		j := i + 1
+		for j < len(sql) && isDigit(sql[j]) {
+			j++
+		}
+		return tokPlaceholder, j
+	case c == '-':
+		if i+1 < len(sql) && sql[i+1] == '-' {
+			j := strings.IndexByte(sql[i:], '\n')
+			if j < 0 {
+				return tokComment, len(sql)
+			}
+			return tokComment, i + j
+		}
+	case c == '/':
+		if i+1 < len(sql) && sql[i+1] == '*' {
+			return tokComment, skipBlockComment(sql, i)
+		}
+	case c == ':':
+		if i+1 < len(sql) {
+			if sql[i+1] == ':' {
+				return tokCast, i + 2
+			}
+			if isWordStart(sql[i+1]) && (i == 0 || !isSQLWordChar(sql[i-1])) {
+				j := i + 2
+				for j < len(sql) && isSQLWordChar(sql[j]) {
+					j++
+				}
+				return tokParam, j
+			}
+		}
+	}
+	return tokText, i
+}
+
+// skipString returns the index after the single quoted string starting at
+// start.
+func (t *sqlTokenizer) skipString(start int, backslash bool) int {
+	sql := t.sql
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case '\'':
+			if i+1 < len(sql) && sql[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// skipDollarQuoted returns the index after the $tag$ quoted string starting
+// at start, or start if it does not begin a tag or the tag is not closed.
+func skipDollarQuoted(sql string, start int) int {
+	i := start + 1
+	if sql[i] != '$' {
+		if !isWordStart(sql[i]) {
+			return start
+		}
+		for i < len(sql) && sql[i] != '$' {
+			if !isSQLWordChar(sql[i]) {
+				return start
+			}
+			i++
+		}
+		if i >= len(sql) {
+			return start
+		}
+	}
+	tag := sql[start : i+1]
+	end := strings.Index(sql[i+1:], tag)
+	if end < 0 {
+		return start
+	}
+	return i + 1 + end + len(tag)
+}
+
+// skipBlockComment returns the index after the possibly nested /* */
+// comment starting at start.
+func skipBlockComment(sql string, start int) int {
+	depth := 0
+	for i := start; i+1 < len(sql); i++ {
+		if sql[i] == '/' && sql[i+1] == '*' {
+			depth++
+			i++
+		} else if sql[i] == '*' && sql[i+1] == '/' {
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(sql)
+}
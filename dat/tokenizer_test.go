@@ -0,0 +1,91 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/matcherino/dat/postgres"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func tokenize(t sqlTokenizer) ([]sqlTokenKind, []string) {
+	var kinds []sqlTokenKind
+	var texts []string
+	for {
+		kind, text := t.next()
+		if text == "" {
+			return kinds, texts
+		}
+		kinds = append(kinds, kind)
+		texts = append(texts, text)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	sql := `SELECT "a$1", E'it\'s $1', 'x''$2' /* $3 /* nested */ */ FROM t -- $4
+WHERE b = $5::int AND c = $fn$ $6 $fn$ AND d = :field`
+	kinds, texts := tokenize(sqlTokenizer{sql: sql, words: true})
+	assert.Equal(t, []string{
+		"SELECT", " ", `"a$1"`, ", ", `E'it\'s $1'`, ", ", `'x''$2'`, " ", "/* $3 /* nested */ */", " ",
+		"FROM", " ", "t", " ", "-- $4", "\n",
+		"WHERE", " ", "b", " = ", "$5", "::", "int", " ", "AND", " ", "c", " = ", "$fn$ $6 $fn$", " ",
+		"AND", " ", "d", " = ", ":field",
+	}, texts)
+	assert.Equal(t, []sqlTokenKind{
+		tokWord, tokText, tokIdent, tokText, tokString, tokText, tokString, tokText, tokComment, tokText,
+		tokWord, tokText, tokWord, tokText, tokComment, tokText,
+		tokWord, tokText, tokWord, tokText, tokPlaceholder, tokCast, tokWord, tokText, tokWord, tokText, tokWord, tokText, tokString, tokText,
+		tokWord, tokText, tokWord, tokText, tokParam,
+	}, kinds)
+
+	kinds, texts = tokenize(sqlTokenizer{sql: `a = ? AND b = ?2 AND c = 'it\'s ?'`, question: true, backslash: true, words: true})
+	assert.Equal(t, []string{"a", " = ", "?", " ", "AND", " ", "b", " = ", "?2", " ", "AND", " ", "c", " = ", `'it\'s ?'`}, texts)
+	assert.Equal(t, tokPlaceholder, kinds[2])
+	assert.Equal(t, tokPlaceholder, kinds[8])
+}
+
+func TestPlaceholdersInQuotes(t *testing.T) {
+	b := Select("a").
+		From("t").
+		Where("b = '$1' AND \"$2\" = $1", 1).
+		Where("c = $1 -- $2", 2).
+		Where("d = $fn$ $1 $fn$ AND e = $1", 3)
+	sql, args, err := b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`SELECT a FROM t WHERE (b = '$1' AND "$2" = $1) AND (c = $2 -- $2
+	) AND (d = $fn$ $1 $fn$ AND e = $3)`), stripWS(sql))
+	checkSliceEqual(t, []interface{}{1, 2, 3}, args)
+
+	sql, args, err = InterpolateWith(postgres.New(), "SELECT '$1', $1, /* $1 */ \"$1\"", []interface{}{"x"})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT '$1', 'x', /* $1 */ "$1"`, sql)
+	assert.Nil(t, args)
+}
+
+func TestSplitWhere(t *testing.T) {
+	sql, where := splitWhere(`INNER JOIN (SELECT id FROM u WHERE u.x = 1) u ON u.id = 'where' WHERE u.id = $1`)
+	assert.Equal(t, `INNER JOIN (SELECT id FROM u WHERE u.x = 1) u ON u.id = 'where'`, sql)
+	assert.Equal(t, ` u.id = $1`, where)
+
+	sql, where = splitWhere(`INNER JOIN u ON u.id = "where"`)
+	assert.Equal(t, `INNER JOIN u ON u.id = "where"`, sql)
+	assert.Equal(t, "", where)
+}
+
+func TestMapScopeQuoted(t *testing.T) {
+	scope := NewScope("WHERE :TABLE.a = :a AND b = ':a' AND c = d::text", M{"a": 1})
+	sql, args := scope.ToSQL("t")
+	assert.Equal(t, `WHERE t.a = $1 AND b = ':a' AND c = d::text`, sql)
+	checkSliceEqual(t, []interface{}{1}, args)
+}
+
+func TestJSQLLeadingComment(t *testing.T) {
+	b := NewJSQLBuilder("-- select everything\nSELECT id FROM t")
+	assert.NoError(t, b.err)
+	assert.Equal(t, "id FROM t", b.query)
+}
+
+func TestTokenizerText(t *testing.T) {
+	kinds, texts := tokenize(sqlTokenizer{sql: `SELECT a FROM t WHERE b = $1 AND c = E'$2'`})
+	assert.Equal(t, []string{"SELECT a FROM t WHERE b = ", "$1", " AND c = ", `E'$2'`}, texts)
+	assert.Equal(t, []sqlTokenKind{tokText, tokPlaceholder, tokText, tokString}, kinds)
+}
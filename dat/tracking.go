@@ -0,0 +1,112 @@
+package dat
+
+import (
+	"reflect"
+)
+
+// Snapshot holds the values of the columns of a record when it was passed
+// to Track.
+type Snapshot struct {
+	typ    reflect.Type
+	values map[string]interface{}
+}
+
+func trackedValue(record interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(record)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return v, NewError("Track requires pointers to structs")
+	}
+	return v.Elem(), nil
+}
+
+// Track snapshots the values of the columns of record, a pointer to a
+// struct, which UpdateBuilder.SetChanged compares them to. Track the record
+// again after it is saved.
+//
+//	snap, err := dat.Track(p)
+func Track(record interface{}) (*Snapshot, error) {
+	v, err := trackedValue(record)
+	if err != nil {
+		return nil, err
+	}
+	tm := fieldMapper.TypeMap(v.Type())
+	columns := reflectSetColumns(record, nil)
+	values := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		values[column] = snapshotField(v, tm.Names[column].Index)
+	}
+	return &Snapshot{typ: v.Type(), values: values}, nil
+}
+
+// changedColumns returns the columns of record whose values differ from
+// those of snap.
+func changedColumns(record interface{}, snap *Snapshot) ([]string, error) {
+	v, err := trackedValue(record)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil || snap.typ != v.Type() {
+		return nil, NewError("SetChanged requires a snapshot of the record returned by Track")
+	}
+	tm := fieldMapper.TypeMap(v.Type())
+	var columns []string
+	for _, column := range reflectSetColumns(record, nil) {
+		original, ok := snap.values[column]
+		value := fieldValue(v, tm.Names[column].Index)
+		if !ok || !reflect.DeepEqual(original, value) {
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+// snapshotField returns a copy of the field of v at index.
+func snapshotField(v reflect.Value, index []int) interface{} {
+	field := reflect.ValueOf(fieldValue(v, index))
+	if !field.IsValid() {
+		return nil
+	}
+	return snapshotValue(field).Interface()
+}
+
+// snapshotValue copies the values which pointers, slices and maps refer
+// to so later changes to them are detected.
+func snapshotValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(snapshotValue(v.Elem()))
+		return p
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(s, v)
+		return s
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return m
+	}
+	return v
+}
+
+// IsNoop reports whether builder has nothing to execute, as for an
+// UpdateBuilder.SetChanged record without changes. Runners return an empty
+// result without executing it.
+func IsNoop(builder Builder) bool {
+	nb, ok := builder.(interface {
+		isNoop() bool
+	})
+	return ok && nb.isNoop()
+}
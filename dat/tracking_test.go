@@ -0,0 +1,113 @@
+package dat
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type trackedRecord struct {
+	ID    int64             `db:"id,pk"`
+	Name  string            `db:"name"`
+	Email *string           `db:"email"`
+	Tags  []string          `db:"tags"`
+	Attrs map[string]string `db:"attrs"`
+}
+
+func TestSetChanged(t *testing.T) {
+	email := "a@acme.com"
+	rec := &trackedRecord{ID: 1, Name: "a", Email: &email, Tags: []string{"x"}}
+	snap, err := Track(rec)
+	assert.NoError(t, err)
+
+	rec.Name = "b"
+	sql, args, err := Update("t").SetChanged(rec, snap).Where("id = $1", rec.ID).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`UPDATE t SET name = $1 WHERE (id = $2)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"b", int64(1)}, args)
+
+	// values which pointers and slices refer to are compared
+	*rec.Email = "b@acme.com"
+	rec.Tags[0] = "y"
+	rec.Attrs = map[string]string{"k": "v"}
+	cols, err := changedColumns(rec, snap)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "email", "tags", "attrs"}, cols)
+
+	snap, err = Track(rec)
+	assert.NoError(t, err)
+	cols, err = changedColumns(rec, snap)
+	assert.NoError(t, err)
+	assert.Empty(t, cols)
+}
+
+func TestSetChangedNoop(t *testing.T) {
+	rec := &trackedRecord{ID: 1, Name: "a"}
+	snap, err := Track(rec)
+	assert.NoError(t, err)
+
+	b := Update("t").SetChanged(rec, snap).Where("id = $1", rec.ID)
+	assert.True(t, IsNoop(b))
+	_, _, err = b.ToSQL()
+	assert.Error(t, err)
+
+	// other SET clauses are executed
+	b = Update("t").SetChanged(rec, snap).Set("name", "b").Where("id = $1", rec.ID)
+	assert.False(t, IsNoop(b))
+
+	assert.False(t, IsNoop(Update("t").Set("name", "b")))
+	assert.False(t, IsNoop(Select("id").From("t")))
+}
+
+func TestSetChangedTimestampsAndVersion(t *testing.T) {
+	type record struct {
+		ID        int64    `db:"id,pk"`
+		Name      string   `db:"name"`
+		Version   int64    `db:"version,version"`
+		UpdatedAt NullTime `db:"updated_at,autoupdate"`
+	}
+	rec := &record{ID: 1, Name: "a", Version: 2}
+	snap, err := Track(rec)
+	assert.NoError(t, err)
+
+	rec.Name = "b"
+	sql, args, err := Update("t").SetChanged(rec, snap).Where("id = $1", rec.ID).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		UPDATE t SET name = $1, updated_at = CURRENT_TIMESTAMP, version = t.version + 1
+		WHERE (id = $2) AND (t.version = $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"b", int64(1), int64(2)}, args)
+}
+
+func TestSetChangedErrors(t *testing.T) {
+	_, _, err := Update("t").SetChanged(&trackedRecord{}, nil).ToSQL()
+	assert.Error(t, err)
+
+	// snapshots of other types of records
+	snap, err := Track(&taggedRecord{})
+	assert.NoError(t, err)
+	_, _, err = Update("t").SetChanged(&trackedRecord{}, snap).ToSQL()
+	assert.Error(t, err)
+
+	_, err = Track(trackedRecord{})
+	assert.Error(t, err)
+	_, err = Track((*trackedRecord)(nil))
+	assert.Error(t, err)
+}
+
+func TestSnapshotsAreIndependent(t *testing.T) {
+	a := &trackedRecord{ID: 1, Name: "a"}
+	b := &trackedRecord{ID: 1, Name: "a"}
+	snapA, err := Track(a)
+	assert.NoError(t, err)
+	snapB, err := Track(b)
+	assert.NoError(t, err)
+
+	a.Name = "b"
+	cols, err := changedColumns(a, snapA)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name"}, cols)
+	cols, err = changedColumns(b, snapB)
+	assert.NoError(t, err)
+	assert.Empty(t, cols)
+}
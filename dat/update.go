@@ -2,17 +2,26 @@ package dat
 
 import (
 	"reflect"
-	"strconv"
+	"strings"
+
+	"github.com/matcherino/dat/common"
+	"github.com/matcherino/dat/reflectx"
+	"github.com/matcherino/str"
 )
 
 // UpdateBuilder contains the clauses for an UPDATE statement
 type UpdateBuilder struct {
 	Execer
 
+	dialect        SQLDialect
 	isInterpolated bool
+	with           withClause
 	table          string
+	timestamps     *Timestamps
 	setClauses     []*setClause
 	fromList       string
+	records        *updateRecords
+	record         interface{}
 	whereFragments []*whereFragment
 	orderBys       []string
 	limitCount     uint64
@@ -21,12 +30,24 @@ type UpdateBuilder struct {
 	offsetValid    bool
 	returnings     []string
 	scope          Scope
+	tableScopes    *TableScopes
+	unscoped       bool
+	unchanged      bool
 	err            error
 }
 
 type setClause struct {
 	column string
 	value  interface{}
+	// fromRecord is set for the clauses of SetWhitelist and SetBlacklist
+	fromRecord bool
+}
+
+// updateRecords are the records of FromRecords.
+type updateRecords struct {
+	keyColumns []string
+	setColumns []string
+	records    reflect.Value
 }
 
 // NewUpdateBuilder creates a new UpdateBuilder for the given table
@@ -52,50 +73,111 @@ func (b *UpdateBuilder) SetMap(clauses map[string]interface{}) *UpdateBuilder {
 	return b
 }
 
-// SetBlacklist creates SET clause(s) using a record and blacklist of columns
+// SetBlacklist creates SET clause(s) using a record and blacklist of columns.
+// Fields tagged pk or readonly are not set.
 func (b *UpdateBuilder) SetBlacklist(rec interface{}, blacklist ...string) *UpdateBuilder {
 	if len(blacklist) == 0 {
 		b.err = NewError("UpdateBuilder.SetBlacklist requires a list of columns names")
 		return b
 	}
 
-	columns := reflectExcludeColumns(rec, blacklist)
-	ind := reflect.Indirect(reflect.ValueOf(rec))
-	vals, err := valuesFor(ind.Type(), ind, columns)
-	if err != nil {
-		b.err = err
-		return b
-	}
-
-	for i, val := range vals {
-		b.Set(columns[i], val)
-	}
-
+	b.setRecord(rec, reflectSetColumns(rec, blacklist))
 	return b
 }
 
 // SetWhitelist creates SET clause(s) using a record and whitelist of columns.
-// To specify all columns, use "*".
+// To specify all columns, except those tagged pk or readonly, use "*".
+// Timestamp columns, see Timestamps, are always set. A field tagged version
+// is incremented and the row is updated only if its version is unchanged.
 func (b *UpdateBuilder) SetWhitelist(rec interface{}, whitelist ...string) *UpdateBuilder {
-	var columns []string
+	columns := whitelist
 	if len(whitelist) == 0 || whitelist[0] == "*" {
-		columns = reflectColumns(rec)
-	} else {
-		columns = whitelist
+		columns = reflectSetColumns(rec, nil)
 	}
+	b.setRecord(rec, columns)
+	return b
+}
 
-	ind := reflect.Indirect(reflect.ValueOf(rec))
-	vals, err := valuesFor(ind.Type(), ind, columns)
+// SetChanged creates SET clause(s) for the columns of a record whose values
+// differ from snap, its snapshot returned by Track. Timestamps and version
+// columns are set as in SetWhitelist. If no column changed Exec returns
+// without executing the statement.
+func (b *UpdateBuilder) SetChanged(rec interface{}, snap *Snapshot) *UpdateBuilder {
+	columns, err := changedColumns(rec, snap)
 	if err != nil {
 		b.err = err
 		return b
 	}
+	if len(columns) == 0 {
+		b.unchanged = true
+		return b
+	}
+	b.setRecord(rec, columns)
+	return b
+}
+
+// isNoop is true if SetChanged found no changed columns and there is
+// nothing else to set.
+func (b *UpdateBuilder) isNoop() bool {
+	return b.unchanged && b.err == nil && len(b.setClauses) == 0 && b.records == nil
+}
+
+func (b *UpdateBuilder) setRecord(rec interface{}, columns []string) {
+	vals, err := recordValues(reflect.ValueOf(rec), columns, nil)
+	if err != nil {
+		b.err = err
+		return
+	}
 
+	b.record = rec
 	for i, val := range vals {
-		b.Set(columns[i], val)
+		b.setClauses = append(b.setClauses, &setClause{column: columns[i], value: val, fromRecord: true})
 	}
+}
 
-	return b
+// recordSetClauses returns the set clauses with the timestamp columns of
+// the record of SetWhitelist or SetBlacklist set to the current time and
+// its version column, if any, incremented.
+func (b *UpdateBuilder) recordSetClauses() []*setClause {
+	if b.record == nil {
+		return b.setClauses
+	}
+	columns := b.timestamps.columns(b.record, false)
+	values := make(map[string]interface{}, len(columns)+1)
+	for _, column := range columns {
+		values[column] = currentTimestamp
+	}
+	if version := versionColumn(b.record); version != "" {
+		columns = append(columns, version)
+		values[version] = versionIncrement(tableQualifier(b.table), version)
+	}
+	if len(columns) == 0 {
+		return b.setClauses
+	}
+	clauses := make([]*setClause, 0, len(b.setClauses)+len(columns))
+	var set []string
+	for _, c := range b.setClauses {
+		if value, ok := values[c.column]; ok && c.fromRecord {
+			c = &setClause{column: c.column, value: value, fromRecord: true}
+		}
+		clauses = append(clauses, c)
+		set = append(set, c.column)
+	}
+	for _, column := range columns {
+		if !str.SliceContains(set, column) {
+			clauses = append(clauses, &setClause{column: column, value: values[column], fromRecord: true})
+		}
+	}
+	return clauses
+}
+
+// versionedRecords returns 1 if the record of SetWhitelist or SetBlacklist
+// has a version column.
+func (b *UpdateBuilder) versionedRecords() int {
+	if versionColumn(b.record) == "" {
+		return 0
+	}
+	return 1
 }
 
 // From sets the fromList to UPDATE FROM. JOINs may also be defined here.
@@ -105,6 +187,102 @@ func (b *UpdateBuilder) From(from string) *UpdateBuilder {
 	return b
 }
 
+// FromRecords updates the row matching keyColumns of each of records, a
+// slice of structs or pointers to structs, setting setColumns to the values
+// of the record in a single statement
+//
+//	UPDATE t SET c = v.c FROM (VALUES ($1::bigint, $2::text), ($3, $4)) AS v(id, c) WHERE t.id = v.id
+//
+// Columns are the db names of fields. The values of the first record are
+// cast to the type of their field, see postgres.SQLType. Set, Where and
+// Returning may be combined with it and refer to the record values as v.column.
+func (b *UpdateBuilder) FromRecords(keyColumns []string, setColumns []string, records interface{}) *UpdateBuilder {
+	if len(keyColumns) == 0 || len(setColumns) == 0 {
+		b.err = NewError("FromRecords requires 1 or more key and set columns")
+		return b
+	}
+	val := reflect.ValueOf(records)
+	if val.Kind() != reflect.Slice || val.Len() == 0 {
+		b.err = NewError("FromRecords requires a slice of 1 or more records")
+		return b
+	}
+	b.records = &updateRecords{keyColumns: keyColumns, setColumns: setColumns, records: val}
+	return b
+}
+
+// writeValues writes the VALUES list of the records aliased as v.
+func (r *updateRecords) writeValues(d SQLDialect, buf common.BufferWriter, args *[]interface{}, pos *int64) error {
+	columns := append(append([]string{}, r.keyColumns...), r.setColumns...)
+	typeBuf := bufPool.Get()
+	defer bufPool.Put(typeBuf)
+
+	buf.WriteString("(VALUES ")
+	for i := 0; i < r.records.Len(); i++ {
+		rec := reflect.Indirect(r.records.Index(i))
+		if rec.Kind() != reflect.Struct {
+			return NewError("FromRecords requires a slice of structs")
+		}
+		values, err := recordValues(rec, columns, nil)
+		if err != nil {
+			return err
+		}
+		var fields map[string]*reflectx.FieldInfo
+		if i == 0 {
+			fields = fieldMapper.TypeMap(rec.Type()).Names
+		} else {
+			buf.WriteString(", ")
+		}
+		buf.WriteRune('(')
+		for j, v := range values {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			writePlaceholder(buf, int(*pos))
+			if fields != nil {
+				// VALUES columns take the types of the first row
+				typeBuf.Reset()
+				d.WriteReflectedType(typeBuf, fields[columns[j]].Field.Type)
+				if typeBuf.Len() > 0 {
+					buf.WriteString("::")
+					buf.WriteString(typeBuf.String())
+				}
+			}
+			*args = append(*args, v)
+			*pos++
+		}
+		buf.WriteRune(')')
+	}
+	buf.WriteString(") AS v(")
+	for i, c := range columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writeIdentifier(buf, c)
+	}
+	buf.WriteRune(')')
+	return nil
+}
+
+// keyFragment returns the condition matching rows of table to the records.
+func (r *updateRecords) keyFragment(table string) *whereFragment {
+	// the alias of the table, if any, qualifies columns
+	fields := strings.Fields(table)
+	qualifier := fields[len(fields)-1]
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	for i, c := range r.keyColumns {
+		if i > 0 {
+			buf.WriteString(" AND ")
+		}
+		buf.WriteString(qualifier)
+		buf.WriteRune('.')
+		writeIdentifier(buf, c)
+		buf.WriteString(" = v.")
+		writeIdentifier(buf, c)
+	}
+	return &whereFragment{Condition: buf.String()}
+}
+
 // ScopeMap uses a predefined scope in place of WHERE.
 func (b *UpdateBuilder) ScopeMap(mapScope *MapScope, m M) *UpdateBuilder {
 	b.scope = mapScope.mergeClone(m)
@@ -156,70 +334,122 @@ func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
 	return b
 }
 
+// SetTableScopes sets the default scopes of tables, overriding those of the
+// connection.
+func (b *UpdateBuilder) SetTableScopes(scopes *TableScopes) *UpdateBuilder {
+	b.tableScopes = scopes
+	return b
+}
+
+// Unscoped bypasses the default scope of the table.
+func (b *UpdateBuilder) Unscoped() *UpdateBuilder {
+	b.unscoped = true
+	return b
+}
+
+// SetTimestamps sets the timestamp columns of records, overriding those of
+// the connection. With nil only fields tagged autocreate and autoupdate are
+// set.
+func (b *UpdateBuilder) SetTimestamps(timestamps *Timestamps) *UpdateBuilder {
+	b.timestamps = timestamps
+	return b
+}
+
 // ToSQL serialized the UpdateBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *UpdateBuilder) ToSQL() (string, []interface{}, error) {
+	return b.toSQL(b.Dialect(), b.tableScopes)
+}
+
+// toSQL is ToSQL with the dialect d and the default scopes of tables
+// scopes.
+func (b *UpdateBuilder) toSQL(d SQLDialect, scopes *TableScopes) (string, []interface{}, error) {
 	if b.err != nil {
 		return "", nil, b.err
 	}
+	if len(b.returnings) > 0 {
+		if err := ErrUnsupported(d, FeatureReturning); err != nil {
+			return "", nil, err
+		}
+	}
 	if len(b.table) == 0 {
 		return "", nil, NewError("no table specified")
 	}
-	if len(b.setClauses) == 0 {
+	setClauses := b.recordSetClauses()
+	if len(setClauses) == 0 && b.records == nil {
 		return "", nil, NewError("no set clauses specified")
 	}
+	if b.records != nil {
+		if err := ErrUnsupported(d, FeatureUpdateFromValues); err != nil {
+			return "", nil, err
+		}
+		if b.scope != nil {
+			return "", nil, NewError("FromRecords cannot be combined with a scope")
+		}
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 	var args []interface{}
+	var placeholderStartPos int64 = 1
+
+	if err := writeWith(d, buf, &b.with, &args, &placeholderStartPos); err != nil {
+		return "", nil, err
+	}
 
 	buf.WriteString("UPDATE ")
 	buf.WriteString(b.table)
 	buf.WriteString(" SET ")
 
-	var placeholderStartPos int64 = 1
-
-	// Build SET clause SQL with placeholders and add values to args
-	for i, c := range b.setClauses {
-		if i > 0 {
-			buf.WriteString(", ")
-		}
-		writeIdentifier(buf, c.column)
-		if e, ok := c.value.(*Expression); ok {
-			start := placeholderStartPos
-			buf.WriteString(" = ")
-			// map relative $1, $2 placeholders to absolute
-			remapPlaceholders(buf, e.Sql, start)
-			args = append(args, e.Args...)
-			placeholderStartPos += int64(len(e.Args))
-		} else {
-			if placeholderStartPos < maxLookup {
-				buf.WriteString(equalsPlaceholderTab[placeholderStartPos])
-			} else {
-				buf.WriteString(" = $")
-				buf.WriteString(strconv.FormatInt(placeholderStartPos, 10))
+	var recordSets int
+	if b.records != nil {
+		for i, c := range b.records.setColumns {
+			if i > 0 {
+				buf.WriteString(", ")
 			}
-			placeholderStartPos++
-			args = append(args, c.value)
+			writeIdentifier(buf, c)
+			buf.WriteString(" = v.")
+			writeIdentifier(buf, c)
 		}
+		recordSets = len(b.records.setColumns)
 	}
 
-	if len(b.fromList) > 0 {
-		buf.WriteString(" FROM ")
-		buf.WriteString(b.fromList)
+	// Build SET clause SQL with placeholders and add values to args
+	if recordSets > 0 && len(setClauses) > 0 {
+		buf.WriteString(", ")
+	}
+	if err := writeSetClauses(d, buf, setClauses, &args, &placeholderStartPos); err != nil {
+		return NewDatSQLErr(err)
 	}
 
-	if b.scope == nil {
-		if len(b.whereFragments) > 0 {
-			buf.WriteString(" WHERE ")
-			writeAndFragmentsToSQL(buf, b.whereFragments, &args, &placeholderStartPos)
+	whereFragments := b.whereFragments
+	if version := versionColumn(b.record); version != "" {
+		if b.scope != nil {
+			return NewDatSQLError("versioned records cannot be combined with a scope")
 		}
-	} else {
-		fragment, err := newWhereFragment(b.scope.ToSQL(b.table))
-		if err != nil {
+		whereFragments = append(append([]*whereFragment{}, whereFragments...), versionCondition(tableQualifier(b.table), version, b.record))
+	}
+	if b.records != nil {
+		buf.WriteString(" FROM ")
+		if err := b.records.writeValues(d, buf, &args, &placeholderStartPos); err != nil {
 			return NewDatSQLErr(err)
 		}
-		writeScopeCondition(buf, fragment, &args, &placeholderStartPos)
+		if len(b.fromList) > 0 {
+			buf.WriteString(", ")
+			buf.WriteString(b.fromList)
+		}
+		whereFragments = append([]*whereFragment{b.records.keyFragment(b.table)}, whereFragments...)
+	} else if len(b.fromList) > 0 {
+		buf.WriteString(" FROM ")
+		buf.WriteString(b.fromList)
+	}
+
+	defaults, err := b.defaultScopeFragments(scopes)
+	if err != nil {
+		return NewDatSQLErr(err)
+	}
+	if err := writeScopedWhere(d, buf, b.table, b.scope, defaults, whereFragments, &args, &placeholderStartPos); err != nil {
+		return NewDatSQLErr(err)
 	}
 
 	// Ordering and limiting
@@ -255,3 +485,49 @@ func (b *UpdateBuilder) ToSQL() (string, []interface{}, error) {
 
 	return buf.String(), args, nil
 }
+
+// defaultScopeFragments returns the conditions of the default scope of the
+// table in scopes.
+func (b *UpdateBuilder) defaultScopeFragments(scopes *TableScopes) ([]*whereFragment, error) {
+	if b.unscoped {
+		return nil, nil
+	}
+	return scopes.fragments(b.table)
+}
+
+// writeSetClauses writes column = value for each of clauses.
+func writeSetClauses(d SQLDialect, buf common.BufferWriter, clauses []*setClause, args *[]interface{}, pos *int64) error {
+	for i, c := range clauses {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writeIdentifier(buf, c.column)
+		buf.WriteString(" = ")
+		if err := writeValue(d, buf, c.value, args, pos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeValue writes a placeholder for value. Builders are written as sub
+// queries and Expressions in place.
+func writeValue(d SQLDialect, buf common.BufferWriter, value interface{}, args *[]interface{}, pos *int64) error {
+	if sub, ok := value.(Builder); ok {
+		return writeSubquery(d, buf, sub, true, args, pos)
+	}
+	if e, ok := value.(*Expression); ok {
+		if hasBuilderArg(e.Args) {
+			return writeBuilderArgs(d, buf, e.Sql, e.Args, args, pos)
+		}
+		// map relative $1, $2 placeholders to absolute
+		remapPlaceholders(d, buf, e.Sql, *pos)
+		*args = append(*args, e.Args...)
+		*pos += int64(len(e.Args))
+		return nil
+	}
+	writePlaceholder(buf, int(*pos))
+	*pos++
+	*args = append(*args, value)
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"gopkg.in/stretchr/testify.v1/assert"
+
+	"github.com/matcherino/dat/postgres"
 )
 
 func BenchmarkUpdateValuesSql(b *testing.B) {
@@ -124,3 +126,53 @@ func TestUpdateBeyondMaxLookup(t *testing.T) {
 	assert.Equal(t, expectedArgs, args)
 
 }
+
+type recordScore struct {
+	ID    int64      `db:"id"`
+	Name  string     `db:"name"`
+	Score *int       `db:"score"`
+	Other NullString `db:"other"`
+}
+
+func TestUpdateFromRecords(t *testing.T) {
+	score := 5
+	records := []*recordScore{
+		{ID: 1, Name: "a", Score: &score},
+		{ID: 2, Name: "b"},
+	}
+	sql, args, err := Update("people p").
+		FromRecords([]string{"id"}, []string{"name", "score"}, records).
+		Set("updated_at", Expr("NOW()")).
+		Where("p.active = $1", true).
+		Returning("p.id", "v.name").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE people p SET name = v.name, score = v.score, updated_at = NOW() "+
+		"FROM (VALUES ($1::bigint, $2::text, $3::bigint), ($4, $5, $6)) AS v(id, name, score) "+
+		"WHERE (p.id = v.id) AND (p.active = $7) RETURNING p.id,v.name", sql)
+	assert.Equal(t, []interface{}{int64(1), "a", &score, int64(2), "b", (*int)(nil), true}, args)
+}
+
+func TestUpdateFromRecordsUncast(t *testing.T) {
+	records := []recordScore{{ID: 1, Other: NullStringFrom("x")}}
+	sql, args, err := Update("people").
+		FromRecords([]string{"id"}, []string{"other"}, records).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE people SET other = v.other FROM (VALUES ($1::bigint, $2)) AS v(id, other) WHERE (people.id = v.id)", sql)
+	assert.Equal(t, []interface{}{int64(1), NullStringFrom("x")}, args)
+}
+
+func TestUpdateFromRecordsErrors(t *testing.T) {
+	_, _, err := Update("people").FromRecords([]string{"id"}, []string{"name"}, []recordScore{}).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Update("people").FromRecords(nil, []string{"name"}, []recordScore{{}}).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Update("people").FromRecords([]string{"id"}, []string{"missing"}, []recordScore{{}}).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Update("people").FromRecords([]string{"id"}, []string{"name"}, []int{1}).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Update("people").SetDialect(questionDialect{postgres.New()}).
+		FromRecords([]string{"id"}, []string{"name"}, []recordScore{{}}).ToSQL()
+	assert.Error(t, err)
+}
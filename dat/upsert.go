@@ -1,20 +1,27 @@
 package dat
 
-import "reflect"
-
-// UpsertBuilder contains the clauses for an INSERT statement
+// UpsertBuilder updates a row or inserts it if it does not exist.
+//
+// With OnConflict, rows are inserted with INSERT ... ON CONFLICT DO UPDATE
+// which is safe under concurrency. Otherwise, and on servers without
+// FeatureOnConflict such as Postgres before 9.5, the row matching Where is
+// updated and a row is inserted if none matched. Concurrent upserts of a
+// new row may then fail with a unique violation.
 type UpsertBuilder struct {
 	Execer
 
-	cols           []string
-	err            error
-	isBlacklist    bool
-	isInterpolated bool
-	record         interface{}
-	returnings     []string
-	table          string
-	vals           []interface{}
-	whereFragments []*whereFragment
+	dialect         SQLDialect
+	cols            []string
+	conflictColumns []string
+	err             error
+	isBlacklist     bool
+	isInterpolated  bool
+	records         []interface{}
+	returnings      []string
+	table           string
+	timestamps      *Timestamps
+	vals            [][]interface{}
+	whereFragments  []*whereFragment
 }
 
 // NewUpsertBuilder creates a new UpsertBuilder for the given table.
@@ -40,21 +47,40 @@ func (b *UpsertBuilder) Blacklist(columns ...string) *UpsertBuilder {
 }
 
 // Whitelist defines a whitelist of columns to be inserted. To
-// specify all columsn of a record use "*".
+// specify all columns of a record, except those tagged readonly, use "*".
 func (b *UpsertBuilder) Whitelist(columns ...string) *UpsertBuilder {
 	b.cols = columns
 	return b
 }
 
-// Values appends a set of values to the statement
+// Values appends a set of values to the statement. More than one row
+// requires OnConflict.
 func (b *UpsertBuilder) Values(vals ...interface{}) *UpsertBuilder {
-	b.vals = vals
+	b.vals = append(b.vals, vals)
 	return b
 }
 
-// Record pulls in values to match Columns from the record
+// Record pulls in values to match Columns from the record. More than one
+// record requires OnConflict.
 func (b *UpsertBuilder) Record(record interface{}) *UpsertBuilder {
-	b.record = record
+	b.records = append(b.records, record)
+	return b
+}
+
+// OnConflict sets the columns of the unique index identifying existing
+// rows. Rows are then upserted with INSERT ... ON CONFLICT (columns) DO
+// UPDATE SET, updating the other columns, and Where is the condition of
+// DO UPDATE. Servers without ON CONFLICT update the row whose columns
+// equal the values instead.
+//
+// Records without Where or OnConflict conflict on their inserted columns
+// tagged pk.
+func (b *UpsertBuilder) OnConflict(columns ...string) *UpsertBuilder {
+	if len(columns) == 0 {
+		b.err = NewError("OnConflict requires 1 or more columns")
+		return b
+	}
+	b.conflictColumns = columns
 	return b
 }
 
@@ -64,48 +90,68 @@ func (b *UpsertBuilder) Returning(columns ...string) *UpsertBuilder {
 	return b
 }
 
+// SetTimestamps sets the timestamp columns of records, overriding those of
+// the connection. With nil only fields tagged autocreate and autoupdate are
+// set.
+func (b *UpsertBuilder) SetTimestamps(timestamps *Timestamps) *UpsertBuilder {
+	b.timestamps = timestamps
+	return b
+}
+
 // ToSQL serialized the UpsertBuilder to a SQL string
 // It returns the string with placeholders and a slice of query arguments
 func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
+	return b.toSQL(b.Dialect())
+}
+
+// toSQL is ToSQL with the dialect d.
+func (b *UpsertBuilder) toSQL(d SQLDialect) (string, []interface{}, error) {
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
 	if len(b.table) == 0 {
 		return NewDatSQLError("no table specified")
 	}
-
-	lenCols := len(b.cols)
-	if lenCols == 0 {
-		return NewDatSQLError("no columns specified")
+	cols, rows, err := conflictRows(d, b.cols, b.isBlacklist, b.vals, b.records, b.timestamps.insertColumns(b.records))
+	if err != nil {
+		return NewDatSQLErr(err)
 	}
-	if len(b.vals) == 0 && b.record == nil {
-		return NewDatSQLError("no values or records specified")
+	version := b.versionColumn()
+	if version != "" {
+		if cols, rows, err = appendVersion(cols, rows, version, b.records); err != nil {
+			return NewDatSQLErr(err)
+		}
 	}
-	if b.record == nil && b.cols[0] == "*" {
-		return NewDatSQLError(`"*" can only be used in conjunction with Record`)
+	returnings := b.returnings
+	if len(returnings) == 0 {
+		returnings = cols
 	}
-	if b.record == nil && b.isBlacklist {
-		return NewDatSQLError(`Blacklist can only be used in conjunction with Record`)
+	conflictColumns := recordConflictColumns(b.conflictColumns, b.whereFragments, cols, b.records)
+	if len(conflictColumns) > 0 && DialectSupports(d, FeatureOnConflict) {
+		return b.onConflictSQL(d, cols, rows, conflictColumns, returnings, version)
 	}
-	// build where clause from columns and values
-	if len(b.whereFragments) == 0 {
-		return NewDatSQLError("where clause required for upsert")
+	if version != "" {
+		return NewDatSQLError("upserting versioned records requires ON CONFLICT")
 	}
-	cols := b.cols
-	returnings := b.returnings
-	vals := b.vals
 
-	// reflect fields removing blacklisted columns
-	if b.record != nil && b.isBlacklist {
-		cols = reflectExcludeColumns(b.record, cols)
+	if err := ErrUnsupported(d, FeatureDataModifyingCTE); err != nil {
+		return NewDatSQLErr(err)
 	}
-	// reflect all fields
-	if b.record != nil && cols[0] == "*" {
-		cols = reflectColumns(b.record)
+	if len(rows) > 1 {
+		return NewDatSQLError("upserting more than one row requires ON CONFLICT")
 	}
-
-	if len(returnings) == 0 {
-		returnings = cols
+	vals := rows[0]
+	whereFragments := b.whereFragments
+	if len(conflictColumns) > 0 {
+		fragments, err := conflictWhere(conflictColumns, cols, vals)
+		if err != nil {
+			return NewDatSQLErr(err)
+		}
+		whereFragments = append(fragments, whereFragments...)
+	}
+	// build where clause from columns and values
+	if len(whereFragments) == 0 {
+		return NewDatSQLError("where clause required for upsert")
 	}
 
 	/*
@@ -144,14 +190,6 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
 			Where("name = $1", "mario").
 			Returning("id", "name", "email")
 	*/
-	if b.record != nil {
-		ind := reflect.Indirect(reflect.ValueOf(b.record))
-		var err error
-		vals, err = valuesFor(ind.Type(), ind, cols)
-		if err != nil {
-			return NewDatSQLErr(err)
-		}
-	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
@@ -182,11 +220,15 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
 
 	buf.WriteString("WITH upd AS ( ")
 
+	createdOnly := b.timestamps.createdOnly(b.records)
 	ub := NewUpdateBuilder(b.table)
+	ub.dialect = d
 	for i, col := range cols {
-		ub.Set(col, vals[i])
+		if indexOfString(createdOnly, col) < 0 {
+			ub.Set(col, vals[i])
+		}
 	}
-	ub.whereFragments = b.whereFragments
+	ub.whereFragments = whereFragments
 	ub.returnings = returnings
 	updateSQL, args, err := ub.ToSQL()
 	if err != nil {
@@ -202,7 +244,17 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
 	writeIdentifiers(buf, cols, ",")
 	buf.WriteString(") SELECT ")
 
-	writePlaceholders(buf, len(vals), ",", 1)
+	// the values are numbered as in the update, whose args are reused
+	var insertArgs []interface{}
+	var placeholderStartPos int64 = 1
+	for i, val := range vals {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		if err := writeValue(d, buf, val, &insertArgs, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
+	}
 
 	buf.WriteString(" WHERE NOT EXISTS (SELECT 1 FROM upd) RETURNING ")
 	writeIdentifiers(buf, returnings, ",")
@@ -212,6 +264,65 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
 	return buf.String(), args, nil
 }
 
+// onConflictSQL returns
+//
+//	INSERT INTO t (a, b) VALUES ($1, $2) ON CONFLICT (a) DO UPDATE SET b = EXCLUDED.b RETURNING a, b
+//
+// The version column of versioned records is incremented and existing rows
+// are updated only if their version is that of the record.
+func (b *UpsertBuilder) onConflictSQL(d SQLDialect, cols []string, rows [][]interface{}, conflictColumns []string, returnings []string, version string) (string, []interface{}, error) {
+	if err := ErrUnsupported(d, FeatureReturning); err != nil {
+		return NewDatSQLErr(err)
+	}
+	createdOnly := b.timestamps.createdOnly(b.records)
+	var updates []string
+	for _, col := range cols {
+		if indexOfString(conflictColumns, col) < 0 && indexOfString(createdOnly, col) < 0 && col != version {
+			updates = append(updates, col)
+		}
+	}
+	if len(updates) == 0 && version == "" {
+		// a row is returned only if it is updated
+		updates = conflictColumns
+	}
+	whereFragments := b.whereFragments
+	if version != "" {
+		condition := bufPool.Get()
+		defer bufPool.Put(condition)
+		writeQualifiedIdentifier(condition, tableQualifier(b.table), version)
+		condition.WriteString(" = EXCLUDED.")
+		writeIdentifier(condition, version)
+		whereFragments = append(append([]*whereFragment{}, whereFragments...), &whereFragment{Condition: condition.String()})
+	}
+
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	var args []interface{}
+	var placeholderStartPos int64 = 1
+
+	if err := writeConflictInsert(d, buf, b.table, cols, rows, conflictColumns, &args, &placeholderStartPos); err != nil {
+		return NewDatSQLErr(err)
+	}
+	writeExcluded(buf, updates)
+	if version != "" {
+		if len(updates) > 0 {
+			buf.WriteString(", ")
+		}
+		writeIdentifier(buf, version)
+		buf.WriteString(" = ")
+		buf.WriteString(versionIncrement(tableQualifier(b.table), version).Sql)
+	}
+	if len(whereFragments) > 0 {
+		buf.WriteString(" WHERE ")
+		if err := writeAndFragmentsToSQL(d, buf, whereFragments, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
+	}
+	buf.WriteString(" RETURNING ")
+	writeIdentifiers(buf, returnings, ",")
+	return buf.String(), args, nil
+}
+
 // Where appends a WHERE clause to the statement for the given string and args
 // or map of column/value pairs
 func (b *UpsertBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *UpsertBuilder {
@@ -223,3 +334,19 @@ func (b *UpsertBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *U
 	}
 	return b
 }
+
+// versionColumn returns the version column of the records, if any.
+func (b *UpsertBuilder) versionColumn() string {
+	if len(b.records) == 0 {
+		return ""
+	}
+	return versionColumn(b.records[0])
+}
+
+// versionedRecords returns the number of versioned records.
+func (b *UpsertBuilder) versionedRecords() int {
+	if b.versionColumn() == "" {
+		return 0
+	}
+	return len(b.records)
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"gopkg.in/stretchr/testify.v1/assert"
+
+	"github.com/matcherino/dat/postgres"
 )
 
 func TestUpsertSQLMissingWhere(t *testing.T) {
@@ -123,3 +125,83 @@ func TestUpsertSQLBlacklistRecord(t *testing.T) {
 	assert.Equal(t, stripWS(expected), stripWS(sql))
 	assert.Equal(t, []interface{}{1, 4}, args)
 }
+
+// preConflictDialect is Postgres before 9.5 which has no ON CONFLICT.
+type preConflictDialect struct {
+	*postgres.Postgres
+}
+
+func (preConflictDialect) Name() string {
+	return "postgres 9.4"
+}
+
+func (preConflictDialect) Supports(feature Feature) bool {
+	return feature != FeatureOnConflict
+}
+
+func TestUpsertSQLOnConflict(t *testing.T) {
+	sql, args, err := Upsert("tab").
+		Columns("b", "c", "d").
+		Values(1, 2, 3).
+		Values(4, 5, 6).
+		OnConflict("b").
+		Where("tab.d < EXCLUDED.d").
+		Returning("id").
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO tab (b,c,d) VALUES ($1,$2,$3),($4,$5,$6) ON CONFLICT (b) "+
+		"DO UPDATE SET c = EXCLUDED.c, d = EXCLUDED.d WHERE (tab.d < EXCLUDED.d) RETURNING id", sql)
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5, 6}, args)
+
+	var rec = struct {
+		B int `db:"b"`
+		C int `db:"c"`
+	}{1, 2}
+	sql, args, err = Upsert("tab").Columns("b", "c").Record(rec).OnConflict("b", "c").ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO tab (b,c) VALUES ($1,$2) ON CONFLICT (b, c) "+
+		"DO UPDATE SET b = EXCLUDED.b, c = EXCLUDED.c RETURNING b,c", sql)
+	assert.Equal(t, []interface{}{1, 2}, args)
+}
+
+func TestUpsertSQLOnConflictFallback(t *testing.T) {
+	sql, args, err := Upsert("tab").
+		SetDialect(preConflictDialect{postgres.New()}).
+		Columns("b", "c").
+		Values(1, 2).
+		OnConflict("b").
+		ToSQL()
+	assert.NoError(t, err)
+	expected := `
+	WITH
+		upd AS (
+			UPDATE tab
+			SET b = $1, c = $2
+			WHERE (b = $3)
+			RETURNING b,c
+		), ins AS (
+			INSERT INTO tab(b,c)
+			SELECT $1,$2
+			WHERE NOT EXISTS (SELECT 1 FROM upd)
+			RETURNING b,c
+		)
+	SELECT * FROM ins UNION ALL SELECT * FROM upd
+	`
+	assert.Equal(t, stripWS(expected), stripWS(sql))
+	assert.Equal(t, []interface{}{1, 2, 1}, args)
+
+	_, _, err = Upsert("tab").
+		SetDialect(preConflictDialect{postgres.New()}).
+		Columns("b", "c").
+		Values(1, 2).
+		Values(3, 4).
+		OnConflict("b").
+		ToSQL()
+	assert.Error(t, err)
+
+	_, _, err = Upsert("tab").Columns("b", "c").Values(1, 2).OnConflict("e").
+		SetDialect(preConflictDialect{postgres.New()}).ToSQL()
+	assert.Error(t, err)
+	_, _, err = Upsert("tab").Columns("b", "c").Values(1).OnConflict("b").ToSQL()
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package dat
+
+import (
+	"reflect"
+
+	"github.com/matcherino/dat/common"
+)
+
+// versionColumn returns the column of the field of record tagged version,
+// or "" if record is nil or not versioned.
+func versionColumn(record interface{}) string {
+	if record == nil {
+		return ""
+	}
+	sm := reflectFields(record)
+	for _, name := range sm.DeclaredNames {
+		if sm.Names[name].Version {
+			return name
+		}
+	}
+	return ""
+}
+
+// versionValue returns the value of column of record.
+func versionValue(record interface{}, column string) interface{} {
+	v := reflect.Indirect(reflect.ValueOf(record))
+	return fieldValue(v, reflectFields(record).Names[column].Index)
+}
+
+// versionCondition returns the condition matching the version read into
+// record. qualifier, if any, qualifies the column.
+func versionCondition(qualifier string, column string, record interface{}) *whereFragment {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	writeQualifiedIdentifier(buf, qualifier, column)
+	buf.WriteString(" = $1")
+	return &whereFragment{Condition: buf.String(), Values: []interface{}{versionValue(record, column)}}
+}
+
+// versionIncrement returns the expression setting column to the next
+// version.
+func versionIncrement(qualifier string, column string) *Expression {
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+	writeQualifiedIdentifier(buf, qualifier, column)
+	buf.WriteString(" + 1")
+	return &Expression{Sql: buf.String()}
+}
+
+// tableQualifier returns the alias of table, such as p of "people AS p", or
+// its name.
+func tableQualifier(table string) string {
+	refs := fromTables(table)
+	if len(refs) == 0 {
+		return table
+	}
+	if refs[0].alias != "" {
+		return unquoteIdentifier(refs[0].alias)
+	}
+	return refs[0].name
+}
+
+func writeQualifiedIdentifier(buf common.BufferWriter, qualifier string, column string) {
+	if qualifier != "" {
+		writeIdentifier(buf, qualifier)
+		buf.WriteRune('.')
+	}
+	writeIdentifier(buf, column)
+}
+
+// versionedWriter is implemented by builders which write records with a
+// field tagged version.
+type versionedWriter interface {
+	versionedRecords() int
+}
+
+// CheckStale returns ErrStaleRecord if builder updates or deletes records
+// with a field tagged version and fewer than one row per record was
+// affected, as the rows were changed or deleted since the records were
+// read. Runners call it with the rows affected by Exec or returned to
+// FillRecords.
+func CheckStale(builder Builder, rowsAffected int64) error {
+	vw, ok := builder.(versionedWriter)
+	if !ok {
+		return nil
+	}
+	if n := vw.versionedRecords(); n > 0 && rowsAffected < int64(n) {
+		return ErrStaleRecord
+	}
+	return nil
+}
+
+// appendVersion returns cols and rows with the version column of records
+// appended unless cols contains it.
+func appendVersion(cols []string, rows [][]interface{}, version string, records []interface{}) ([]string, [][]interface{}, error) {
+	if indexOfString(cols, version) >= 0 {
+		return cols, rows, nil
+	}
+	if len(rows) > len(records) {
+		return nil, nil, NewError("Values cannot be combined with versioned records")
+	}
+	cols = append(append([]string{}, cols...), version)
+	for i, record := range records {
+		rows[i] = append(rows[i], versionValue(record, version))
+	}
+	return cols, rows, nil
+}
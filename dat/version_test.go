@@ -0,0 +1,114 @@
+package dat
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type versionedRecord struct {
+	ID      int64  `db:"id,pk"`
+	Name    string `db:"name"`
+	Version int    `db:"lock_version,version"`
+}
+
+func TestVersionUpdate(t *testing.T) {
+	rec := &versionedRecord{ID: 1, Name: "a", Version: 3}
+	b := Update("t").SetWhitelist(rec, "name").Where("id = $1", 1)
+	sql, args, err := b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		UPDATE t SET name = $1, lock_version = t.lock_version + 1
+		WHERE (id = $2) AND (t.lock_version = $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", 1, 3}, args)
+	assert.Equal(t, ErrStaleRecord, CheckStale(b, 0))
+	assert.NoError(t, CheckStale(b, 1))
+
+	sql, args, err = Update("t").SetWhitelist(rec, "*").Where("id = $1", 1).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		UPDATE t SET name = $1, lock_version = t.lock_version + 1
+		WHERE (id = $2) AND (t.lock_version = $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", 1, 3}, args)
+
+	// the version columns of other tables are not ambiguous
+	sql, args, err = Update("t AS x").
+		SetWhitelist(rec, "name").
+		From("locks").
+		Where("locks.id = x.id AND locks.lock_version > $1", 0).
+		ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		UPDATE t AS x SET name = $1, lock_version = x.lock_version + 1
+		FROM locks
+		WHERE (locks.id = x.id AND locks.lock_version > $2) AND (x.lock_version = $3)`), stripWS(sql))
+	assert.Equal(t, []interface{}{"a", 0, 3}, args)
+
+	_, _, err = Update("t").SetWhitelist(rec, "name").Scope("WHERE id = $1", 1).ToSQL()
+	assert.Error(t, err)
+
+	// not versioned
+	b = Update("t").Set("name", "a")
+	assert.NoError(t, CheckStale(b, 0))
+	assert.NoError(t, CheckStale(Select("a").From("t"), 0))
+}
+
+func TestVersionUpdateReturning(t *testing.T) {
+	rec := &versionedRecord{ID: 1, Name: "a"}
+	b := Update("t").SetWhitelist(rec, "name").Where("id = $1", 1).Returning("id")
+	_, err := ReturningRecords(b)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "lock_version"}, b.returnings)
+}
+
+func TestVersionDelete(t *testing.T) {
+	rec := &versionedRecord{ID: 1, Version: 3}
+	b := DeleteFrom("t").Record(rec)
+	sql, args, err := b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`DELETE FROM t WHERE (id = $1) AND (lock_version = $2)`), stripWS(sql))
+	assert.Equal(t, []interface{}{int64(1), 3}, args)
+	assert.Equal(t, ErrStaleRecord, CheckStale(b, 0))
+
+	type unversioned struct {
+		ID int64 `db:"id,pk"`
+	}
+	b = DeleteFrom("t").Record(&unversioned{ID: 1})
+	sql, _, err = b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`DELETE FROM t WHERE (id = $1)`), stripWS(sql))
+	assert.NoError(t, CheckStale(b, 0))
+
+	_, _, err = DeleteFrom("t").Record(&struct{ ID int64 }{1}).ToSQL()
+	assert.Error(t, err)
+}
+
+func TestVersionUpsert(t *testing.T) {
+	recs := []*versionedRecord{{ID: 1, Name: "a", Version: 3}, {ID: 2, Name: "b"}}
+	b := Upsert("t").Columns("id", "name").Record(recs[0]).Record(recs[1])
+	sql, args, err := b.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		INSERT INTO t (id, name, lock_version) VALUES ($1, $2, $3), ($4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, lock_version = t.lock_version + 1
+		WHERE (t.lock_version = EXCLUDED.lock_version)
+		RETURNING id, name, lock_version`), stripWS(sql))
+	assert.Equal(t, []interface{}{int64(1), "a", 3, int64(2), "b", 0}, args)
+	assert.Equal(t, ErrStaleRecord, CheckStale(b, 1))
+	assert.NoError(t, CheckStale(b, 2))
+
+	sql, _, err = Upsert("people AS p").Columns("id", "name").Record(recs[0]).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, stripWS(`
+		INSERT INTO people AS p (id, name, lock_version) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, lock_version = p.lock_version + 1
+		WHERE (p.lock_version = EXCLUDED.lock_version)
+		RETURNING id, name, lock_version`), stripWS(sql))
+
+	_, _, err = Upsert("t").Columns("id", "name").Values(3, "c").Record(recs[0]).ToSQL()
+	assert.Error(t, err)
+
+	// the fallback cannot check versions
+	_, _, err = Upsert("t").Columns("id", "name").Record(recs[0]).Where("id = $1", 1).ToSQL()
+	assert.Error(t, err)
+}
@@ -2,7 +2,6 @@ package dat
 
 import (
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 
@@ -16,6 +15,7 @@ type whereFragment struct {
 	Condition   string
 	Values      []interface{}
 	EqualityMap map[string]interface{}
+	Cond        Condition
 }
 
 func newWhereFragment(whereSQLOrMap interface{}, args []interface{}) (*whereFragment, error) {
@@ -30,41 +30,57 @@ func newWhereFragment(whereSQLOrMap interface{}, args []interface{}) (*whereFrag
 		return &whereFragment{EqualityMap: pred}, nil
 	case Eq:
 		return &whereFragment{EqualityMap: map[string]interface{}(pred)}, nil
+	case Condition:
+		return &whereFragment{Cond: pred}, nil
 	default:
-		return nil, NewError("Invalid argument passed to Where. Pass a string or an Eq map.")
+		return nil, NewError("Invalid argument passed to Where. Pass a string, an Eq map or a Condition.")
 	}
 }
 
-var rePlaceholder = regexp.MustCompile(`\$\d+`)
-
-func remapPlaceholders(buf common.BufferWriter, statement string, start int64) int64 {
-	if !strings.Contains(statement, "$") {
+// remapPlaceholders writes statement to buf with its placeholders renumbered
+// to begin at start. ? placeholders are accepted when d uses
+// QuestionPlaceholders or NumberedPlaceholders. As in SQLite, a ? is
+// numbered one more than the highest placeholder before it. Returns the
+// highest placeholder in statement.
+func remapPlaceholders(d SQLDialect, buf common.BufferWriter, statement string, start int64) int64 {
+	t := newSQLTokenizer(d, statement)
+	if strings.IndexByte(statement, '$') < 0 && (!t.question || strings.IndexByte(statement, '?') < 0) {
 		buf.WriteString(statement)
 		return 0
 	}
 
 	highest := 0
 	pos := int(start) - 1 // 0-based
-	statement = rePlaceholder.ReplaceAllStringFunc(statement, func(s string) string {
-		i, _ := strconv.Atoi(s[1:])
+	for {
+		kind, text := t.next()
+		if text == "" {
+			break
+		}
+		if kind != tokPlaceholder {
+			buf.WriteString(text)
+			continue
+		}
+
+		var i int
+		if len(text) > 1 {
+			i, _ = strconv.Atoi(text[1:])
+		} else {
+			i = highest + 1
+		}
 		if i > highest {
 			highest = i
 		}
-
-		sum := strconv.Itoa(pos + i)
-		return "$" + sum
-	})
-
-	buf.WriteString(statement)
+		writePlaceholder(buf, pos+i)
+	}
 	return int64(highest)
 }
 
 // Invariant: for scope conditions only
-func writeScopeCondition(buf common.BufferWriter, f *whereFragment, args *[]interface{}, pos *int64) {
+func writeScopeCondition(d SQLDialect, buf common.BufferWriter, f *whereFragment, args *[]interface{}, pos *int64) {
 	buf.WriteRune(' ')
 	if len(f.Values) > 0 {
 		// map relative $1, $2 placeholders to absolute
-		replaced := remapPlaceholders(buf, f.Condition, *pos)
+		replaced := remapPlaceholders(d, buf, f.Condition, *pos)
 		*pos += replaced
 		*args = append(*args, f.Values...)
 	} else {
@@ -72,20 +88,20 @@ func writeScopeCondition(buf common.BufferWriter, f *whereFragment, args *[]inte
 	}
 }
 
-func writeAndFragmentsToSQL(buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
-	return writeFragmentsToSQL(" AND ", true, buf, fragments, args, pos)
+func writeAndFragmentsToSQL(d SQLDialect, buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
+	return writeFragmentsToSQL(d, " AND ", true, buf, fragments, args, pos)
 }
 
-func writeCommaFragmentsToSQL(buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
-	return writeFragmentsToSQL(", ", false, buf, fragments, args, pos)
+func writeCommaFragmentsToSQL(d SQLDialect, buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
+	return writeFragmentsToSQL(d, ", ", false, buf, fragments, args, pos)
 }
 
-func writeConcatFragmentsToSQL(buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
-	return writeFragmentsToSQL(" ", false, buf, fragments, args, pos)
+func writeConcatFragmentsToSQL(d SQLDialect, buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
+	return writeFragmentsToSQL(d, " ", false, buf, fragments, args, pos)
 }
 
 // Invariant: only called when len(fragments) > 0
-func writeFragmentsToSQL(delimiter string, addParens bool, buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
+func writeFragmentsToSQL(d SQLDialect, delimiter string, addParens bool, buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
 	hasConditions := false
 	for _, f := range fragments {
 		if f.Condition != "" {
@@ -99,9 +115,13 @@ func writeFragmentsToSQL(delimiter string, addParens bool, buf common.BufferWrit
 				buf.WriteRune('(')
 			}
 
-			if len(f.Values) > 0 {
+			if hasBuilderArg(f.Values) {
+				if err := writeBuilderArgs(d, buf, f.Condition, f.Values, args, pos); err != nil {
+					return err
+				}
+			} else if len(f.Values) > 0 {
 				// map relative $1, $2 placeholders to absolute
-				replaced := remapPlaceholders(buf, f.Condition, *pos)
+				replaced := remapPlaceholders(d, buf, f.Condition, *pos)
 				*pos += replaced
 				*args = append(*args, f.Values...)
 			} else {
@@ -112,6 +132,17 @@ func writeFragmentsToSQL(delimiter string, addParens bool, buf common.BufferWrit
 			}
 		} else if f.EqualityMap != nil {
 			hasConditions = writeEqualityMapToSQL(buf, f.EqualityMap, args, hasConditions, pos)
+		} else if f.Cond != nil {
+			if hasConditions {
+				buf.WriteString(delimiter)
+			} else {
+				hasConditions = true
+			}
+			buf.WriteRune('(')
+			if err := f.Cond.writeCondition(d, buf, args, pos); err != nil {
+				return err
+			}
+			buf.WriteRune(')')
 		} else {
 			return NewError("invalid equality map")
 		}
@@ -122,54 +153,55 @@ func writeFragmentsToSQL(delimiter string, addParens bool, buf common.BufferWrit
 
 func writeEqualityMapToSQL(buf common.BufferWriter, eq map[string]interface{}, args *[]interface{}, anyConditions bool, pos *int64) bool {
 	for k, v := range eq {
-		if v == nil {
-			anyConditions = writeWhereCondition(buf, k, " IS NULL", anyConditions)
+		if anyConditions {
+			buf.WriteString(" AND (")
 		} else {
-			vVal := reflect.ValueOf(v)
-
-			if vVal.Kind() == reflect.Array || vVal.Kind() == reflect.Slice {
-				vValLen := vVal.Len()
-				if vValLen == 0 {
-					if vVal.IsNil() {
-						anyConditions = writeWhereCondition(buf, k, " IS NULL", anyConditions)
-					} else {
-						if anyConditions {
-							buf.WriteString(" AND (1=0)")
-						} else {
-							buf.WriteString("(1=0)")
-						}
-					}
-				} else if vValLen == 1 {
-					anyConditions = writeWhereCondition(buf, k, equalsPlaceholderTab[*pos], anyConditions)
-					*args = append(*args, vVal.Index(0).Interface())
-					*pos++
-				} else {
-					// " IN $n"
-					anyConditions = writeWhereCondition(buf, k, inPlaceholderTab[*pos], anyConditions)
-					*args = append(*args, v)
-					*pos++
-				}
-			} else {
-				anyConditions = writeWhereCondition(buf, k, equalsPlaceholderTab[*pos], anyConditions)
-				*args = append(*args, v)
-				*pos++
-			}
+			buf.WriteRune('(')
+			anyConditions = true
 		}
+		writeEquality(buf, k, v, args, pos)
+		buf.WriteRune(')')
 	}
 
 	return anyConditions
 }
 
-func writeWhereCondition(buf common.BufferWriter, k string, pred string, anyConditions bool) bool {
-	if anyConditions {
-		buf.WriteString(" AND (")
-	} else {
-		buf.WriteRune('(')
-		anyConditions = true
+// writeEquality writes the condition for a single pair of an equality map.
+func writeEquality(buf common.BufferWriter, k string, v interface{}, args *[]interface{}, pos *int64) {
+	if v == nil {
+		writeIdentifier(buf, k)
+		buf.WriteString(" IS NULL")
+		return
 	}
-	writeIdentifier(buf, k)
-	buf.WriteString(pred)
-	buf.WriteRune(')')
 
-	return anyConditions
+	vVal := reflect.ValueOf(v)
+	if vVal.Kind() == reflect.Array || vVal.Kind() == reflect.Slice {
+		vValLen := vVal.Len()
+		if vValLen == 0 {
+			if vVal.Kind() == reflect.Slice && vVal.IsNil() {
+				writeIdentifier(buf, k)
+				buf.WriteString(" IS NULL")
+			} else {
+				buf.WriteString("1=0")
+			}
+			return
+		} else if vValLen == 1 {
+			writeIdentifier(buf, k)
+			buf.WriteString(equalsPlaceholderTab[*pos])
+			*args = append(*args, vVal.Index(0).Interface())
+			*pos++
+			return
+		}
+		// " IN $n"
+		writeIdentifier(buf, k)
+		buf.WriteString(inPlaceholderTab[*pos])
+		*args = append(*args, v)
+		*pos++
+		return
+	}
+
+	writeIdentifier(buf, k)
+	buf.WriteString(equalsPlaceholderTab[*pos])
+	*args = append(*args, v)
+	*pos++
 }
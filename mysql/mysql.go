@@ -0,0 +1,185 @@
+package mysql
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/matcherino/dat/common"
+	"github.com/matcherino/dat/dat"
+)
+
+// MySQL is the MySQL dialect.
+type MySQL struct {
+	// NoBackslashEscapes must be set if the server's sql_mode includes
+	// NO_BACKSLASH_ESCAPES. Quotes are then escaped by doubling only.
+	NoBackslashEscapes bool
+	// Location is the time zone times are converted to before formatting.
+	// MySQL DATETIME values do not carry a time zone.
+	Location *time.Location
+}
+
+// New returns a new MySQL dialect.
+func New() *MySQL {
+	return &MySQL{Location: time.UTC}
+}
+
+// Name returns the dialect name.
+func (md *MySQL) Name() string {
+	return "mysql"
+}
+
+// Supports returns whether MySQL supports feature.
+//
+// MySQL has no RETURNING, ON CONFLICT, data-modifying or MATERIALIZED CTEs,
+// DISTINCT ON, row_to_json, ILIKE, jsonb, NULLS FIRST, UPDATE FROM or
+// MERGE.
+// Running queries are not cancelled on timeout.
+func (md *MySQL) Supports(feature dat.Feature) bool {
+	return feature == dat.FeatureDefaultValues
+}
+
+// PlaceholderStyle returns dat.QuestionPlaceholders.
+func (md *MySQL) PlaceholderStyle() dat.PlaceholderStyle {
+	return dat.QuestionPlaceholders
+}
+
+// BackslashEscapes returns whether string literals may contain backslash
+// escapes, which is the case unless NoBackslashEscapes is set.
+func (md *MySQL) BackslashEscapes() bool {
+	return !md.NoBackslashEscapes
+}
+
+// WriteStringLiteral writes an escaped string.
+func (md *MySQL) WriteStringLiteral(buf common.BufferWriter, val string) {
+	buf.WriteRune('\'')
+	if md.NoBackslashEscapes {
+		buf.WriteString(strings.Replace(val, "'", "''", -1))
+		buf.WriteRune('\'')
+		return
+	}
+
+	for _, char := range val {
+		switch char {
+		case 0:
+			buf.WriteString(`\0`)
+		case '\'':
+			buf.WriteString(`\'`)
+		case '"':
+			buf.WriteString(`\"`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case 26:
+			buf.WriteString(`\Z`)
+		case '\\':
+			buf.WriteString(`\\`)
+		default:
+			buf.WriteRune(char)
+		}
+	}
+	buf.WriteRune('\'')
+}
+
+// WriteIdentifier writes an escaped identifier using backticks.
+func (md *MySQL) WriteIdentifier(buf common.BufferWriter, ident string) {
+	if ident == "" || ident == "*" {
+		buf.WriteString(ident)
+		return
+	}
+
+	buf.WriteRune('`')
+	for _, char := range ident {
+		switch char {
+		case '.':
+			buf.WriteString("`.`")
+		case '`':
+			buf.WriteString("``")
+		default:
+			buf.WriteRune(char)
+		}
+	}
+	buf.WriteRune('`')
+}
+
+// WriteBoolLiteral writes TRUE or FALSE.
+func (md *MySQL) WriteBoolLiteral(buf common.BufferWriter, val bool) {
+	if val {
+		buf.WriteString("TRUE")
+	} else {
+		buf.WriteString("FALSE")
+	}
+}
+
+// WriteFormattedTime formats t as a DATETIME literal in md.Location.
+func (md *MySQL) WriteFormattedTime(buf common.BufferWriter, t time.Time) {
+	if md.Location != nil {
+		t = t.In(md.Location)
+	}
+	buf.WriteRune('\'')
+	buf.WriteString(t.Format("2006-01-02 15:04:05.999999"))
+	buf.WriteRune('\'')
+}
+
+// SQLType is called by WriteReflectedType before any other conversion is
+// considered. This allows for types which implement Scan/Value to also
+// provide their own MySQL type name.
+type SQLType interface {
+	MySQLType() string
+}
+
+// WriteReflectedType writes a MySQL type name based on a primitive type
+// given by t. MySQL has no array types, the element type is written for
+// slices.
+func (md *MySQL) WriteReflectedType(buf common.BufferWriter, t interface{}) {
+	var typ reflect.Type
+	switch r := t.(type) {
+	case reflect.Value:
+		typ = r.Type()
+	case reflect.Type:
+		typ = r
+	default:
+		typ = reflect.TypeOf(t)
+	}
+	if call, ok := reflect.New(typ).Elem().Interface().(SQLType); ok {
+		buf.WriteString(call.MySQLType())
+		return
+	}
+	if typ.ConvertibleTo(reflect.TypeOf(time.Time{})) {
+		buf.WriteString("DATETIME(6)")
+		return
+	}
+	switch typ.Kind() {
+	case reflect.Interface, reflect.Array, reflect.Slice, reflect.Ptr:
+		md.WriteReflectedType(buf, typ.Elem())
+	case reflect.Bool:
+		buf.WriteString("BOOLEAN")
+	case reflect.Float32:
+		buf.WriteString("FLOAT")
+	case reflect.Float64:
+		buf.WriteString("DOUBLE")
+	case reflect.Int8:
+		buf.WriteString("TINYINT")
+	case reflect.Int16:
+		buf.WriteString("SMALLINT")
+	case reflect.Int32:
+		buf.WriteString("INT")
+	case reflect.Int64, reflect.Int:
+		buf.WriteString("BIGINT")
+	case reflect.String:
+		buf.WriteString("TEXT")
+	case reflect.Uint8:
+		buf.WriteString("TINYINT UNSIGNED")
+	case reflect.Uint16:
+		buf.WriteString("SMALLINT UNSIGNED")
+	case reflect.Uint32:
+		buf.WriteString("INT UNSIGNED")
+	case reflect.Uint, reflect.Uint64:
+		buf.WriteString("BIGINT UNSIGNED")
+	}
+}
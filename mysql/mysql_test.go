@@ -0,0 +1,51 @@
+package mysql
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestWriteStringLiteral(t *testing.T) {
+	var buf bytes.Buffer
+	md := New()
+	md.WriteStringLiteral(&buf, "it's a \\ \"test\"\n\x00")
+	assert.Equal(t, `'it\'s a \\ \"test\"\n\0'`, buf.String())
+
+	buf.Reset()
+	md.NoBackslashEscapes = true
+	md.WriteStringLiteral(&buf, `it's a \`)
+	assert.Equal(t, `'it''s a \'`, buf.String())
+}
+
+func TestWriteIdentifier(t *testing.T) {
+	var buf bytes.Buffer
+	md := New()
+	md.WriteIdentifier(&buf, "people.na`me")
+	assert.Equal(t, "`people`.`na``me`", buf.String())
+
+	buf.Reset()
+	md.WriteIdentifier(&buf, "*")
+	assert.Equal(t, "*", buf.String())
+}
+
+func TestWriteFormattedTime(t *testing.T) {
+	var buf bytes.Buffer
+	loc := time.FixedZone("X", -5*3600)
+	New().WriteFormattedTime(&buf, time.Date(2016, 1, 2, 3, 4, 5, 600000000, loc))
+	assert.Equal(t, `'2016-01-02 08:04:05.6'`, buf.String())
+}
+
+func TestWriteReflectedType(t *testing.T) {
+	var buf bytes.Buffer
+	md := New()
+	md.WriteReflectedType(&buf, reflect.TypeOf([]int64{}))
+	assert.Equal(t, "BIGINT", buf.String())
+
+	buf.Reset()
+	md.WriteReflectedType(&buf, time.Now())
+	assert.Equal(t, "DATETIME(6)", buf.String())
+}
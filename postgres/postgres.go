@@ -2,8 +2,11 @@ package postgres
 
 import (
 	"bytes"
+	"encoding/hex"
 	"math/rand"
+	"net"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -214,3 +217,118 @@ func (pd *Postgres) WriteReflectedType(buf common.BufferWriter, t interface{}) {
 		buf.WriteString("smallint")
 	}
 }
+
+// WriteBytesLiteral writes b as a bytea hex literal.
+func (pd *Postgres) WriteBytesLiteral(buf common.BufferWriter, b []byte) {
+	buf.WriteString(`'\x`)
+	buf.WriteString(hex.EncodeToString(b))
+	buf.WriteString(`'::bytea`)
+}
+
+// WriteUUIDLiteral writes u as a uuid literal.
+func (pd *Postgres) WriteUUIDLiteral(buf common.BufferWriter, u [16]byte) {
+	s := hex.EncodeToString(u[:])
+	buf.WriteRune('\'')
+	buf.WriteString(s[0:8])
+	buf.WriteRune('-')
+	buf.WriteString(s[8:12])
+	buf.WriteRune('-')
+	buf.WriteString(s[12:16])
+	buf.WriteRune('-')
+	buf.WriteString(s[16:20])
+	buf.WriteRune('-')
+	buf.WriteString(s[20:])
+	buf.WriteString(`'::uuid`)
+}
+
+// WriteInetLiteral writes ip as an inet literal.
+func (pd *Postgres) WriteInetLiteral(buf common.BufferWriter, ip net.IP) {
+	buf.WriteRune('\'')
+	buf.WriteString(ip.String())
+	buf.WriteString(`'::inet`)
+}
+
+// WriteCIDRLiteral writes n as a cidr literal.
+func (pd *Postgres) WriteCIDRLiteral(buf common.BufferWriter, n *net.IPNet) {
+	buf.WriteRune('\'')
+	buf.WriteString(n.String())
+	buf.WriteString(`'::cidr`)
+}
+
+// WriteIntervalLiteral writes d as an interval literal in seconds. Postgres
+// rounds intervals to microseconds.
+func (pd *Postgres) WriteIntervalLiteral(buf common.BufferWriter, d time.Duration) {
+	buf.WriteRune('\'')
+	if d < 0 {
+		buf.WriteRune('-')
+		d = -d
+	}
+	buf.WriteString(strconv.FormatInt(int64(d/time.Second), 10))
+	if ns := int64(d % time.Second); ns > 0 {
+		// pad to 9 digits by formatting 1e9 + ns without the leading 1
+		frac := strconv.FormatInt(1e9+ns, 10)[1:]
+		buf.WriteRune('.')
+		buf.WriteString(strings.TrimRight(frac, "0"))
+	}
+	buf.WriteString(` seconds'::interval`)
+}
+
+// WriteHstoreLiteral writes m as an hstore literal with its keys sorted.
+func (pd *Postgres) WriteHstoreLiteral(buf common.BufferWriter, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var hstore bytes.Buffer
+	for i, k := range keys {
+		if i > 0 {
+			hstore.WriteString(", ")
+		}
+		writeHstoreString(&hstore, k)
+		hstore.WriteString("=>")
+		writeHstoreString(&hstore, m[k])
+	}
+	pd.WriteStringLiteral(buf, hstore.String())
+	buf.WriteString("::hstore")
+}
+
+// writeHstoreString writes a double quoted hstore key or value.
+func writeHstoreString(buf *bytes.Buffer, s string) {
+	buf.WriteRune('"')
+	for _, char := range s {
+		if char == '"' || char == '\\' {
+			buf.WriteRune('\\')
+		}
+		buf.WriteRune(char)
+	}
+	buf.WriteRune('"')
+}
+
+// WriteDecimalLiteral writes value, which must be a decimal number, as a
+// numeric literal.
+func (pd *Postgres) WriteDecimalLiteral(buf common.BufferWriter, value string) {
+	buf.WriteString(value)
+	buf.WriteString("::numeric")
+}
+
+// WriteArrayLiteral writes an ARRAY constructor of n elements written by
+// writeElem. An empty array is written as '{}'.
+func (pd *Postgres) WriteArrayLiteral(buf common.BufferWriter, n int, writeElem func(i int) error) error {
+	if n == 0 {
+		buf.WriteString("'{}'")
+		return nil
+	}
+	buf.WriteString("ARRAY[")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		if err := writeElem(i); err != nil {
+			return err
+		}
+	}
+	buf.WriteRune(']')
+	return nil
+}
@@ -25,6 +25,14 @@ type FieldInfo struct {
 	Embedded bool
 	Children []*FieldInfo
 	Parent   *FieldInfo
+
+	// tag options, as in `db:"id,pk"`
+	PrimaryKey bool // pk
+	ReadOnly   bool // readonly, never written
+	AutoCreate bool // autocreate, set to the current time when inserted
+	AutoUpdate bool // autoupdate, set to the current time when written
+	OmitEmpty  bool // omitempty, zero values are not inserted
+	Version    bool // version, incremented by each update
 }
 
 // A StructMap is an index of field metadata for a struct.
@@ -312,6 +320,13 @@ func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc func(string)
 				}
 			}
 
+			_, fi.PrimaryKey = fi.Options["pk"]
+			_, fi.ReadOnly = fi.Options["readonly"]
+			_, fi.AutoCreate = fi.Options["autocreate"]
+			_, fi.AutoUpdate = fi.Options["autoupdate"]
+			_, fi.OmitEmpty = fi.Options["omitempty"]
+			_, fi.Version = fi.Options["version"]
+
 			if tagMapFunc != nil {
 				tag = tagMapFunc(tag)
 			}
@@ -0,0 +1,147 @@
+package sqlite
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/matcherino/dat/common"
+	"github.com/matcherino/dat/dat"
+)
+
+// SQLite is the SQLite 3.35+ dialect.
+type SQLite struct{}
+
+// New returns a new SQLite dialect.
+func New() *SQLite {
+	return &SQLite{}
+}
+
+// Name returns the dialect name.
+func (sd *SQLite) Name() string {
+	return "sqlite"
+}
+
+// Supports returns whether SQLite supports feature.
+//
+// SQLite supports RETURNING, ON CONFLICT and MATERIALIZED CTEs since 3.35.
+// It has no data-modifying CTEs, DISTINCT ON, array types, ILIKE, jsonb,
+// column aliases for VALUES, DEFAULT in VALUES or MERGE and running queries
+// are not cancelled on timeout.
+func (sd *SQLite) Supports(feature dat.Feature) bool {
+	switch feature {
+	case dat.FeatureReturning, dat.FeatureOnConflict, dat.FeatureJSONDocuments,
+		dat.FeatureMaterializedCTE, dat.FeatureNullsOrdering:
+		return true
+	}
+	return false
+}
+
+// PlaceholderStyle returns dat.NumberedPlaceholders.
+func (sd *SQLite) PlaceholderStyle() dat.PlaceholderStyle {
+	return dat.NumberedPlaceholders
+}
+
+// JSONStyle returns dat.SQLiteJSON.
+func (sd *SQLite) JSONStyle() dat.JSONStyle {
+	return dat.SQLiteJSON
+}
+
+// WriteStringLiteral writes an escaped string. SQLite has no escape
+// sequences, quotes are doubled.
+func (sd *SQLite) WriteStringLiteral(buf common.BufferWriter, val string) {
+	buf.WriteRune('\'')
+	if strings.Contains(val, "'") {
+		buf.WriteString(strings.Replace(val, "'", "''", -1))
+	} else {
+		buf.WriteString(val)
+	}
+	buf.WriteRune('\'')
+}
+
+// WriteIdentifier writes an escaped identifier.
+func (sd *SQLite) WriteIdentifier(buf common.BufferWriter, ident string) {
+	if ident == "" || ident == "*" {
+		buf.WriteString(ident)
+		return
+	}
+
+	buf.WriteRune('"')
+	for _, char := range ident {
+		switch char {
+		case '.':
+			buf.WriteString(`"."`)
+		case '"':
+			buf.WriteString(`""`)
+		default:
+			buf.WriteRune(char)
+		}
+	}
+	buf.WriteRune('"')
+}
+
+// WriteBoolLiteral writes TRUE or FALSE.
+func (sd *SQLite) WriteBoolLiteral(buf common.BufferWriter, val bool) {
+	if val {
+		buf.WriteString("TRUE")
+	} else {
+		buf.WriteString("FALSE")
+	}
+}
+
+// timeFormat is the format go-sqlite3 writes time.Time arguments in.
+const timeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// WriteFormattedTime formats t as text go-sqlite3 reads back as time.Time.
+func (sd *SQLite) WriteFormattedTime(buf common.BufferWriter, t time.Time) {
+	buf.WriteRune('\'')
+	buf.WriteString(t.Format(timeFormat))
+	buf.WriteRune('\'')
+}
+
+// SQLType is called by WriteReflectedType before any other conversion is
+// considered. This allows for types which implement Scan/Value to also
+// provide their own SQLite type name.
+type SQLType interface {
+	SQLiteType() string
+}
+
+// WriteReflectedType writes a SQLite type name based on a primitive type
+// given by t. SQLite has no array types, the element type is written for
+// slices.
+func (sd *SQLite) WriteReflectedType(buf common.BufferWriter, t interface{}) {
+	var typ reflect.Type
+	switch r := t.(type) {
+	case reflect.Value:
+		typ = r.Type()
+	case reflect.Type:
+		typ = r
+	default:
+		typ = reflect.TypeOf(t)
+	}
+	if call, ok := reflect.New(typ).Elem().Interface().(SQLType); ok {
+		buf.WriteString(call.SQLiteType())
+		return
+	}
+	if typ.ConvertibleTo(reflect.TypeOf(time.Time{})) {
+		buf.WriteString("TIMESTAMP")
+		return
+	}
+	switch typ.Kind() {
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			buf.WriteString("BLOB")
+			return
+		}
+		sd.WriteReflectedType(buf, typ.Elem())
+	case reflect.Interface, reflect.Array, reflect.Ptr:
+		sd.WriteReflectedType(buf, typ.Elem())
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteString("INTEGER")
+	case reflect.Float32, reflect.Float64:
+		buf.WriteString("REAL")
+	case reflect.String:
+		buf.WriteString("TEXT")
+	}
+}
@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matcherino/dat/dat"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestWriteStringLiteral(t *testing.T) {
+	var buf bytes.Buffer
+	New().WriteStringLiteral(&buf, `it's a \`)
+	assert.Equal(t, `'it''s a \'`, buf.String())
+}
+
+func TestWriteIdentifier(t *testing.T) {
+	var buf bytes.Buffer
+	New().WriteIdentifier(&buf, `people.na"me`)
+	assert.Equal(t, `"people"."na""me"`, buf.String())
+}
+
+func TestWriteFormattedTime(t *testing.T) {
+	var buf bytes.Buffer
+	loc := time.FixedZone("X", -5*3600)
+	New().WriteFormattedTime(&buf, time.Date(2016, 1, 2, 3, 4, 5, 600000000, loc))
+	assert.Equal(t, `'2016-01-02 03:04:05.6-05:00'`, buf.String())
+}
+
+func TestWriteReflectedType(t *testing.T) {
+	var buf bytes.Buffer
+	sd := New()
+	sd.WriteReflectedType(&buf, reflect.TypeOf([]int64{}))
+	assert.Equal(t, "INTEGER", buf.String())
+
+	buf.Reset()
+	sd.WriteReflectedType(&buf, []byte("x"))
+	assert.Equal(t, "BLOB", buf.String())
+}
+
+func TestDefaultValues(t *testing.T) {
+	type person struct {
+		Name string  `db:"name"`
+		Nick *string `db:"nick,omitempty"`
+	}
+	nick := "b"
+	_, _, err := dat.InsertInto("people").
+		Columns("name", "nick").
+		Record(&person{Name: "a"}).
+		Record(&person{Name: "b", Nick: &nick}).
+		SetDialect(New()).
+		ToSQL()
+	assert.Error(t, err)
+
+	_, _, err = dat.InsertInto("people").
+		Columns("name", "nick").
+		Record(&person{Name: "a", Nick: &nick}).
+		SetDialect(New()).
+		ToSQL()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,202 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/matcherino/dat/dat"
+	"github.com/matcherino/dat/postgres"
+)
+
+// AuditTable is the table of the audit log written by the triggers of
+// tables passed to MustAudit.
+const AuditTable = "dat__audit"
+
+// auditFunctionVersion is the version of the dat__audit trigger function.
+// Change it whenever auditFunction changes.
+const auditFunctionVersion = "2"
+
+const createAuditTable = `
+CREATE TABLE IF NOT EXISTS dat__audit (
+	id bigserial primary key,
+	table_name text NOT NULL,
+	action text NOT NULL,
+	row_key jsonb NOT NULL,
+	before jsonb,
+	after jsonb,
+	changes jsonb NOT NULL,
+	actor text,
+	meta jsonb,
+	created_at timestamptz NOT NULL default now()
+);
+CREATE INDEX IF NOT EXISTS dat__audit_row_idx ON dat__audit (table_name, row_key);
+`
+
+// auditFunction is the trigger function which writes the before and after
+// images of a row to dat__audit. The trigger arguments are the key columns
+// of the row. changes are the columns whose values changed, or the columns
+// of an inserted or deleted row. Updates which change nothing are not
+// logged. Tables are logged by their schema qualified names.
+const auditFunction = `
+CREATE OR REPLACE FUNCTION dat__audit() RETURNS trigger AS $$
+DECLARE
+	old_row jsonb;
+	new_row jsonb;
+	key_row jsonb;
+	row_key jsonb := '{}';
+	changes jsonb;
+	col text;
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		old_row := to_jsonb(OLD);
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		new_row := to_jsonb(NEW);
+	END IF;
+
+	key_row := COALESCE(new_row, old_row);
+	FOREACH col IN ARRAY TG_ARGV LOOP
+		row_key := row_key || jsonb_build_object(col, key_row -> col);
+	END LOOP;
+
+	IF TG_OP = 'UPDATE' THEN
+		SELECT COALESCE(jsonb_object_agg(n.key, n.value), '{}') INTO changes
+		FROM jsonb_each(new_row) n
+		WHERE old_row -> n.key IS DISTINCT FROM n.value;
+		IF changes = '{}' THEN
+			RETURN NULL;
+		END IF;
+	ELSE
+		changes := key_row;
+	END IF;
+
+	INSERT INTO dat__audit (table_name, action, row_key, before, after, changes, actor, meta)
+	VALUES (
+		TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME, TG_OP, row_key, old_row, new_row, changes,
+		NULLIF(current_setting('dat.audit_actor', true), ''),
+		NULLIF(current_setting('dat.audit_meta', true), '')::jsonb
+	);
+	RETURN NULL;
+END $$ LANGUAGE plpgsql;
+`
+
+// AuditEntry is a change of a row logged to AuditTable.
+type AuditEntry struct {
+	ID int64 `db:"id" json:"id"`
+	// Table is the schema qualified name of the table, such as public.posts.
+	Table string `db:"table_name" json:"table"`
+	// Action is INSERT, UPDATE or DELETE.
+	Action string   `db:"action" json:"action"`
+	RowKey dat.JSON `db:"row_key" json:"rowKey"`
+	// Before is the row before an UPDATE or DELETE, otherwise null.
+	Before dat.JSON `db:"before" json:"before"`
+	// After is the row after an INSERT or UPDATE, otherwise null.
+	After dat.JSON `db:"after" json:"after"`
+	// Changes are the changed columns of an UPDATE and their new values, or
+	// the row inserted or deleted.
+	Changes   dat.JSON       `db:"changes" json:"changes"`
+	Actor     dat.NullString `db:"actor" json:"actor"`
+	Meta      dat.JSON       `db:"meta" json:"meta"`
+	CreatedAt time.Time      `db:"created_at" json:"createdAt"`
+}
+
+// MustAudit logs the inserts, updates and deletes of the rows of table to
+// AuditTable with triggers, including those of Insert, Upsert, Update and
+// DeleteFrom builders. keyColumns identify the rows of table, "id" if none.
+// Set the actor and metadata of changes with Tx.SetAuditActor. Requires
+// Postgres 9.6 or later.
+//
+//	DB.MustAudit("posts")
+func (db *DB) MustAudit(table string, keyColumns ...string) {
+	if len(keyColumns) == 0 {
+		keyColumns = []string{"id"}
+	}
+
+	db.MustCreateMetaTable()
+	_, err := db.ExecMulti(dat.Expr(createAuditTable))
+	if err != nil {
+		logger.Fatal("Could not create audit table", "err", err)
+	}
+	db.MustRegisterFunction("dat__audit", auditFunctionVersion, auditFunction)
+
+	_, err = db.ExecMulti(
+		dat.Expr("DROP TRIGGER IF EXISTS dat__audit ON "+table),
+		dat.Expr(auditTriggerSQL(table, keyColumns)),
+	)
+	if err != nil {
+		logger.Fatal("Could not create audit trigger", "err", err, "table", table)
+	}
+}
+
+// auditTriggerSQL returns the statement creating the audit trigger of table.
+func auditTriggerSQL(table string, keyColumns []string) string {
+	pd := postgres.New()
+	var buf bytes.Buffer
+	buf.WriteString("CREATE TRIGGER dat__audit AFTER INSERT OR UPDATE OR DELETE ON ")
+	buf.WriteString(table)
+	buf.WriteString(" FOR EACH ROW EXECUTE PROCEDURE dat__audit(")
+	for i, column := range keyColumns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		pd.WriteStringLiteral(&buf, column)
+	}
+	buf.WriteRune(')')
+	return buf.String()
+}
+
+// SetAuditActor sets the actor, such as a user ID, and metadata, such as a
+// request ID, of the changes of the transaction logged to AuditTable.
+func (tx *Tx) SetAuditActor(actor string, meta dat.M) error {
+	var metaJSON string
+	if meta != nil {
+		b, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		metaJSON = string(b)
+	}
+	_, err := tx.
+		SQL(`SELECT set_config('dat.audit_actor', $1, true), set_config('dat.audit_meta', $2, true)`, actor, metaJSON).
+		Exec()
+	return err
+}
+
+// auditTableName is the schema qualified name of the table named $1, as
+// resolved by the search path, or $1 if there is no such table.
+const auditTableName = `COALESCE((
+	SELECT n.nspname || '.' || c.relname
+	FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.oid = to_regclass($1)
+), $1)`
+
+// AuditHistory returns the changes of the row of table identified by key,
+// its key columns and values, oldest first. table is qualified by its schema
+// or found on the search path.
+//
+//	entries, err := DB.AuditHistory("posts", dat.M{"id": 1})
+func (q *Queryable) AuditHistory(table string, key dat.M) ([]*AuditEntry, error) {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*AuditEntry
+	err = q.
+		Select(
+			"id", "table_name", "action", "row_key",
+			"COALESCE(before, 'null') AS before",
+			"COALESCE(after, 'null') AS after",
+			"changes", "actor",
+			"COALESCE(meta, 'null') AS meta",
+			"created_at",
+		).
+		From(AuditTable).
+		Where("table_name = "+auditTableName+" AND row_key = $2::jsonb", table, string(b)).
+		OrderBy("id").
+		QueryStructs(&entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/matcherino/dat/dat"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestAuditHistory(t *testing.T) {
+	installFixtures()
+	testDB.MustAudit("people")
+	defer testDB.SQL("DROP TRIGGER IF EXISTS dat__audit ON people").Exec()
+
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+	assert.NoError(t, tx.SetAuditActor("admin", dat.M{"request": "r1"}))
+
+	var id int64
+	err = tx.InsertInto("people").Columns("name").Values("Luigi").Returning("id").QueryScalar(&id)
+	assert.NoError(t, err)
+	_, err = tx.Update("people").Set("name", "Super Luigi").Where("id = $1", id).Exec()
+	assert.NoError(t, err)
+	// updates which change nothing are not logged
+	_, err = tx.Update("people").Set("name", "Super Luigi").Where("id = $1", id).Exec()
+	assert.NoError(t, err)
+	_, err = tx.DeleteFrom("people").Where("id = $1", id).Exec()
+	assert.NoError(t, err)
+
+	entries, err := tx.AuditHistory("people", dat.M{"id": id})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "public.people", entries[0].Table)
+	assert.Equal(t, "INSERT", entries[0].Action)
+	assert.Equal(t, "UPDATE", entries[1].Action)
+	assert.Equal(t, "DELETE", entries[2].Action)
+	assert.Equal(t, "admin", entries[1].Actor.String)
+
+	var changes map[string]interface{}
+	assert.NoError(t, entries[1].Changes.Unmarshal(&changes))
+	assert.Equal(t, map[string]interface{}{"name": "Super Luigi"}, changes)
+
+	var before, after map[string]interface{}
+	assert.NoError(t, entries[1].Before.Unmarshal(&before))
+	assert.NoError(t, entries[1].After.Unmarshal(&after))
+	assert.Equal(t, "Luigi", before["name"])
+	assert.Equal(t, "Super Luigi", after["name"])
+
+	var meta map[string]interface{}
+	assert.NoError(t, entries[0].Meta.Unmarshal(&meta))
+	assert.Equal(t, "r1", meta["request"])
+}
+
+func TestAuditHistorySchema(t *testing.T) {
+	installFixtures()
+	_, err := testDB.ExecMulti(
+		dat.Expr("CREATE SCHEMA IF NOT EXISTS dat_audit"),
+		dat.Expr("DROP TABLE IF EXISTS dat_audit.people"),
+		dat.Expr("CREATE TABLE dat_audit.people (id serial PRIMARY KEY, name text)"),
+	)
+	assert.NoError(t, err)
+	defer testDB.SQL("DROP SCHEMA dat_audit CASCADE").Exec()
+	testDB.MustAudit("dat_audit.people")
+	testDB.MustAudit("people")
+	defer testDB.SQL("DROP TRIGGER IF EXISTS dat__audit ON people").Exec()
+
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	_, err = tx.InsertInto("dat_audit.people").Columns("id", "name").Values(1000, "Luigi").Exec()
+	assert.NoError(t, err)
+	_, err = tx.InsertInto("people").Columns("id", "name").Values(1000, "Wario").Exec()
+	assert.NoError(t, err)
+
+	entries, err := tx.AuditHistory("dat_audit.people", dat.M{"id": 1000})
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(entries)) {
+		assert.Equal(t, "dat_audit.people", entries[0].Table)
+	}
+
+	// tables on the search path
+	entries, err = tx.AuditHistory("people", dat.M{"id": 1000})
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(entries)) {
+		assert.Equal(t, "public.people", entries[0].Table)
+	}
+}
+
+func TestAuditTriggerSQL(t *testing.T) {
+	assert.Equal(t,
+		"CREATE TRIGGER dat__audit AFTER INSERT OR UPDATE OR DELETE ON people FOR EACH ROW EXECUTE PROCEDURE dat__audit('id', 'o''k')",
+		auditTriggerSQL("people", []string{"id", "o'k"}))
+}
@@ -274,3 +274,20 @@ func TestCacheExecInvalidate(t *testing.T) {
 	assert.Equal(t, "", s)
 	assert.NoError(t, err)
 }
+
+func TestCacheQueryPage(t *testing.T) {
+	Cache.FlushDB()
+	for i := 0; i < 2; i++ {
+		var people []Person
+		page, err := testDB.
+			Select("id", "name").
+			From("people").
+			OrderBy("id").
+			Cache("querypage.1", 1*time.Second, false).
+			QueryPage(&people, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(people))
+		assert.Equal(t, int64(6), page.Total)
+		assert.True(t, page.HasNext)
+	}
+}
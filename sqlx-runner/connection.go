@@ -6,6 +6,7 @@ import "github.com/matcherino/dat/dat"
 type Connection interface {
 	Begin() (*Tx, error)
 	Call(sproc string, args ...interface{}) *dat.CallBuilder
+	CopyFrom(table string, columns []string, rows [][]interface{}) (int64, error)
 	DeleteFrom(table string) *dat.DeleteBuilder
 	Exec(cmd string, args ...interface{}) (*dat.Result, error)
 	ExecBuilder(b dat.Builder) error
@@ -13,6 +14,7 @@ type Connection interface {
 	InsertInto(table string) *dat.InsertBuilder
 	Insect(table string) *dat.InsectBuilder
 	JSQL(sql string, args ...interface{}) *dat.JSQLBuilder
+	Merge(table string) *dat.MergeBuilder
 	Select(columns ...string) *dat.SelectBuilder
 	SelectDoc(columns ...string) *dat.SelectDocBuilder
 	SQL(sql string, args ...interface{}) *dat.RawBuilder
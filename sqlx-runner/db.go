@@ -3,9 +3,14 @@ package runner
 import (
 	"database/sql"
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/matcherino/dat/dat"
+	"github.com/matcherino/dat/mysql"
+	"github.com/matcherino/dat/postgres"
+	"github.com/matcherino/dat/sqlite"
 )
 
 // DB represents an abstract database connection pool.
@@ -53,20 +58,39 @@ func pgSetVersion(db *DB) {
 	}
 }
 
-// NewDB instantiates a Connection for a given database/sql connection
+// postgresVersion is the Postgres dialect of a server, which lacks the
+// features of later versions.
+type postgresVersion struct {
+	*postgres.Postgres
+	// version is the server_version_num
+	version int64
+}
+
+// Name returns the dialect name and server version.
+func (pv *postgresVersion) Name() string {
+	return "postgres " + strconv.FormatInt(pv.version, 10)
+}
+
+// Supports returns whether the server supports feature.
+func (pv *postgresVersion) Supports(feature dat.Feature) bool {
+	switch feature {
+	case dat.FeatureOnConflict:
+		return pv.version >= 90500
+	case dat.FeatureJSONContains:
+		return pv.version >= 90400
+	case dat.FeatureMaterializedCTE:
+		return pv.version >= 120000
+	case dat.FeatureMerge:
+		return pv.version >= 150000
+	}
+	return true
+}
+
+// NewDB instantiates a Connection for a given database/sql connection. It
+// panics if driverName is not a supported driver.
 func NewDB(db *sql.DB, driverName string) *DB {
 	database := sqlx.NewDb(db, driverName)
-	conn := &DB{DB: database, Queryable: &Queryable{database}}
-	if driverName == "postgres" {
-		pgMustNotAllowEscapeSequence(conn)
-		pgSetVersion(conn)
-		if dat.Strict {
-			conn.SQL("SET client_min_messages to 'DEBUG';")
-		}
-	} else {
-		panic("Unsupported driver: " + driverName)
-	}
-	return conn
+	return newDB(database, driverName)
 }
 
 // NewDBFromString instantiates a Connection from a given driver
@@ -84,13 +108,95 @@ func NewDBFromString(driver string, connectionString string) *DB {
 }
 
 // NewDBFromSqlx creates a new Connection object from existing Sqlx.DB.
+// Drivers other than the supported ones, such as wrapped or instrumented
+// Postgres drivers, are assumed to be Postgres.
 func NewDBFromSqlx(dbx *sqlx.DB) *DB {
-	conn := &DB{DB: dbx, Queryable: &Queryable{dbx}}
-	pgMustNotAllowEscapeSequence(conn)
-	pgSetVersion(conn)
+	driverName := dbx.DriverName()
+	switch driverName {
+	case "postgres", pgxDriverName, "mysql", "sqlite3", "sqlite":
+	default:
+		logger.Warn("Unknown driver, using the Postgres dialect", "driver", driverName)
+		driverName = "postgres"
+	}
+	return newDB(dbx, driverName)
+}
+
+// newDB sets the dialect of builders created by the connection for
+// driverName and checks the database settings interpolation depends on.
+func newDB(dbx *sqlx.DB, driverName string) *DB {
+	conn := &DB{DB: dbx, Queryable: &Queryable{runner: dbx}}
+	switch driverName {
+	case "postgres", pgxDriverName:
+		conn.dialect = postgres.New()
+		pgMustNotAllowEscapeSequence(conn)
+		pgSetVersion(conn)
+		conn.dialect = &postgresVersion{Postgres: postgres.New(), version: conn.Version}
+		if dat.Strict {
+			conn.SQL("SET client_min_messages to 'DEBUG';")
+		}
+	case "mysql":
+		dialect := mysql.New()
+		conn.dialect = dialect
+		dialect.NoBackslashEscapes = mysqlNoBackslashEscapes(conn)
+	case "sqlite3", "sqlite":
+		conn.dialect = sqlite.New()
+	default:
+		panic("Unsupported driver: " + driverName)
+	}
 	return conn
 }
 
+// mysqlNoBackslashEscapes returns whether the server's sql_mode treats
+// backslashes literally, which changes how string literals are escaped
+// when dat.EnableInterpolation == true.
+func mysqlNoBackslashEscapes(conn *DB) bool {
+	if !dat.EnableInterpolation {
+		return false
+	}
+
+	var sqlMode string
+	err := conn.
+		SQL("SELECT @@SESSION.sql_mode").
+		QueryScalar(&sqlMode)
+	if err != nil {
+		panic(err)
+	}
+	return strings.Contains(sqlMode, "NO_BACKSLASH_ESCAPES")
+}
+
+// SetTimestamps sets the timestamp columns of records written by builders
+// of the connection and its transactions, which may be overridden per
+// builder with SetTimestamps. Use dat.DefaultTimestamps for created_at and
+// updated_at.
+func (db *DB) SetTimestamps(timestamps *dat.Timestamps) *DB {
+	db.timestamps = timestamps
+	return db
+}
+
+// DefaultScope sets the default scope of table, a condition applied to the
+// Select, SelectDoc, Update and DeleteFrom builders of the connection and
+// its transactions for the table unless they are Unscoped.
+//
+//	DB.DefaultScope("posts", dat.NewScope(":TABLE.tenant_id = :tenant", dat.M{"tenant": 1}))
+func (db *DB) DefaultScope(table string, scope dat.Scope) *DB {
+	db.defaultTableScopes().Add(table, scope)
+	return db
+}
+
+// SoftDelete makes DeleteFrom of table set column to the current time and
+// sets the default scope of table to rows where column is NULL.
+func (db *DB) SoftDelete(table string, column string) *DB {
+	db.defaultTableScopes().SoftDelete(table, column)
+	return db
+}
+
+func (db *DB) defaultTableScopes() *dat.TableScopes {
+	if db.tableScopes == nil {
+		db.tableScopes = dat.NewTableScopes()
+	}
+	return db.tableScopes
+}
+
 // Loose returns a DB clone that can loosely populate a struct. sqlx refers
 // to loose as `Unsafe`, but what it means is error when a result of a query
 // has more columns than a destination struct. In loose mode ignore this error.
@@ -99,7 +205,7 @@ func (db *DB) Loose() *DB {
 
 	return &DB{
 		DB:        unsafe,
-		Queryable: &Queryable{unsafe},
+		Queryable: &Queryable{runner: unsafe, dialect: db.dialect, timestamps: db.timestamps, tableScopes: db.tableScopes},
 		Version:   db.Version,
 	}
 }
@@ -29,3 +29,28 @@ func TestMissingDestinationColumns(t *testing.T) {
 	err = testDB.Loose().SQL(`SELECT * FROM posts LIMIT 2`).QueryStructs(&rows)
 	assert.NoError(t, err, "In non-strict mode, having more result columns than destination should not error")
 }
+
+func TestCopyFrom(t *testing.T) {
+	rows := [][]interface{}{
+		{"copy1", "copy1@acme.com"},
+		{"copy2", "copy2@acme.com"},
+	}
+
+	// DB uses COPY with pgx
+	installFixtures()
+	n, err := testDB.CopyFrom("people", []string{"name", "email"}, rows)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	var count int
+	err = testDB.SQL(`SELECT count(*) FROM people WHERE name LIKE 'copy%'`).QueryScalar(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Tx inserts
+	tx := beginTxWithFixtures()
+	defer tx.AutoRollback()
+	n, err = tx.CopyFrom("people", []string{"name", "email"}, rows)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+}
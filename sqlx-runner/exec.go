@@ -12,7 +12,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	"github.com/lib/pq"
+	"github.com/jmoiron/sqlx/reflectx"
 	"github.com/matcherino/dat/dat"
 	"github.com/matcherino/dat/kvs"
 	guid "github.com/satori/go.uuid"
@@ -53,13 +53,11 @@ func toOutputStr(args []interface{}) string {
 func logSQLError(err error, msg string, statement string, args []interface{}) error {
 	// it might be possible for a query to finish in between ex.timeout expiring locally
 	// and before pg_cancel_backend executes on postgres server.
-	if pe, ok := err.(*pq.Error); ok {
-		if pe.Code == "57014" {
-			// dat initiates the cancellation of a query on timeout.  Coerce the error into
-			// a timedout error so the end user does not see a false error in the logs.
-			if strings.HasPrefix(statement, queryIDPrefix) {
-				return dat.ErrTimedout
-			}
+	if isCancelled(err) {
+		// dat initiates the cancellation of a query on timeout.  Coerce the error into
+		// a timedout error so the end user does not see a false error in the logs.
+		if strings.HasPrefix(statement, queryIDPrefix) {
+			return dat.ErrTimedout
 		}
 	} else if err == sql.ErrNoRows {
 		if LogErrNoRows && logger.IsDebug() {
@@ -96,6 +94,7 @@ func (ex *Execer) exec() (sql.Result, error) {
 		return ex.execFn()
 	}
 
+	defer ex.queryContext()()
 	ch := make(chan bool, 1)
 	var result sql.Result
 	var err error
@@ -157,6 +156,7 @@ func (ex *Execer) query() (*sqlx.Rows, error) {
 		return ex.queryFn()
 	}
 
+	defer ex.queryContext()()
 	ch := make(chan bool, 1)
 	var rows *sqlx.Rows
 	var err error
@@ -196,6 +196,7 @@ func (ex *Execer) queryScalar(destinations ...interface{}) error {
 		return ex.queryScalarFn(destinations)
 	}
 
+	defer ex.queryContext()()
 	ch := make(chan bool, 1)
 	var err error
 	go func() {
@@ -259,6 +260,7 @@ func (ex *Execer) querySlice(dest interface{}) error {
 		return ex.querySliceFn(dest)
 	}
 
+	defer ex.queryContext()()
 	ch := make(chan bool, 1)
 	var err error
 	go func() {
@@ -355,6 +357,7 @@ func (ex *Execer) queryStruct(dest interface{}) error {
 		return ex.queryStructFn(dest)
 	}
 
+	defer ex.queryContext()()
 	ch := make(chan bool, 1)
 	var err error
 	go func() {
@@ -404,6 +407,7 @@ func (ex *Execer) queryStructs(dest interface{}) error {
 		return ex.queryStructsFn(dest)
 	}
 
+	defer ex.queryContext()()
 	ch := make(chan bool, 1)
 	var err error
 	go func() {
@@ -450,6 +454,232 @@ func (ex *Execer) queryStructsFn(dest interface{}) error {
 	return err
 }
 
+func (ex *Execer) queryPage(dest interface{}) (int64, error) {
+	if ex.timeout == 0 {
+		return ex.queryPageFn(dest)
+	}
+
+	defer ex.queryContext()()
+	ch := make(chan bool, 1)
+	var err error
+	var total int64
+	go func() {
+		total, err = ex.queryPageFn(dest)
+		ch <- true
+	}()
+	for {
+		select {
+		case <-time.After(ex.timeout):
+			return 0, ex.Cancel()
+		case <-ch:
+			return total, err
+		}
+	}
+}
+
+// pageCache is the cached result of QueryPage.
+type pageCache struct {
+	Items interface{} `json:"items"`
+	Total int64       `json:"total"`
+}
+
+// queryPageFn executes the query of a builder paginated with
+// dat.PaginateTotal, loads the rows into dest and returns the total count
+// of rows.
+//
+// Returns 0 if nothing was found
+func (ex *Execer) queryPageFn(dest interface{}) (int64, error) {
+	if ex.builder.CanJSON() {
+		blob, err := ex.queryJSONBlobFn(false)
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if err = json.Unmarshal(blob, dest); err != nil {
+			return 0, err
+		}
+		// every document has the total
+		var totals []struct {
+			Total int64 `json:"dat__total"`
+		}
+		if err = json.Unmarshal(blob, &totals); err != nil || len(totals) == 0 {
+			return 0, err
+		}
+		return totals[0].Total, nil
+	}
+
+	fullSQL, args, blob, err := ex.cacheOrSQL()
+	if err != nil {
+		logger.Error("queryPage.1: Could not convert to SQL", "err", err)
+		return 0, err
+	}
+	if blob != nil {
+		cached := pageCache{Items: dest}
+		err = json.Unmarshal(blob, &cached)
+		if err == nil {
+			return cached.Total, nil
+		}
+		// log it and let the query continue
+		logger.Warn("queryPage.2: Could not unmarshal queryPage cache data. Continuing with query", "err", err)
+	}
+
+	defer logExecutionTime(time.Now(), fullSQL, args)
+	rows, err := ex.database.Queryx(fullSQL, args...)
+	if err != nil {
+		return 0, logSQLError(err, "queryPage", fullSQL, args)
+	}
+	defer rows.Close()
+	total, err := scanPage(rows, dest)
+	if err != nil {
+		return 0, logSQLError(err, "queryPage", fullSQL, args)
+	}
+
+	ex.setCache(&pageCache{Items: dest, Total: total}, dtStruct)
+	return total, nil
+}
+
+// scanPage scans rows into dest, a pointer to a slice of structs or
+// pointers to structs, like sqlx.Select except for dat.PageTotalColumn
+// which is returned.
+func scanPage(rows *sqlx.Rows, dest interface{}) (int64, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return 0, errors.New("QueryPage requires a pointer to a slice")
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	base := elemType
+	if isPtr {
+		base = base.Elem()
+	}
+	if base.Kind() != reflect.Struct {
+		return 0, errors.New("QueryPage requires a slice of structs")
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	traversals := rows.Mapper.TraversalsByName(base, columns)
+	for i, column := range columns {
+		if column != dat.PageTotalColumn && len(traversals[i]) == 0 {
+			return 0, fmt.Errorf("missing destination name %s in %T", column, dest)
+		}
+	}
+
+	var total int64
+	targets := make([]interface{}, len(columns))
+	slice.Set(slice.Slice(0, 0))
+	for rows.Next() {
+		item := reflect.New(base)
+		for i, column := range columns {
+			if column == dat.PageTotalColumn {
+				targets[i] = &total
+				continue
+			}
+			targets[i] = reflectx.FieldByIndexes(item.Elem(), traversals[i]).Addr().Interface()
+		}
+		if err = rows.Scan(targets...); err != nil {
+			return 0, err
+		}
+		if isPtr {
+			slice.Set(reflect.Append(slice, item))
+		} else {
+			slice.Set(reflect.Append(slice, item.Elem()))
+		}
+	}
+	return total, rows.Err()
+}
+
+func (ex *Execer) fillRecords(records []interface{}) error {
+	if ex.timeout == 0 {
+		return ex.fillRecordsFn(records)
+	}
+
+	defer ex.queryContext()()
+	ch := make(chan bool, 1)
+	var err error
+	go func() {
+		err = ex.fillRecordsFn(records)
+		ch <- true
+	}()
+	for {
+		select {
+		case <-time.After(ex.timeout):
+			return ex.Cancel()
+		case <-ch:
+			return err
+		}
+	}
+}
+
+// fillRecordsFn executes the query in builder and scans each returned row
+// into the record of the same index.
+//
+// Returns sql.ErrNoRows if nothing was returned
+func (ex *Execer) fillRecordsFn(records []interface{}) error {
+	fullSQL, args, err := ex.Interpolate()
+	if err != nil {
+		logger.Error("fillRecords.1: Could not convert to SQL", "err", err)
+		return err
+	}
+
+	defer logExecutionTime(time.Now(), fullSQL, args)
+	rows, err := ex.database.Queryx(fullSQL, args...)
+	if err != nil {
+		return logSQLError(err, "fillRecords", fullSQL, args)
+	}
+	defer rows.Close()
+	n, err := scanRecords(rows, records)
+	if err != nil {
+		return logSQLError(err, "fillRecords", fullSQL, args)
+	}
+	if err = dat.CheckStale(ex.builder, int64(n)); err != nil {
+		return err
+	}
+	if n == 0 {
+		return logSQLError(sql.ErrNoRows, "fillRecords", fullSQL, args)
+	}
+	if n != len(records) {
+		return logSQLError(fmt.Errorf("FillRecords returned %d rows for %d records", n, len(records)), "fillRecords", fullSQL, args)
+	}
+	return nil
+}
+
+// scanRecords scans the i-th of rows into records[i], a pointer to a
+// struct, and returns the number of rows.
+func scanRecords(rows *sqlx.Rows, records []interface{}) (int, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	targets := make([]interface{}, len(columns))
+	for rows.Next() {
+		if n >= len(records) {
+			n++
+			continue
+		}
+		v := reflect.ValueOf(records[n]).Elem()
+		traversals := rows.Mapper.TraversalsByName(v.Type(), columns)
+		for i, column := range columns {
+			if len(traversals[i]) == 0 {
+				return n, fmt.Errorf("missing destination name %s in %T", column, records[n])
+			}
+			targets[i] = reflectx.FieldByIndexes(v, traversals[i]).Addr().Interface()
+		}
+		if err = rows.Scan(targets...); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
 // queryJSONStruct executes the query in builder and loads the resulting data into
 // a struct, using json.Unmarshal().
 //
@@ -470,6 +700,7 @@ func (ex *Execer) queryJSONBlob(single bool) ([]byte, error) {
 		return ex.queryJSONBlobFn(single)
 	}
 
+	defer ex.queryContext()()
 	ch := make(chan bool, 1)
 	var err error
 	var b []byte
@@ -665,6 +896,7 @@ func (ex *Execer) queryJSON() ([]byte, error) {
 		return ex.queryJSONFn()
 	}
 
+	defer ex.queryContext()()
 	ch := make(chan bool, 1)
 	var err error
 	var b []byte
@@ -688,6 +920,10 @@ func (ex *Execer) queryJSON() ([]byte, error) {
 //
 // Returns sql.ErrNoRows if nothing was found
 func (ex *Execer) queryJSONFn() ([]byte, error) {
+	if err := dat.ErrUnsupported(dat.BuilderDialect(ex.builder), dat.FeatureJSONDocuments); err != nil {
+		return nil, err
+	}
+
 	fullSQL, args, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return nil, err
@@ -696,6 +932,14 @@ func (ex *Execer) queryJSONFn() ([]byte, error) {
 		return blob, nil
 	}
 
+	if dat.DialectJSONStyle(dat.BuilderDialect(ex.builder)) == dat.SQLiteJSON {
+		blob, err = ex.queryJSONRows(fullSQL, args)
+		if err == nil {
+			ex.setCache(blob, dtBytes)
+		}
+		return blob, err
+	}
+
 	defer logExecutionTime(time.Now(), fullSQL, args)
 	jsonSQL := fmt.Sprintf("SELECT TO_JSON(ARRAY_AGG(__datq.*)) FROM (%s) AS __datq", fullSQL)
 
@@ -708,6 +952,42 @@ func (ex *Execer) queryJSONFn() ([]byte, error) {
 	return blob, err
 }
 
+// queryJSONRows executes fullSQL and encodes the rows as a JSON array of
+// objects. It is used by dialects without an equivalent of
+// TO_JSON(ARRAY_AGG(row)), where column names must be known to build an
+// object.
+//
+// Returns nil if nothing was found
+func (ex *Execer) queryJSONRows(fullSQL string, args []interface{}) ([]byte, error) {
+	defer logExecutionTime(time.Now(), fullSQL, args)
+	rows, err := ex.database.Queryx(fullSQL, args...)
+	if err != nil {
+		return nil, logSQLError(err, "queryJSON", fullSQL, args)
+	}
+	defer rows.Close()
+
+	var objects []map[string]interface{}
+	for rows.Next() {
+		obj := map[string]interface{}{}
+		if err = rows.MapScan(obj); err != nil {
+			return nil, err
+		}
+		for k, v := range obj {
+			if b, ok := v.([]byte); ok {
+				obj[k] = string(b)
+			}
+		}
+		objects = append(objects, obj)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, logSQLError(err, "queryJSON", fullSQL, args)
+	}
+	if objects == nil {
+		return nil, nil
+	}
+	return json.Marshal(objects)
+}
+
 // queryObject executes the query in builder and loads the resulting data into
 // an object agreeable with json.Unmarshal.
 //
@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -24,6 +25,9 @@ type Execer struct {
 	// uuid is prepended into the SQL for the query to be searched
 	// in pg_stat_activity, used by timeout logic
 	queryID string
+
+	// cancel cancels the context of the last query on pgx connections
+	cancel context.CancelFunc
 }
 
 const queryIDPrefix = "--dat:qid="
@@ -46,15 +50,43 @@ func (ex *Execer) Cache(id string, ttl time.Duration, invalidate bool) dat.Exece
 
 // Timeout sets the timeout for current query.
 func (ex *Execer) Timeout(timeout time.Duration) dat.Execer {
+	if d := dat.BuilderDialect(ex.builder); timeout > 0 && !dat.DialectSupports(d, dat.FeatureQueryCancel) {
+		logger.Warn("Timeout ignored", "err", dat.ErrUnsupported(d, dat.FeatureQueryCancel))
+		return ex
+	}
 	ex.timeout = timeout
 	if timeout > 0 {
 		ex.queryID = uuid()
 	} else {
 		ex.queryID = ""
 	}
+
+	if ctxdb, ok := ex.database.(ctxDatabase); ok {
+		ex.database = ctxdb.contextDatabase
+		ex.cancel = nil
+	}
 	return ex
 }
 
+// queryContext runs the next query of ex with a new context on pgx
+// connections, which cancel queries natively when their context is
+// cancelled by Cancel. The returned func releases the context once the
+// query is done.
+func (ex *Execer) queryContext() context.CancelFunc {
+	db := ex.database
+	if ctxdb, ok := db.(ctxDatabase); ok {
+		db = ctxdb.contextDatabase
+	}
+	ctxdb, ok := db.(contextDatabase)
+	if !ok || !isPgx(ctxdb) {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ex.database = ctxDatabase{ctxdb, ctx}
+	ex.cancel = cancel
+	return cancel
+}
+
 func datQueryID(id string) string {
 	return fmt.Sprintf("--dat:qid=%s", id)
 }
@@ -70,6 +102,11 @@ func (ex *Execer) Cancel() error {
 		return dat.ErrInvalidOperation
 	}
 
+	if ex.cancel != nil {
+		ex.cancel()
+		return dat.ErrTimedout
+	}
+
 	q := fmt.Sprintf(`
 	SELECT pg_cancel_backend(psa.pid)
 	FROM (
@@ -95,8 +132,13 @@ func (ex *Execer) Interpolate() (string, []interface{}, error) {
 	return sql, args, err
 }
 
-// Exec executes a builder's query.
+// Exec executes a builder's query. dat.ErrStaleRecord is returned if
+// versioned records were changed or deleted since they were read. Builders
+// with nothing to execute, see dat.IsNoop, return an empty result.
 func (ex *Execer) Exec() (*dat.Result, error) {
+	if dat.IsNoop(ex.builder) {
+		return &dat.Result{}, nil
+	}
 	res, err := ex.exec()
 	if err != nil {
 		return nil, err
@@ -105,6 +147,9 @@ func (ex *Execer) Exec() (*dat.Result, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = dat.CheckStale(ex.builder, rowsAffected); err != nil {
+		return nil, err
+	}
 	return &dat.Result{RowsAffected: rowsAffected}, nil
 }
 
@@ -169,3 +214,60 @@ func (ex *Execer) QueryJSON() ([]byte, error) {
 
 	return ex.queryJSON()
 }
+
+// QueryKeyset executes the query of a builder using KeysetPaginate, scans
+// the rows of the page into dest, a pointer to a slice of structs, and
+// returns the cursors of the pages before and after it.
+func (ex *Execer) QueryKeyset(dest interface{}) (*dat.KeysetPage, error) {
+	if err := ex.QueryStructs(dest); err != nil {
+		return nil, err
+	}
+	return dat.KeysetResult(ex.builder, dest)
+}
+
+// QueryPage executes the query of a Select or SelectDoc builder for page,
+// numbered from 1, of perPage rows. The rows are scanned into dest, a
+// pointer to a slice of structs, and counted in the same round trip, or a
+// second one if the page is past the last. The builder is not paginated.
+func (ex *Execer) QueryPage(dest interface{}, page, perPage uint64) (*dat.Page, error) {
+	paged, err := dat.PaginateTotal(ex.builder, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+	pex := *ex
+	pex.builder = paged
+	total, err := pex.queryPage(dest)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 && page > 1 {
+		// a page past the last has no rows holding the count
+		count, err := dat.PageCount(paged)
+		if err != nil {
+			return nil, err
+		}
+		cex := pex
+		cex.builder = count
+		cex.cacheID = ""
+		if err = cex.QueryScalar(&total); err != nil {
+			return nil, err
+		}
+	}
+	return dat.NewPage(dest, total, page, perPage), nil
+}
+
+// FillRecords executes the query of an Insert, Upsert or Insect builder of
+// records, or an Update of SetWhitelist or SetBlacklist, and scans the rows
+// of its RETURNING clause back into the records in order. The records must
+// be pointers to structs. dat.ErrStaleRecord is returned if versioned
+// records were changed or deleted since they were read.
+func (ex *Execer) FillRecords() error {
+	if dat.IsNoop(ex.builder) {
+		return nil
+	}
+	records, err := dat.ReturningRecords(ex.builder)
+	if err != nil {
+		return err
+	}
+	return ex.fillRecords(records)
+}
@@ -0,0 +1,7 @@
+package runner
+
+// BeginTxWithFixtures is beginTxWithFixtures for tests of packages which
+// import runner, such as schema.
+func BeginTxWithFixtures() *Tx {
+	return beginTxWithFixtures()
+}
@@ -20,7 +20,7 @@ var sqlDB *sql.DB
 func init() {
 	dat.Dialect = postgres.New()
 	sqlDB = realDb()
-	testDB = NewDB(sqlDB, "postgres")
+	testDB = NewDB(sqlDB, os.Getenv("DAT_DRIVER"))
 	dat.Strict = false
 	logxi.Suppress(true)
 
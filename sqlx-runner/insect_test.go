@@ -69,3 +69,20 @@ func TestInsectSelectWhere(t *testing.T) {
 	assert.Equal(t, "Mario", p.Name)
 	assert.Equal(t, "mario@acme.com", p.Email.String)
 }
+
+func TestInsectFillRecords(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	// existing rows are returned
+	p := &Person{Name: "Mario"}
+	err := s.
+		Insect("people").
+		Columns("name").
+		Record(p).
+		Returning("id", "email").
+		FillRecords()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, p.ID)
+	assert.Equal(t, "mario@acme.com", p.Email.String)
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/matcherino/dat/common"
 	"github.com/matcherino/dat/dat"
@@ -241,3 +242,75 @@ func TestInsertBytes(t *testing.T) {
 	assert.Exactly(t, b, image)
 	dat.EnableInterpolation = false
 }
+
+func TestInsertFromSelect(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var copies []*Person
+	err := s.
+		InsertInto("people").
+		Columns("name", "email").
+		FromSelect(dat.Select("name", "'copy-' || email").From("people").Where("id IN $1", []int{1, 2})).
+		Returning("id", "name", "email").
+		QueryStructs(&copies)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(copies))
+	for _, p := range copies {
+		assert.True(t, strings.HasPrefix(p.Email.String, "copy-"))
+	}
+}
+
+func TestInsertFillRecords(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	a := &Person{Name: "Fill A"}
+	b := &Person{Name: "Fill B"}
+	err := s.
+		InsertInto("people").
+		Columns("name").
+		Record(a).
+		Record(b).
+		Returning("id", "created_at").
+		FillRecords()
+	assert.NoError(t, err)
+	assert.True(t, a.ID > 0)
+	assert.Equal(t, a.ID+1, b.ID)
+	assert.True(t, a.CreatedAt.Valid)
+	assert.Equal(t, "Fill B", b.Name)
+}
+
+func TestInsertTimestamps(t *testing.T) {
+	installFixtures()
+	db := testDB.Loose().SetTimestamps(&dat.Timestamps{Created: []string{"created_at"}})
+	s, err := db.Begin()
+	assert.NoError(t, err)
+	defer s.AutoRollback()
+
+	p := &Person{Name: "Stamped"}
+	p.CreatedAt.Time = time.Unix(0, 0)
+	p.CreatedAt.Valid = true
+	err = s.
+		InsertInto("people").
+		Columns("name").
+		Record(p).
+		Returning("id").
+		FillRecords()
+	assert.NoError(t, err)
+	assert.True(t, p.ID > 0)
+	assert.True(t, p.CreatedAt.Valid)
+	assert.True(t, p.CreatedAt.Time.After(time.Unix(0, 0)))
+
+	// overridden per builder
+	p = &Person{Name: "Unstamped"}
+	err = s.
+		InsertInto("people").
+		Columns("name").
+		Record(p).
+		SetTimestamps(nil).
+		Returning("id").
+		FillRecords()
+	assert.NoError(t, err)
+	assert.False(t, p.CreatedAt.Valid)
+}
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+// interpolated runs sql with arg interpolated as a literal and scans the
+// single result into dest.
+func interpolated(sql string, arg interface{}, dest interface{}) error {
+	return testDB.SQL(sql, arg).SetIsInterpolated(true).QueryScalar(dest)
+}
+
+func TestInterpolateRoundTrip(t *testing.T) {
+	var b []byte
+	err := interpolated("SELECT $1", []byte{0, 1, 0xff}, &b)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 0xff}, b)
+
+	var s string
+	uuid := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	err = interpolated("SELECT $1::text", uuid, &s)
+	assert.NoError(t, err)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", s)
+
+	err = interpolated("SELECT host($1)", net.ParseIP("192.168.0.1"), &s)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.1", s)
+
+	_, ipnet, _ := net.ParseCIDR("10.1.0.0/16")
+	err = interpolated("SELECT $1::text", ipnet, &s)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.0/16", s)
+
+	var f float64
+	err = interpolated("SELECT extract(epoch FROM $1)", -90*time.Minute-500*time.Millisecond, &f)
+	assert.NoError(t, err)
+	assert.Equal(t, -5400.5, f)
+
+	err = interpolated("SELECT $1 -> 'b'", map[string]string{"a": "1", "b": `it's "quoted"`}, &s)
+	assert.NoError(t, err)
+	assert.Equal(t, `it's "quoted"`, s)
+
+	i, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	err = interpolated("SELECT $1::text", i, &s)
+	assert.NoError(t, err)
+	assert.Equal(t, i.String(), s)
+
+	err = interpolated("SELECT $1::text", big.NewRat(-5, 8), &s)
+	assert.NoError(t, err)
+	assert.Equal(t, "-0.625", s)
+
+	err = interpolated("SELECT $1::text", [][]int{{1, 2}, {3, 4}}, &s)
+	assert.NoError(t, err)
+	assert.Equal(t, "{{1,2},{3,4}}", s)
+}
@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/matcherino/dat/dat"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestMergeSlice(t *testing.T) {
+	if testDB.Version < 150000 {
+		t.Skip("MERGE requires Postgres 15")
+	}
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	type staged struct {
+		ID    int64  `db:"id"`
+		Name  string `db:"name"`
+		Email string `db:"email"`
+	}
+	result, err := s.
+		Merge("people AS p").
+		Using([]staged{{1, "Mario", "mario@barc.com"}, {6, "", ""}, {200, "Luigi", "luigi@barc.com"}}, "s").
+		On("p.id = s.id").
+		WhenMatched("s.name = ''").Delete().
+		WhenMatched().Update(dat.M{"email": dat.Expr("s.email")}).
+		WhenNotMatched().Insert([]string{"id", "name", "email"}).
+		Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, result.RowsAffected)
+
+	var people []*Person
+	err = s.
+		Select("id", "name", "email").
+		From("people").
+		Where("id IN $1", []int64{1, 6, 200}).
+		OrderBy("id").
+		QueryStructs(&people)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(people))
+	assert.Equal(t, "mario@barc.com", people[0].Email.String)
+	assert.Equal(t, "Luigi", people[1].Name)
+}
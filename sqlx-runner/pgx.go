@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// pgxDriverName is the database/sql driver name registered by pgx's stdlib
+// package.
+const pgxDriverName = "pgx"
+
+// maxCopyParams is the maximum number of placeholders Postgres accepts in
+// a statement, which limits the rows per INSERT when CopyFrom falls back
+// to inserting.
+const maxCopyParams = 65535
+
+// contextDatabase is implemented by sqlx's DB and Tx.
+type contextDatabase interface {
+	database
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// ctxDatabase runs the queries of a database with ctx. pgx cancels a
+// running query on the server when its context is cancelled.
+type ctxDatabase struct {
+	contextDatabase
+	ctx context.Context
+}
+
+func (db ctxDatabase) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(db.ctx, query, args...)
+}
+
+func (db ctxDatabase) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return db.QueryxContext(db.ctx, query, args...)
+}
+
+func (db ctxDatabase) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	return db.QueryRowxContext(db.ctx, query, args...)
+}
+
+func (db ctxDatabase) Select(dest interface{}, query string, args ...interface{}) error {
+	return db.SelectContext(db.ctx, dest, query, args...)
+}
+
+func (db ctxDatabase) Get(dest interface{}, query string, args ...interface{}) error {
+	return db.GetContext(db.ctx, dest, query, args...)
+}
+
+// isPgx returns whether db is connected with pgx's stdlib driver.
+func isPgx(db database) bool {
+	if ctxdb, ok := db.(ctxDatabase); ok {
+		db = ctxdb.contextDatabase
+	}
+	dn, ok := db.(interface {
+		DriverName() string
+	})
+	return ok && dn.DriverName() == pgxDriverName
+}
+
+// pgErrorCode returns the SQLSTATE code of a Postgres error returned by
+// lib/pq or pgx, or "" for other errors.
+func pgErrorCode(err error) string {
+	switch pe := err.(type) {
+	case *pq.Error:
+		return string(pe.Code)
+	case *pgconn.PgError:
+		return pe.Code
+	}
+	return ""
+}
+
+// isCancelled returns whether err is the result of a query cancelled by
+// dat on timeout.
+func isCancelled(err error) bool {
+	return pgErrorCode(err) == "57014" || err == context.Canceled || pgconn.Timeout(err)
+}
+
+// CopyFrom inserts rows into the columns of table and returns the number of
+// rows inserted. Connections using pgx use the COPY protocol, otherwise
+// rows are inserted with multi-row INSERT statements.
+func (q *Queryable) CopyFrom(table string, columns []string, rows [][]interface{}) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if dbx, ok := q.runner.(*sqlx.DB); ok && isPgx(dbx) {
+		return pgxCopyFrom(dbx, table, columns, rows)
+	}
+
+	var count int64
+	size := maxCopyParams / len(columns)
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		b := q.InsertInto(table).Columns(columns...)
+		for _, row := range rows[start:end] {
+			b.Values(row...)
+		}
+		result, err := b.Exec()
+		if err != nil {
+			return count, err
+		}
+		count += result.RowsAffected
+	}
+	return count, nil
+}
+
+// pgxCopyFrom copies rows with pgx's CopyFrom on a connection from the pool
+// of dbx.
+func pgxCopyFrom(dbx *sqlx.DB, table string, columns []string, rows [][]interface{}) (int64, error) {
+	ctx := context.Background()
+	conn, err := dbx.Conn(ctx)
+	if err != nil {
+		return 0, logger.Error("pgxCopyFrom.10", "err", err)
+	}
+	defer conn.Close()
+
+	var count int64
+	err = conn.Raw(func(driverConn interface{}) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return logger.Error("pgxCopyFrom.20: not a pgx connection")
+		}
+		count, err = pc.Conn().CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, pgx.CopyFromRows(rows))
+		return err
+	})
+	if err != nil {
+		return count, logger.Error("pgxCopyFrom.30", "err", err, "table", table)
+	}
+	return count, nil
+}
@@ -11,6 +11,15 @@ import (
 // Queryable is an object that can be queried.
 type Queryable struct {
 	runner database
+	// dialect is the SQLDialect of builders created by this Queryable. The
+	// default dat.Dialect is used if nil.
+	dialect dat.SQLDialect
+	// timestamps are the timestamp columns of records written by builders
+	// created by this Queryable.
+	timestamps *dat.Timestamps
+	// tableScopes are the default scopes of tables of builders created by
+	// this Queryable.
+	tableScopes *dat.TableScopes
 }
 
 // WrapSqlxExt converts a sqlx.Ext to a *Queryable
@@ -19,13 +28,14 @@ func WrapSqlxExt(e sqlx.Ext) (*Queryable, error) {
 	default:
 		return nil, dat.NewError(fmt.Sprintf("unexpected type %T", e))
 	case database:
-		return &Queryable{e}, nil
+		return &Queryable{runner: e}, nil
 	}
 }
 
 // Call creates a new CallBuilder for the given sproc and args.
 func (q *Queryable) Call(sproc string, args ...interface{}) *dat.CallBuilder {
 	b := dat.NewCallBuilder(sproc, args...)
+	b.SetDialect(q.dialect)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -33,6 +43,8 @@ func (q *Queryable) Call(sproc string, args ...interface{}) *dat.CallBuilder {
 // DeleteFrom creates a new DeleteBuilder for the given table.
 func (q *Queryable) DeleteFrom(table string) *dat.DeleteBuilder {
 	b := dat.NewDeleteBuilder(table)
+	b.SetDialect(q.dialect)
+	b.SetTableScopes(q.tableScopes)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -90,6 +102,8 @@ func (q *Queryable) ExecMulti(commands ...*dat.Expression) (int, error) {
 // InsertInto creates a new InsertBuilder for the given table.
 func (q *Queryable) InsertInto(table string) *dat.InsertBuilder {
 	b := dat.NewInsertBuilder(table)
+	b.SetDialect(q.dialect)
+	b.SetTimestamps(q.timestamps)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -97,6 +111,8 @@ func (q *Queryable) InsertInto(table string) *dat.InsertBuilder {
 // Insect inserts or selects.
 func (q *Queryable) Insect(table string) *dat.InsectBuilder {
 	b := dat.NewInsectBuilder(table)
+	b.SetDialect(q.dialect)
+	b.SetTimestamps(q.timestamps)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -104,6 +120,15 @@ func (q *Queryable) Insect(table string) *dat.InsectBuilder {
 // JSQL creates a new JSON SQL builder.
 func (q *Queryable) JSQL(sql string, args ...interface{}) *dat.JSQLBuilder {
 	b := dat.NewJSQLBuilder(sql, args...)
+	b.SetDialect(q.dialect)
+	b.Execer = NewExecer(q.runner, b)
+	return b
+}
+
+// Merge creates a new MergeBuilder for the given table.
+func (q *Queryable) Merge(table string) *dat.MergeBuilder {
+	b := dat.NewMergeBuilder(table)
+	b.SetDialect(q.dialect)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -111,6 +136,8 @@ func (q *Queryable) JSQL(sql string, args ...interface{}) *dat.JSQLBuilder {
 // Select creates a new SelectBuilder for the given columns.
 func (q *Queryable) Select(columns ...string) *dat.SelectBuilder {
 	b := dat.NewSelectBuilder(columns...)
+	b.SetDialect(q.dialect)
+	b.SetTableScopes(q.tableScopes)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -118,6 +145,8 @@ func (q *Queryable) Select(columns ...string) *dat.SelectBuilder {
 // SelectDoc creates a new SelectBuilder for the given columns.
 func (q *Queryable) SelectDoc(columns ...string) *dat.SelectDocBuilder {
 	b := dat.NewSelectDocBuilder(columns...)
+	b.SetDialect(q.dialect)
+	b.SetTableScopes(q.tableScopes)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -125,6 +154,7 @@ func (q *Queryable) SelectDoc(columns ...string) *dat.SelectDocBuilder {
 // SQL creates a new raw SQL builder.
 func (q *Queryable) SQL(sql string, args ...interface{}) *dat.RawBuilder {
 	b := dat.NewRawBuilder(sql, args...)
+	b.SetDialect(q.dialect)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -132,6 +162,9 @@ func (q *Queryable) SQL(sql string, args ...interface{}) *dat.RawBuilder {
 // Update creates a new UpdateBuilder for the given table.
 func (q *Queryable) Update(table string) *dat.UpdateBuilder {
 	b := dat.NewUpdateBuilder(table)
+	b.SetDialect(q.dialect)
+	b.SetTableScopes(q.tableScopes)
+	b.SetTimestamps(q.timestamps)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -139,6 +172,8 @@ func (q *Queryable) Update(table string) *dat.UpdateBuilder {
 // Upsert creates a new UpdateBuilder for the given table.
 func (q *Queryable) Upsert(table string) *dat.UpsertBuilder {
 	b := dat.NewUpsertBuilder(table)
+	b.SetDialect(q.dialect)
+	b.SetTimestamps(q.timestamps)
 	b.Execer = NewExecer(q.runner, b)
 	return b
 }
@@ -0,0 +1,66 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/matcherino/dat/dat"
+	"github.com/matcherino/dat/dat/schema"
+	"github.com/matcherino/dat/sqlx-runner"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestSchemaRead(t *testing.T) {
+	tx := runner.BeginTxWithFixtures()
+	defer tx.AutoRollback()
+
+	_, err := tx.ExecMulti(
+		dat.Expr("CREATE TYPE post_state AS ENUM ('draft', 'published')"),
+		dat.Expr("CREATE INDEX posts_title_idx ON posts (title)"),
+	)
+	assert.NoError(t, err)
+
+	s, err := schema.Read(tx)
+	assert.NoError(t, err)
+
+	people, posts := s.Table("people"), s.Table("posts")
+	if !assert.NotNil(t, people) || !assert.NotNil(t, posts) {
+		return
+	}
+
+	name := people.Column("name")
+	if assert.NotNil(t, name) {
+		assert.Equal(t, "text", name.Type)
+		assert.False(t, name.Nullable)
+	}
+	foo := people.Column("foo")
+	if assert.NotNil(t, foo) {
+		assert.True(t, foo.Nullable)
+		assert.Equal(t, "'bar'::text", foo.Default.String)
+	}
+	if assert.NotNil(t, people.PrimaryKey) {
+		assert.Equal(t, []string{"id"}, people.PrimaryKey.Columns)
+	}
+
+	if assert.Len(t, posts.ForeignKeys, 1) {
+		fk := posts.ForeignKeys[0]
+		assert.Equal(t, []string{"user_id"}, fk.Columns)
+		assert.Equal(t, "people", fk.RefTable)
+		assert.Equal(t, []string{"id"}, fk.RefColumns)
+	}
+
+	var index *schema.Index
+	for _, idx := range posts.Indexes {
+		if idx.Name == "posts_title_idx" {
+			index = idx
+		}
+	}
+	if assert.NotNil(t, index) {
+		assert.Equal(t, []string{"title"}, index.Columns)
+		assert.False(t, index.Unique)
+	}
+
+	enum := s.Enum("post_state")
+	if assert.NotNil(t, enum) {
+		assert.Equal(t, []string{"draft", "published"}, enum.Values)
+	}
+}
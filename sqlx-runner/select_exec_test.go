@@ -319,4 +319,110 @@ func TestSelectFor(t *testing.T) {
 	assert.Equal(t, int64(1), p.ID)
 }
 
+func TestSelectSetOperations(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var people []Person
+	err := s.
+		Select("id", "name", "email").
+		From("people").
+		Where("name = $1", "Mario").
+		Union(s.Select("id", "name", "email").From("people").Where("name = $1", "John")).
+		OrderBy("id ASC").
+		QueryStructs(&people)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(people))
+	assert.Equal(t, "Mario", people[0].Name)
+	assert.Equal(t, "John", people[1].Name)
+
+	var names []string
+	err = s.
+		Select("name").
+		From("people").
+		Except("SELECT name FROM people WHERE name <> $1", "John").
+		QuerySlice(&names)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"John"}, names)
+}
+
+func TestSelectQueryKeyset(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	order := []string{"name DESC", "id"}
+	var people []Person
+	page, err := s.
+		Select("id", "name", "email").
+		From("people").
+		KeysetPaginate(order, "", 4).
+		QueryKeyset(&people)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(people))
+	assert.Equal(t, "", page.Prev)
+
+	var rest []Person
+	page, err = s.
+		Select("id", "name", "email").
+		From("people").
+		KeysetPaginate(order, page.Next, 4).
+		QueryKeyset(&rest)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rest))
+	assert.Equal(t, "", page.Next)
+
+	var prev []Person
+	_, err = s.
+		Select("id", "name", "email").
+		From("people").
+		KeysetPaginate(order, page.Prev, 4).
+		QueryKeyset(&prev)
+	assert.NoError(t, err)
+	assert.Equal(t, people, prev)
+}
+
+func TestSelectQueryPage(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var people []Person
+	page, err := s.
+		Select("id", "name", "email").
+		From("people").
+		OrderBy("id ASC").
+		QueryPage(&people, 2, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(people))
+	assert.Equal(t, int64(6), page.Total)
+	assert.Equal(t, uint64(2), page.Page)
+	assert.Equal(t, uint64(4), page.PerPage)
+	assert.False(t, page.HasNext)
+
+	var docs []*Person
+	page, err = s.
+		SelectDoc("id", "name", "email").
+		From("people").
+		OrderBy("id ASC").
+		QueryPage(&docs, 1, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(docs))
+	assert.Equal(t, int64(6), page.Total)
+	assert.True(t, page.HasNext)
+
+	// a page past the last is counted separately
+	people = nil
+	b := s.Select("id", "name", "email").From("people").OrderBy("id ASC")
+	page, err = b.QueryPage(&people, 3, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(people))
+	assert.Equal(t, int64(6), page.Total)
+	assert.False(t, page.HasNext)
+
+	// the builder is not paginated
+	people = nil
+	err = b.QueryStructs(&people)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(people))
+}
+
 // Series of tests that test mapping struct fields to columns
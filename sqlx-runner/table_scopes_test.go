@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestSoftDelete(t *testing.T) {
+	installFixtures()
+	db := testDB.Loose().SoftDelete("notes", "deleted_at")
+	s, err := db.Begin()
+	assert.NoError(t, err)
+	defer s.AutoRollback()
+
+	_, err = s.SQL(`
+		CREATE TEMP TABLE notes (
+			id serial PRIMARY KEY,
+			body text NOT NULL,
+			deleted_at timestamptz
+		) ON COMMIT DROP;
+		INSERT INTO notes (body) VALUES ('a'), ('b');
+	`).Exec()
+	assert.NoError(t, err)
+
+	res, err := s.DeleteFrom("notes").Where("body = $1", "a").Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, res.RowsAffected)
+
+	// deleting again finds no row
+	res, err = s.DeleteFrom("notes").Where("body = $1", "a").Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, res.RowsAffected)
+
+	var bodies []string
+	err = s.Select("body").From("notes").OrderBy("id").QuerySlice(&bodies)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, bodies)
+
+	res, err = s.Update("notes").Set("body", "c").Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, res.RowsAffected)
+
+	var count int
+	err = s.Select("count(*)").From("notes").Unscoped().QueryScalar(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	res, err = s.DeleteFrom("notes").Unscoped().Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, res.RowsAffected)
+}
@@ -25,6 +25,22 @@ func TestTimeoutExec(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestTimeoutReuse(t *testing.T) {
+	_, err := testDB.SQL("CREATE SEQUENCE timeout_reuse_seq").Exec()
+	assert.NoError(t, err)
+	defer testDB.SQL("DROP SEQUENCE timeout_reuse_seq").Exec()
+
+	// only the first query sleeps
+	b := testDB.SQL("SELECT pg_sleep(CASE WHEN nextval('timeout_reuse_seq') = 1 THEN 1 ELSE 0 END)")
+	b.Timeout(100 * time.Millisecond)
+	_, err = b.Exec()
+	assert.Equal(t, dat.ErrTimedout, err)
+
+	// a cancelled query does not cancel the next one
+	_, err = b.Exec()
+	assert.NoError(t, err)
+}
+
 func TestTimeoutScalar(t *testing.T) {
 	var s string
 	var n int
@@ -34,7 +34,7 @@ type Tx struct {
 
 // WrapSqlxTx creates a Tx from a sqlx.Tx
 func WrapSqlxTx(tx *sqlx.Tx) *Tx {
-	newtx := &Tx{Tx: tx, Queryable: &Queryable{tx}}
+	newtx := &Tx{Tx: tx, Queryable: &Queryable{runner: tx}}
 	if dat.Strict {
 		newtx.timer = time.AfterFunc(1*time.Minute, func() {
 			if !newtx.IsRollbacked && newtx.state == txPending {
@@ -55,7 +55,11 @@ func (db *DB) Begin() (*Tx, error) {
 		return nil, logger.Error("begin.error", err)
 	}
 	logger.Debug("begin tx")
-	return WrapSqlxTx(tx), nil
+	newtx := WrapSqlxTx(tx)
+	newtx.dialect = db.dialect
+	newtx.timestamps = db.timestamps
+	newtx.tableScopes = db.tableScopes
+	return newtx, nil
 }
 
 // Begin returns this transaction
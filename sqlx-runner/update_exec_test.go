@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"database/sql"
 	"testing"
 
 	"github.com/matcherino/dat/dat"
@@ -232,3 +233,79 @@ func TestUpdateScopeFunc(t *testing.T) {
 	assert.Equal(t, person.Email.Valid, true)
 	assert.Equal(t, person.Email.String, "barack@whitehouse.gov")
 }
+
+func TestUpdateFromRecords(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	records := []*Person{
+		{ID: 1, Name: "Mario2", Email: dat.NullStringFrom("mario2@acme.com")},
+		{ID: 2, Name: "John2"},
+	}
+	var updated []*Person
+	err := s.
+		Update("people").
+		FromRecords([]string{"id"}, []string{"name", "email"}, records).
+		Returning("id", "name", "email").
+		QueryStructs(&updated)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(updated))
+
+	var people []*Person
+	err = s.Select("id", "name", "email").From("people").Where("id IN $1", []int{1, 2}).OrderBy("id").QueryStructs(&people)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mario2", people[0].Name)
+	assert.Equal(t, "mario2@acme.com", people[0].Email.String)
+	assert.Equal(t, "John2", people[1].Name)
+	assert.False(t, people[1].Email.Valid)
+}
+
+func TestUpdateFillRecords(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	p := &Person{ID: 1, Name: "Super Mario"}
+	err := s.
+		Update("people").
+		SetWhitelist(p, "name").
+		Where("id = $1", p.ID).
+		Returning("email", "created_at").
+		FillRecords()
+	assert.NoError(t, err)
+	assert.Equal(t, "mario@acme.com", p.Email.String)
+	assert.True(t, p.CreatedAt.Valid)
+
+	err = s.
+		Update("people").
+		SetWhitelist(p, "name").
+		Where("id = $1", 1000).
+		Returning("email").
+		FillRecords()
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestUpdateSetChanged(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	var p Person
+	err := s.Select("id", "name", "email").From("people").Where("id = $1", 1).QueryStruct(&p)
+	assert.NoError(t, err)
+	snap, err := dat.Track(&p)
+	assert.NoError(t, err)
+
+	// nothing changed, nothing executed
+	res, err := s.Update("people").SetChanged(&p, snap).Where("id = $1", p.ID).Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, res.RowsAffected)
+
+	p.Name = "Super Mario"
+	res, err = s.Update("people").SetChanged(&p, snap).Where("id = $1", p.ID).Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, res.RowsAffected)
+
+	var name string
+	err = s.Select("name").From("people").Where("id = $1", p.ID).QueryScalar(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "Super Mario", name)
+}
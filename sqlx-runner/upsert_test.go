@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/matcherino/dat/dat"
@@ -48,3 +49,90 @@ func TestUpsertReal(t *testing.T) {
 	assert.True(t, person.ID > 0)
 	assert.NotEqual(t, person.CreatedAt, dat.NullTime{})
 }
+
+func TestUpsertOnConflictConcurrent(t *testing.T) {
+	_, err := testDB.Exec(`
+		DROP TABLE IF EXISTS upsert_counters;
+		CREATE TABLE upsert_counters (key text PRIMARY KEY, value int NOT NULL);
+	`)
+	assert.NoError(t, err)
+	defer testDB.Exec("DROP TABLE upsert_counters")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			var value int
+			errs <- testDB.Upsert("upsert_counters").
+				Columns("key", "value").
+				Values("hits", i).
+				OnConflict("key").
+				Returning("value").
+				QueryScalar(&value)
+		}(i)
+		go func() {
+			defer wg.Done()
+			var key string
+			errs <- testDB.Insect("upsert_counters").
+				Columns("key", "value").
+				Values("hits", -1).
+				OnConflict("key").
+				Returning("key").
+				QueryScalar(&key)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	var count int
+	err = testDB.SQL("SELECT count(*) FROM upsert_counters").QueryScalar(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestUpsertOnConflictRecords(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	_, err := s.SQL("CREATE UNIQUE INDEX people_email_idx ON people (email)").Exec()
+	assert.NoError(t, err)
+
+	people := []*Person{
+		{Name: "Mario2", Email: dat.NullStringFrom("mario@acme.com")},
+		{Name: "Wario", Email: dat.NullStringFrom("wario@acme.com")},
+	}
+	b := s.Upsert("people").Columns("name", "email")
+	for _, p := range people {
+		b.Record(p)
+	}
+	var upserted []*Person
+	err = b.OnConflict("email").Returning("id", "name", "email").QueryStructs(&upserted)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(upserted))
+	assert.Equal(t, int64(1), upserted[0].ID)
+	assert.Equal(t, "Mario2", upserted[0].Name)
+}
+
+func TestUpsertFillRecords(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+
+	p := &Person{ID: 1, Name: "Mario"}
+	p.Email.Valid = true
+	p.Email.String = "mario@barc.com"
+	err := s.
+		Upsert("people").
+		Columns("id", "email").
+		Record(p).
+		OnConflict("id").
+		Returning("id", "name", "foo").
+		FillRecords()
+	assert.NoError(t, err)
+	assert.Equal(t, "Mario", p.Name)
+	assert.Equal(t, "bar", p.Foo)
+}
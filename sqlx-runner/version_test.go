@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/matcherino/dat/dat"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type document struct {
+	ID      int64  `db:"id,pk,omitempty"`
+	Title   string `db:"title"`
+	Version int64  `db:"version,version"`
+}
+
+func createDocuments(t *testing.T, s *Tx) {
+	_, err := s.SQL(`
+		CREATE TEMP TABLE documents (
+			id serial PRIMARY KEY,
+			title text NOT NULL,
+			version bigint NOT NULL DEFAULT 0
+		) ON COMMIT DROP
+	`).Exec()
+	assert.NoError(t, err)
+}
+
+func TestVersionUpdate(t *testing.T) {
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+	createDocuments(t, s)
+
+	doc := &document{Title: "a"}
+	err := s.InsertInto("documents").Whitelist("*").Record(doc).Returning("id").FillRecords()
+	assert.NoError(t, err)
+
+	stale := *doc
+	doc.Title = "b"
+	err = s.Update("documents").SetWhitelist(doc, "title").Where("id = $1", doc.ID).Returning("id").FillRecords()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, doc.Version)
+
+	stale.Title = "c"
+	_, err = s.Update("documents").SetWhitelist(&stale, "title").Where("id = $1", stale.ID).Exec()
+	assert.Equal(t, dat.ErrStaleRecord, err)
+	err = s.Update("documents").SetWhitelist(&stale, "title").Where("id = $1", stale.ID).Returning("id").FillRecords()
+	assert.Equal(t, dat.ErrStaleRecord, err)
+
+	_, err = s.DeleteFrom("documents").Record(&stale).Exec()
+	assert.Equal(t, dat.ErrStaleRecord, err)
+	res, err := s.DeleteFrom("documents").Record(doc).Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, res.RowsAffected)
+}
+
+func TestVersionUpsert(t *testing.T) {
+	if testDB.Version < 90500 {
+		t.Skip("ON CONFLICT requires Postgres 9.5")
+	}
+	s := beginTxWithFixtures()
+	defer s.AutoRollback()
+	createDocuments(t, s)
+
+	doc := &document{ID: 1, Title: "a"}
+	err := s.Upsert("documents").Whitelist("*").Record(doc).FillRecords()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, doc.Version)
+
+	stale := *doc
+	doc.Title = "b"
+	err = s.Upsert("documents").Whitelist("*").Record(doc).FillRecords()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, doc.Version)
+
+	_, err = s.Upsert("documents").Whitelist("*").Record(&stale).Exec()
+	assert.Equal(t, dat.ErrStaleRecord, err)
+}